@@ -42,9 +42,23 @@ func Jitter[T ~int64 | ~int | ~int32 | ~float64 | ~float32](input T, coefficient
 	if coefficient == 0 {
 		return input
 	}
+	return jitter(input, coefficient, rand.Float64())
+}
+
+// JitterWithSource is like Jitter, but draws the random number from source instead of the global source,
+// so the result is reproducible for a given source seed.
+func JitterWithSource[T ~int64 | ~int | ~int32 | ~float64 | ~float32](input T, coefficient float64, source *rand.Rand) T {
+	validateCoefficient(coefficient)
+
+	if coefficient == 0 {
+		return input
+	}
+	return jitter(input, coefficient, source.Float64())
+}
 
+func jitter[T ~int64 | ~int | ~int32 | ~float64 | ~float32](input T, coefficient float64, random float64) T {
 	base := float64(input) * (1 - coefficient)
-	addon := rand.Float64() * 2 * (float64(input) - base)
+	addon := random * 2 * (float64(input) - base)
 	return T(base + addon)
 }
 
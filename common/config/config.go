@@ -288,8 +288,18 @@ type (
 		NumHistoryShards int32 `yaml:"numHistoryShards" validate:"nonzero"`
 		// DataStores contains the configuration for all datastores
 		DataStores map[string]DataStore `yaml:"datastores"`
+		// RetryableErrorTypes are the names of the serviceerror types, e.g. "Unavailable", that persistence clients
+		// retry. Unavailable and DataLoss errors are retried when empty.
+		RetryableErrorTypes []string `yaml:"retryableErrorTypes"`
 		// TransactionSizeLimit is the largest allowed transaction size
 		TransactionSizeLimit dynamicconfig.IntPropertyFn `yaml:"-" json:"-"`
+		// LatencySensitiveTaskReadTimeout is the timeout of latency sensitive history task reads
+		LatencySensitiveTaskReadTimeout dynamicconfig.DurationPropertyFn `yaml:"-" json:"-"`
+		// ConsistentTaskReadTimeout is the timeout of consistent history task reads
+		ConsistentTaskReadTimeout dynamicconfig.DurationPropertyFn `yaml:"-" json:"-"`
+		// TimerTaskRangeDeleteBatchSize is the maximum number of timer tasks deleted by one statement when timer
+		// tasks are range completed on SQL persistence
+		TimerTaskRangeDeleteBatchSize dynamicconfig.IntPropertyFn `yaml:"-" json:"-"`
 	}
 
 	// DataStore is the configuration for a single datastore
@@ -438,6 +448,11 @@ type (
 		ConnectAddr string `yaml:"connectAddr" validate:"nonzero"`
 		// ConnectProtocol is the protocol that goes with the ConnectAddr ex - tcp, unix
 		ConnectProtocol string `yaml:"connectProtocol" validate:"nonzero"`
+		// ReadReplicaConnectAddr is the remote addr of a read replica of the database. When set, task statistics
+		// and history task reads at persistence.ReadTierLagTolerant are sent to it; all other reads, including
+		// queue reads, and writes use ConnectAddr. It is connected to with the rest of this config and is ignored
+		// if Connect is set or the plugin doesn't support read replicas.
+		ReadReplicaConnectAddr string `yaml:"readReplicaConnectAddr"`
 		// ConnectAttributes is a set of key-value attributes to be sent as part of connect data_source_name url
 		ConnectAttributes map[string]string `yaml:"connectAttributes"`
 		// MaxConns the max number of connections to this datastore
@@ -446,10 +461,71 @@ type (
 		MaxIdleConns int `yaml:"maxIdleConns"`
 		// MaxConnLifetime is the maximum time a connection can be alive
 		MaxConnLifetime time.Duration `yaml:"maxConnLifetime"`
+		// SlowQueryThreshold is the duration above which queries are logged with their elapsed time, see
+		// sqlplugin.GenericDB.SetSlowQueryThreshold. Disabled when zero.
+		SlowQueryThreshold time.Duration `yaml:"slowQueryThreshold"`
 		// EXPERIMENTAL - TaskScanPartitions is the number of partitions to sequentially scan during ListTaskQueue operations.
 		// This is used for in a sharded sql database such as Vitess for heavy task workloads to minimize scatter gather.
 		// The default value for this param is 1, and should not be configured without a thorough understanding of what this does.
 		TaskScanPartitions int `yaml:"taskScanPartitions"`
+		// LenientPageTokens makes history task reads treat a page token that cannot be deserialized as the start
+		// of the requested range instead of failing the read. A warning metric is emitted whenever this happens.
+		// The default is to reject corrupt page tokens.
+		LenientPageTokens bool `yaml:"lenientPageTokens"`
+		// URLSafePageTokens makes scheduled (e.g. timer) task reads return page tokens encoded as URL-safe base64
+		// instead of raw JSON. Tokens in either encoding are always accepted.
+		URLSafePageTokens bool `yaml:"urlSafePageTokens"`
+		// BinaryPageTokens makes scheduled (e.g. timer) task reads return compact binary page tokens instead of
+		// JSON. Tokens in either format are always accepted, so it should only be enabled once all history hosts
+		// run a version that accepts binary tokens.
+		BinaryPageTokens bool `yaml:"binaryPageTokens"`
+		// TaskCategoryValidation checks that history tasks read from the legacy per-category tables (transfer, timer,
+		// replication and visibility) have a task type belonging to that table's category, which catches tasks written
+		// to the wrong table. Supported values are "error", which fails the read, and "skip", which drops mismatched
+		// tasks from the result and emits a metric. Validation is disabled when empty.
+		TaskCategoryValidation string `yaml:"taskCategoryValidation"`
+		// PartialResultsPageSize makes history task reads fetch their batch in pages of at most this many tasks.
+		// If the context deadline is exceeded after at least one page was read, the tasks read so far are returned
+		// with a page token to continue from, instead of failing the read. Disabled when zero.
+		PartialResultsPageSize int `yaml:"partialResultsPageSize"`
+		// DefaultTaskDataEncoding is the encoding (e.g. "Proto3") assumed for history task rows with an empty
+		// data_encoding, as written by some old server versions. Such rows fail to deserialize when it is empty.
+		DefaultTaskDataEncoding string `yaml:"defaultTaskDataEncoding"`
+		// TaskDataChecksums makes history task writes store a CRC32 checksum of the task data, and history task
+		// reads verify it to detect data corrupted at rest. Supported values are "error", which fails the read
+		// with a DataLoss error, and "skip", which drops corrupt tasks from the result and emits a metric.
+		// Rows written without a checksum are never verified. Checksums are disabled when empty.
+		TaskDataChecksums string `yaml:"taskDataChecksums"`
+		// ShardLockedRangeCompletes makes range completes of history tasks run in a transaction holding a read lock
+		// on the shard row, like adding history tasks does, so that a history host that lost ownership of a shard
+		// cannot delete tasks the new owner still needs. Range complete requests must then carry the shard's
+		// RangeID and fail with ShardOwnershipLostError if it is stale. Disabled by default.
+		ShardLockedRangeCompletes bool `yaml:"shardLockedRangeCompletes"`
+		// BacklogPressureFullPageThreshold is the number of consecutive full pages a history task read of a shard and
+		// task category may return before a backlog is reported. Every further full page emits a metric and a
+		// throttled warning log, until a read returns a page that isn't full. Disabled when zero.
+		BacklogPressureFullPageThreshold int `yaml:"backlogPressureFullPageThreshold"`
+		// TransferTaskReadAheadSize is the number of transfer tasks a read of a shard's transfer tasks reads beyond
+		// the requested page. They are kept in memory, and the next read of the shard is served from them if it
+		// starts where the last one left off, until they run out. Adding tasks to a shard drops them. They are also
+		// dropped after a few seconds, as another host may write the shard's tasks while owning the shard, so
+		// enabling it must be avoided if shards can be read through a store and written through another for longer.
+		// Disabled when zero.
+		TransferTaskReadAheadSize int `yaml:"transferTaskReadAheadSize"`
+		// StrictTaskOrder makes history task reads check that their tasks are in task key order, and report and sort
+		// tasks returned out of order by the database. Readers return tasks in task key order without it; this is
+		// meant for tests, to catch ordering bugs of persistence plugins.
+		StrictTaskOrder bool `yaml:"strictTaskOrder"`
+		// AddHistoryTasksSplitThreshold is the number of tasks above which an AddHistoryTasks call with tasks of several
+		// categories is split into one transaction per task category, so that the shard row isn't read locked for the
+		// whole insert. Every transaction locks the shard row again and checks the range ID. The split call is not
+		// atomic: if a transaction fails, e.g. because the shard was acquired by another host, the categories added
+		// before stay and the remaining ones aren't added. Disabled when zero, which adds all tasks in one transaction.
+		AddHistoryTasksSplitThreshold int `yaml:"addHistoryTasksSplitThreshold"`
+		// TimerTaskRangeDeleteBatchSize is the maximum number of timer tasks deleted by one statement when timer
+		// tasks are range completed. It is set from dynamic config, see Persistence.TimerTaskRangeDeleteBatchSize.
+		// The whole range is deleted with one statement when nil or zero.
+		TimerTaskRangeDeleteBatchSize dynamicconfig.IntPropertyFn `yaml:"-" json:"-"`
 		// TLS is the configuration for TLS connections
 		TLS *auth.TLS `yaml:"tls"`
 	}
@@ -162,6 +162,25 @@ for signal / start / signal with start API if namespace is not active`,
 		primitives.DefaultTransactionSizeLimit,
 		`TransactionSizeLimit is the largest allowed transaction size to persistence`,
 	)
+	LatencySensitiveTaskReadTimeout = NewGlobalDurationSetting(
+		"system.latencySensitiveTaskReadTimeout",
+		0,
+		`LatencySensitiveTaskReadTimeout is the timeout of history task reads that should fail fast, e.g. by queue
+processors. Zero means reads use the caller's deadline.`,
+	)
+	ConsistentTaskReadTimeout = NewGlobalDurationSetting(
+		"system.consistentTaskReadTimeout",
+		0,
+		`ConsistentTaskReadTimeout is the timeout of history task reads that can wait longer for a consistent result,
+e.g. by admin APIs. Zero means reads use the caller's deadline.`,
+	)
+	TimerTaskRangeDeleteBatchSize = NewGlobalIntSetting(
+		"system.timerTaskRangeDeleteBatchSize",
+		0,
+		`TimerTaskRangeDeleteBatchSize is the maximum number of timer tasks a single statement deletes when timer tasks
+are range completed on SQL persistence. The range is deleted in batches until a batch deletes fewer tasks.
+Zero means the whole range is deleted with one statement.`,
+	)
 	DisallowQuery = NewNamespaceBoolSetting(
 		"system.disallowQuery",
 		false,
@@ -2135,6 +2154,41 @@ the user has not specified an explicit RetryPolicy`,
 		retrypolicy.DefaultDefaultRetrySettings,
 		`DefaultWorkflowRetryPolicy represents the out-of-box retry policy for unset fields
 where the user has set an explicit RetryPolicy, but not specified all the fields`,
+	)
+	RetryMinimumBackoffInterval = NewNamespaceDurationSetting(
+		"history.retryMinimumBackoffInterval",
+		0,
+		`RetryMinimumBackoffInterval is the lower bound applied to the backoff interval computed from a workflow's
+retry policy, preventing retries that are only microseconds apart. Zero disables the bound.`,
+	)
+	RetryBackoffIntervalGranularity = NewNamespaceDurationSetting(
+		"history.retryBackoffIntervalGranularity",
+		0,
+		`RetryBackoffIntervalGranularity rounds the backoff interval computed from a workflow's or activity's retry
+policy up to a multiple of this duration, so that retries align with the tick of the timer queue. Zero disables
+rounding.`,
+	)
+	RetryBackoffJitterCoefficient = NewNamespaceFloatSetting(
+		"history.retryBackoffJitterCoefficient",
+		0,
+		`RetryBackoffJitterCoefficient jitters the exponential backoff interval computed from a workflow's or activity's
+retry policy by up to this fraction of it. The jitter is derived from the workflow, run and activity IDs and the
+attempt, so that a task always retries after the same interval while different tasks are spread apart. A retry
+delay requested by the worker is never jittered. Zero disables jitter, and values above 1 are treated as 1.`,
+	)
+	RetryBackoffCoefficientBreakpoints = NewNamespaceTypedSetting(
+		"history.retryBackoffCoefficientBreakpoints",
+		[]retrypolicy.BackoffCoefficientBreakpoint(nil),
+		`RetryBackoffCoefficientBreakpoints overrides the backoff coefficient of workflow and activity retry policies
+from a given attempt on, e.g. [{FromAttempt: 5, Coefficient: 1.2}] grows the backoff interval by the retry policy's
+coefficient for the first 5 attempts and by 1.2 after that. Breakpoints are applied in the order of their attempt.
+Empty applies the retry policy's coefficient to every attempt.`,
+	)
+	GlobalNonRetryableErrorTypes = NewNamespaceTypedSetting(
+		"history.globalNonRetryableErrorTypes",
+		[]string(nil),
+		`GlobalNonRetryableErrorTypes is a list of application failure types that are not retried by any workflow or
+activity of the namespace, in addition to the non-retryable error types of their retry policies.`,
 	)
 	FollowReusePolicyAfterConflictPolicyTerminate = NewNamespaceTypedSetting(
 		"history.followReusePolicyAfterConflictPolicyTerminate",
@@ -230,10 +230,16 @@ const (
 	PersistenceRangeDeleteReplicationTaskFromDLQScope = "RangeDeleteReplicationTaskFromDLQ"
 	// PersistenceGetTimerTasksScope tracks GetTimerTasks calls made by service to persistence layer
 	PersistenceGetTimerTasksScope = "GetTimerTasks"
+	// PersistenceGetOldestTimerTaskScope tracks GetOldestTimerTask calls made by service to persistence layer
+	PersistenceGetOldestTimerTaskScope = "GetOldestTimerTask"
+	// PersistenceGetEarliestTimerFireTimeScope tracks GetEarliestTimerFireTime calls made by service to persistence layer
+	PersistenceGetEarliestTimerFireTimeScope = "GetEarliestTimerFireTime"
 	// PersistenceCompleteTimerTaskScope tracks CompleteTimerTasks calls made by service to persistence layer
 	PersistenceCompleteTimerTaskScope = "CompleteTimerTask"
 	// PersistenceRangeCompleteTimerTasksScope tracks CompleteTimerTasks calls made by service to persistence layer
 	PersistenceRangeCompleteTimerTasksScope = "RangeCompleteTimerTasks"
+	// PersistenceCompleteAndRescheduleTimerTaskScope tracks CompleteAndRescheduleTimerTask calls made by service to persistence layer
+	PersistenceCompleteAndRescheduleTimerTaskScope = "CompleteAndRescheduleTimerTask"
 	// PersistenceCreateTasksScope tracks CreateTasks calls made by service to persistence layer
 	PersistenceCreateTasksScope = "CreateTasks"
 	// PersistenceGetTasksScope tracks GetTasks calls made by service to persistence layer
@@ -740,6 +746,10 @@ var (
 		"shardinfo_scheduled_queue_lag",
 		WithDescription("A histogram across history shards for the difference between the earliest scheduled time of pending history tasks and current time."),
 	)
+	ShardInfoTimerTaskLagGauge = NewGaugeDef(
+		"shardinfo_timer_task_lag",
+		WithDescription("Seconds since the visibility timestamp of the oldest timer task of a history shard, or zero if it isn't due yet, keyed by `shard_id`."),
+	)
 	SyncShardFromRemoteCounter = NewCounterDef("syncshard_remote_count")
 	SyncShardFromRemoteFailure = NewCounterDef("syncshard_remote_failed")
 	FinalizerItemsCompleted    = NewCounterDef("finalizer_items_completed")
@@ -1227,6 +1237,42 @@ var (
 	)
 	PersistenceShardRPS                    = NewDimensionlessHistogramDef("persistence_shard_rps")
 	PersistenceErrResourceExhaustedCounter = NewCounterDef("persistence_errors_resource_exhausted")
+	PersistenceCorruptPageTokens           = NewCounterDef(
+		"persistence_corrupt_page_tokens",
+		WithDescription("Page tokens that could not be deserialized and were reset to the start of the requested range, keyed by `operation`"),
+	)
+	PersistenceTaskEncodingFallbacks = NewCounterDef(
+		"persistence_task_encoding_fallbacks",
+		WithDescription("History tasks read without a data encoding that were decoded with the configured default encoding, keyed by `operation`"),
+	)
+	PersistenceTaskCategoryMismatches = NewCounterDef(
+		"persistence_task_category_mismatches",
+		WithDescription("History tasks skipped because their task type does not belong to the category of the table they were read from, keyed by `operation`"),
+	)
+	PersistenceTaskDataChecksumMismatches = NewCounterDef(
+		"persistence_task_data_checksum_mismatches",
+		WithDescription("History tasks skipped because their data does not match the checksum stored with it, keyed by `operation`"),
+	)
+	PersistenceHistoryTasksRead = NewDimensionlessHistogramDef(
+		"persistence_history_tasks_read",
+		WithDescription("Number of history tasks returned by a single GetHistoryTasks call, keyed by `task_category`"),
+	)
+	PersistenceTaskOrderViolations = NewCounterDef(
+		"persistence_task_order_violations",
+		WithDescription("History task reads whose tasks were returned out of task key order and were sorted, keyed by `operation`. Only checked with strict task order"),
+	)
+	PersistenceTaskBacklogPressure = NewCounterDef(
+		"persistence_task_backlog_pressure",
+		WithDescription("GetHistoryTasks calls that returned a full page after more consecutive full pages for the same shard than the configured threshold, keyed by `task_category`"),
+	)
+	PersistenceTimerTasksDeletedPerBatch = NewDimensionlessHistogramDef(
+		"persistence_timer_tasks_deleted_per_batch",
+		WithDescription("Number of timer tasks deleted by a single statement when timer tasks are range completed"),
+	)
+	PersistenceTaskProcessingLatency = NewTimerDef(
+		"persistence_task_processing_latency",
+		WithDescription("Time from the creation of a history task to its completion, keyed by `task_category`. Only emitted for completions that carry the task creation time"),
+	)
 	VisibilityPersistenceRequests          = NewCounterDef("visibility_persistence_requests")
 	VisibilityPersistenceErrorWithType     = NewCounterDef("visibility_persistence_error_with_type")
 	VisibilityPersistenceFailures          = NewCounterDef("visibility_persistence_errors")
@@ -59,6 +59,7 @@ const (
 	actionType     = "action_type"
 	workerBuildId  = "worker-build-id"
 	destination    = "destination"
+	shardID        = "shard_id"
 	// Generic reason tag can be used anywhere a reason is needed.
 	reason = "reason"
 	// See server.api.enums.v1.ReplicationTaskType
@@ -368,6 +369,12 @@ func OperationTag(value string) Tag {
 	return &tagImpl{key: OperationTagName, value: value}
 }
 
+// ShardIDTag returns a new shard ID tag. Shard IDs are unbounded, so it's only meant for metrics emitted
+// once per shard and not per request.
+func ShardIDTag(value int32) Tag {
+	return &tagImpl{key: shardID, value: strconv.Itoa(int(value))}
+}
+
 func StringTag(key string, value string) Tag {
 	return &tagImpl{key: key, value: value}
 }
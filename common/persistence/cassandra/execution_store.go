@@ -170,6 +170,19 @@ func (d *ExecutionStore) ConflictResolveWorkflowExecution(
 	return d.MutableStateStore.ConflictResolveWorkflowExecution(ctx, request)
 }
 
+// MarkShardClosing is a no-op, Cassandra stores don't fail AddHistoryTasks of closing shards fast.
+func (d *ExecutionStore) MarkShardClosing(
+	shardID int32,
+	rangeID int64,
+) {
+}
+
+// MarkShardOpen is a no-op, see MarkShardClosing.
+func (d *ExecutionStore) MarkShardOpen(
+	shardID int32,
+) {
+}
+
 func (d *ExecutionStore) GetName() string {
 	return cassandraPersistenceName
 }
@@ -174,6 +174,8 @@ const (
 		`and visibility_ts = ? ` +
 		`and task_id = ?`
 
+	templateCompleteTimerTaskIfExistsQuery = templateCompleteTimerTaskQuery + ` IF EXISTS`
+
 	templateRangeCompleteTimerTaskQuery = `DELETE FROM executions ` +
 		`WHERE shard_id = ? ` +
 		`and type = ? ` +
@@ -299,6 +301,84 @@ func (d *MutableStateTaskStore) RangeCompleteHistoryTasks(
 	}
 }
 
+// CompleteAndRescheduleTimerTask deletes a timer task and inserts the next timer task of the same recurring timer
+// in one conditional batch with the shard range ID check. The delete is conditioned on the timer task existing, so
+// a batch that isn't applied while the range ID matches means the completed timer task was not found.
+func (d *MutableStateTaskStore) CompleteAndRescheduleTimerTask(
+	ctx context.Context,
+	request *p.InternalCompleteAndRescheduleTimerTaskRequest,
+) error {
+	batch := d.Session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+
+	batch.Query(templateCompleteTimerTaskIfExistsQuery,
+		request.ShardID,
+		rowTypeTimerTask,
+		rowTypeTimerNamespaceID,
+		rowTypeTimerWorkflowID,
+		rowTypeTimerRunID,
+		p.UnixMilliseconds(request.CompletedTaskKey.FireTime),
+		request.CompletedTaskKey.TaskID,
+	)
+	if err := createTimerTasks(batch, []p.InternalHistoryTask{request.NextTask}, request.ShardID); err != nil {
+		return err
+	}
+	batch.Query(templateUpdateLeaseQuery,
+		request.RangeID,
+		request.ShardID,
+		rowTypeShard,
+		rowTypeShardNamespaceID,
+		rowTypeShardWorkflowID,
+		rowTypeShardRunID,
+		defaultVisibilityTimestamp,
+		rowTypeShardTaskID,
+		request.RangeID,
+	)
+
+	conflictRecord := newConflictRecord()
+	applied, conflictIter, err := d.Session.MapExecuteBatchCAS(batch, conflictRecord)
+	if err != nil {
+		return gocql.ConvertError("CompleteAndRescheduleTimerTask", err)
+	}
+	defer func() {
+		_ = conflictIter.Close()
+	}()
+
+	if applied {
+		return nil
+	}
+	err = convertErrors(conflictRecord, conflictIter, request.ShardID, request.RangeID, "", nil)
+	if _, ok := err.(*p.ConditionFailedError); ok {
+		return serviceerror.NewNotFound(fmt.Sprintf(
+			"CompleteAndRescheduleTimerTask failed. Timer task not found. ShardID: %v, TaskKey: %v",
+			request.ShardID,
+			request.CompletedTaskKey,
+		))
+	}
+	return err
+}
+
+// GetOldestTimerTask reads the first timer task row of the shard. Timer task rows are clustered by visibility
+// timestamp and task ID, so it is the oldest one.
+func (d *MutableStateTaskStore) GetOldestTimerTask(
+	ctx context.Context,
+	request *p.GetOldestTimerTaskRequest,
+) (*p.InternalGetOldestTimerTaskResponse, error) {
+	resp, err := d.getTimerTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             request.ShardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.MinimumKey,
+		ExclusiveMaxTaskKey: tasks.MaximumKey,
+		BatchSize:           1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Tasks) == 0 {
+		return &p.InternalGetOldestTimerTaskResponse{}, nil
+	}
+	return &p.InternalGetOldestTimerTaskResponse{Task: &resp.Tasks[0]}, nil
+}
+
 func (d *MutableStateTaskStore) getTransferTasks(
 	ctx context.Context,
 	request *p.GetHistoryTasksRequest,
@@ -25,6 +25,8 @@
 package client
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"go.temporal.io/api/serviceerror"
@@ -32,6 +34,7 @@ import (
 	"go.temporal.io/server/common/backoff"
 	"go.temporal.io/server/common/config"
 	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
 	"go.temporal.io/server/common/metrics"
 	"go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/persistence/serialization"
@@ -41,6 +44,19 @@ import (
 var (
 	retryPolicy               = common.CreatePersistenceClientRetryPolicy()
 	namespaceQueueRetryPolicy = backoff.NewConstantDelayRetryPolicy(time.Millisecond * 50).WithMaximumAttempts(10)
+
+	// persistenceErrorTypes are the serviceerror types that can be configured as retryable in
+	// config.Persistence.RetryableErrorTypes, keyed by type name.
+	persistenceErrorTypes = map[string]func(error) bool{
+		"Canceled":          isErrorType[*serviceerror.Canceled],
+		"DataLoss":          isErrorType[*serviceerror.DataLoss],
+		"DeadlineExceeded":  isErrorType[*serviceerror.DeadlineExceeded],
+		"Internal":          isErrorType[*serviceerror.Internal],
+		"InvalidArgument":   isErrorType[*serviceerror.InvalidArgument],
+		"NotFound":          isErrorType[*serviceerror.NotFound],
+		"ResourceExhausted": isErrorType[*serviceerror.ResourceExhausted],
+		"Unavailable":       isErrorType[*serviceerror.Unavailable],
+	}
 )
 
 type (
@@ -80,6 +96,7 @@ type (
 		namespaceRateLimiter quotas.RequestRateLimiter
 		shardRateLimiter     quotas.RequestRateLimiter
 		healthSignals        persistence.HealthSignalAggregator
+		isTransientError     backoff.IsRetryable
 	}
 )
 
@@ -116,6 +133,11 @@ func NewFactory(
 		shardRateLimiter:     shardRateLimiter,
 		healthSignals:        healthSignals,
 	}
+	isTransientError, err := NewPersistenceTransientErrorClassifier(cfg.RetryableErrorTypes)
+	if err != nil {
+		logger.Fatal("invalid config: persistence retryableErrorTypes", tag.Error(err))
+	}
+	factory.isTransientError = isTransientError
 	factory.initDependencies()
 	return factory
 }
@@ -134,7 +156,7 @@ func (f *factoryImpl) NewTaskManager() (persistence.TaskManager, error) {
 	if f.metricsHandler != nil && f.healthSignals != nil {
 		result = persistence.NewTaskPersistenceMetricsClient(result, f.metricsHandler, f.healthSignals, f.logger)
 	}
-	result = persistence.NewTaskPersistenceRetryableClient(result, retryPolicy, IsPersistenceTransientError)
+	result = persistence.NewTaskPersistenceRetryableClient(result, retryPolicy, f.isTransientError)
 	return result, nil
 }
 
@@ -152,7 +174,7 @@ func (f *factoryImpl) NewShardManager() (persistence.ShardManager, error) {
 	if f.metricsHandler != nil && f.healthSignals != nil {
 		result = persistence.NewShardPersistenceMetricsClient(result, f.metricsHandler, f.healthSignals, f.logger)
 	}
-	result = persistence.NewShardPersistenceRetryableClient(result, retryPolicy, IsPersistenceTransientError)
+	result = persistence.NewShardPersistenceRetryableClient(result, retryPolicy, f.isTransientError)
 	return result, nil
 }
 
@@ -170,7 +192,7 @@ func (f *factoryImpl) NewMetadataManager() (persistence.MetadataManager, error)
 	if f.metricsHandler != nil && f.healthSignals != nil {
 		result = persistence.NewMetadataPersistenceMetricsClient(result, f.metricsHandler, f.healthSignals, f.logger)
 	}
-	result = persistence.NewMetadataPersistenceRetryableClient(result, retryPolicy, IsPersistenceTransientError)
+	result = persistence.NewMetadataPersistenceRetryableClient(result, retryPolicy, f.isTransientError)
 	return result, nil
 }
 
@@ -188,7 +210,7 @@ func (f *factoryImpl) NewClusterMetadataManager() (persistence.ClusterMetadataMa
 	if f.metricsHandler != nil && f.healthSignals != nil {
 		result = persistence.NewClusterMetadataPersistenceMetricsClient(result, f.metricsHandler, f.healthSignals, f.logger)
 	}
-	result = persistence.NewClusterMetadataPersistenceRetryableClient(result, retryPolicy, IsPersistenceTransientError)
+	result = persistence.NewClusterMetadataPersistenceRetryableClient(result, retryPolicy, f.isTransientError)
 	return result, nil
 }
 
@@ -199,14 +221,17 @@ func (f *factoryImpl) NewExecutionManager() (persistence.ExecutionManager, error
 		return nil, err
 	}
 
-	result := persistence.NewExecutionManager(store, f.serializer, f.eventBlobCache, f.logger, f.config.TransactionSizeLimit)
+	result := persistence.NewExecutionManager(store, f.serializer, f.eventBlobCache, f.logger, f.config.TransactionSizeLimit, persistence.ReadTierTimeouts{
+		persistence.ReadTierLatencySensitive: f.config.LatencySensitiveTaskReadTimeout,
+		persistence.ReadTierConsistent:       f.config.ConsistentTaskReadTimeout,
+	})
 	if f.systemRateLimiter != nil && f.namespaceRateLimiter != nil {
 		result = persistence.NewExecutionPersistenceRateLimitedClient(result, f.systemRateLimiter, f.namespaceRateLimiter, f.shardRateLimiter, f.logger)
 	}
 	if f.metricsHandler != nil && f.healthSignals != nil {
 		result = persistence.NewExecutionPersistenceMetricsClient(result, f.metricsHandler, f.healthSignals, f.logger)
 	}
-	result = persistence.NewExecutionPersistenceRetryableClient(result, retryPolicy, IsPersistenceTransientError)
+	result = persistence.NewExecutionPersistenceRetryableClient(result, retryPolicy, f.isTransientError)
 	return result, nil
 }
 
@@ -247,7 +272,7 @@ func (f *factoryImpl) NewNexusEndpointManager() (persistence.NexusEndpointManage
 	if f.metricsHandler != nil && f.healthSignals != nil {
 		result = persistence.NewNexusEndpointPersistenceMetricsClient(result, f.metricsHandler, f.healthSignals, f.logger)
 	}
-	result = persistence.NewNexusEndpointPersistenceRetryableClient(result, retryPolicy, IsPersistenceTransientError)
+	result = persistence.NewNexusEndpointPersistenceRetryableClient(result, retryPolicy, f.isTransientError)
 	return result, nil
 }
 
@@ -269,6 +294,33 @@ func IsPersistenceTransientError(err error) bool {
 	return false
 }
 
+// NewPersistenceTransientErrorClassifier returns a function reporting whether persistence clients should retry an
+// error, which is the case if it is of one of the serviceerror types named in errorTypes, e.g. "Unavailable".
+// IsPersistenceTransientError is returned when errorTypes is empty.
+func NewPersistenceTransientErrorClassifier(errorTypes []string) (backoff.IsRetryable, error) {
+	if len(errorTypes) == 0 {
+		return IsPersistenceTransientError, nil
+	}
+	matchers := make([]func(error) bool, 0, len(errorTypes))
+	for _, errorType := range errorTypes {
+		matcher, ok := persistenceErrorTypes[errorType]
+		if !ok {
+			return nil, fmt.Errorf("unknown error type %q", errorType)
+		}
+		matchers = append(matchers, matcher)
+	}
+	return func(err error) bool {
+		return slices.ContainsFunc(matchers, func(matcher func(error) bool) bool {
+			return matcher(err)
+		})
+	}, nil
+}
+
+func isErrorType[T error](err error) bool {
+	_, ok := err.(T)
+	return ok
+}
+
 func IsNamespaceQueueTransientError(err error) bool {
 	switch err.(type) {
 	case *serviceerror.Unavailable, *persistence.ConditionFailedError:
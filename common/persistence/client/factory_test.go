@@ -25,13 +25,17 @@
 package client_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/persistence/client"
 	"go.temporal.io/server/common/persistence/mock"
+	"go.temporal.io/server/service/history/tasks"
 	"go.uber.org/mock/gomock"
 )
 
@@ -83,3 +87,87 @@ func TestFactoryImpl_NewHistoryTaskQueueManager(t *testing.T) {
 		})
 	}
 }
+
+func TestNewPersistenceTransientErrorClassifier(t *testing.T) {
+	t.Parallel()
+
+	isTransientError, err := client.NewPersistenceTransientErrorClassifier(nil)
+	require.NoError(t, err)
+	require.True(t, isTransientError(serviceerror.NewUnavailable("unavailable")))
+	require.True(t, isTransientError(serviceerror.NewDataLoss("data loss")))
+	require.False(t, isTransientError(serviceerror.NewInvalidArgument("invalid argument")))
+
+	isTransientError, err = client.NewPersistenceTransientErrorClassifier([]string{"Unavailable", "ResourceExhausted"})
+	require.NoError(t, err)
+	require.True(t, isTransientError(serviceerror.NewUnavailable("unavailable")))
+	require.True(t, isTransientError(&serviceerror.ResourceExhausted{Message: "resource exhausted"}))
+	require.False(t, isTransientError(serviceerror.NewDataLoss("data loss")))
+	require.False(t, isTransientError(serviceerror.NewInvalidArgument("invalid argument")))
+
+	_, err = client.NewPersistenceTransientErrorClassifier([]string{"Unavailable", "Unknown"})
+	require.ErrorContains(t, err, `"Unknown"`)
+}
+
+func TestFactoryImpl_RetryableErrorTypes(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{
+			name:  "Unavailable is retried",
+			err:   serviceerror.NewUnavailable("unavailable"),
+			calls: 2,
+		},
+		{
+			name:  "InvalidArgument is not retried",
+			err:   serviceerror.NewInvalidArgument("invalid argument"),
+			calls: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			executionStore := mock.NewMockExecutionStore(ctrl)
+			gomock.InOrder(
+				executionStore.EXPECT().RangeCompleteHistoryTasks(gomock.Any(), gomock.Any()).Return(tc.err),
+				executionStore.EXPECT().RangeCompleteHistoryTasks(gomock.Any(), gomock.Any()).Return(nil).Times(tc.calls-1),
+			)
+			dataStoreFactory := mock.NewMockDataStoreFactory(ctrl)
+			dataStoreFactory.EXPECT().NewExecutionStore().Return(executionStore, nil)
+
+			factory := client.NewFactory(
+				dataStoreFactory,
+				&config.Persistence{
+					NumHistoryShards:    1,
+					RetryableErrorTypes: []string{"Unavailable"},
+				},
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+				"",
+				nil,
+				nil,
+				nil,
+			)
+			executionManager, err := factory.NewExecutionManager()
+			require.NoError(t, err)
+			err = executionManager.RangeCompleteHistoryTasks(context.Background(), &persistence.RangeCompleteHistoryTasksRequest{
+				ShardID:             1,
+				TaskCategory:        tasks.CategoryTransfer,
+				InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+				ExclusiveMaxTaskKey: tasks.NewImmediateKey(2),
+			})
+			if tc.calls == 1 {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
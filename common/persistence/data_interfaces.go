@@ -71,6 +71,23 @@ const (
 	CreateWorkflowModeBypassCurrent
 )
 
+// ReadTier is the tolerance of a history task read for latency versus consistency
+type ReadTier int
+
+// Read tiers of history task reads
+const (
+	// ReadTierDefault reads with the caller's context as is
+	ReadTierDefault ReadTier = iota
+	// ReadTierLatencySensitive is for hot reads, e.g. by queue processors, that should fail fast
+	ReadTierLatencySensitive
+	// ReadTierConsistent is for reads, e.g. by admin APIs, that can wait longer for a consistent result. Stores
+	// serve them from the primary database and not from tasks they read ahead.
+	ReadTierConsistent
+	// ReadTierLagTolerant is for reads, e.g. diagnostics, that may miss recently written tasks. Stores may serve
+	// them from a read replica. Queue processors must not use it, as they would ack past the missed tasks.
+	ReadTierLagTolerant
+)
+
 // UpdateWorkflowMode update mode
 type UpdateWorkflowMode int
 
@@ -421,6 +438,9 @@ type (
 		ExclusiveMaxTaskKey tasks.Key
 		BatchSize           int
 		NextPageToken       []byte
+		// ReadTier selects the configured read timeout of the request, see ReadTierTimeouts, and whether it may
+		// be served by a read replica, see ReadTierLagTolerant
+		ReadTier ReadTier
 	}
 
 	// GetHistoryTasksResponse is the response for GetHistoryTasks
@@ -434,18 +454,58 @@ type (
 		ShardID      int32
 		TaskCategory tasks.Category
 		TaskKey      tasks.Key
+		// TaskCreationTime is optional. If set by the caller who read the task, the store emits
+		// the task processing latency, measured from this time, when the task is completed.
+		TaskCreationTime time.Time
 	}
 
 	// RangeCompleteHistoryTasksRequest deletes a range of history tasks
 	// Either max TaskID or FireTime is required depending on the
 	// task category type. Min TaskID or FireTime is optional.
 	RangeCompleteHistoryTasksRequest struct {
-		ShardID             int32
+		ShardID int32
+		// RangeID is the range ID of the shard owner. It is optional, but stores that guard range completes
+		// against stale shard owners require it.
+		RangeID             int64
 		TaskCategory        tasks.Category
 		InclusiveMinTaskKey tasks.Key
 		ExclusiveMaxTaskKey tasks.Key
 	}
 
+	// CompleteAndRescheduleTimerTaskRequest is used to complete a timer task of a recurring timer and add its
+	// next timer task atomically
+	CompleteAndRescheduleTimerTaskRequest struct {
+		ShardID int32
+		RangeID int64
+
+		CompletedTaskKey tasks.Key
+		NextTask         tasks.Task
+	}
+
+	// GetEarliestTimerFireTimeRequest is used to get the earliest fire time of the timer tasks of a shard
+	GetEarliestTimerFireTimeRequest struct {
+		ShardID int32
+	}
+
+	// GetEarliestTimerFireTimeResponse is the response for GetEarliestTimerFireTime
+	GetEarliestTimerFireTimeResponse struct {
+		// FireTime is the visibility timestamp of the earliest timer task, only set if Found is true
+		FireTime time.Time
+		// Found is false if the shard has no timer tasks
+		Found bool
+	}
+
+	// GetOldestTimerTaskRequest is used to get the timer task of a shard with the smallest task key
+	GetOldestTimerTaskRequest struct {
+		ShardID int32
+	}
+
+	// GetOldestTimerTaskResponse is the response for GetOldestTimerTask
+	GetOldestTimerTaskResponse struct {
+		// Task is nil if the shard has no timer tasks
+		Task tasks.Task
+	}
+
 	// GetReplicationTasksRequest is used to read tasks from the replication task queue
 	GetReplicationTasksRequest struct {
 		ShardID       int32
@@ -460,6 +520,8 @@ type (
 		ShardID           int32
 		SourceClusterName string
 		TaskInfo          *persistencespb.ReplicationTaskInfo
+		// Reason is optional. It records why the task is put into the DLQ.
+		Reason string
 	}
 
 	// GetReplicationTasksFromDLQRequest is used to get replication tasks from dlq
@@ -1114,9 +1176,24 @@ type (
 		// Tasks related APIs
 
 		AddHistoryTasks(ctx context.Context, request *AddHistoryTasksRequest) error
+		// MarkShardClosing makes AddHistoryTasks of the shard with rangeID or a lower range ID fail fast with
+		// ShardOwnershipLostError, until MarkShardOpen is called for the shard. Stores may ignore it.
+		MarkShardClosing(shardID int32, rangeID int64)
+		// MarkShardOpen clears the closing mark of the shard set by MarkShardClosing.
+		MarkShardOpen(shardID int32)
 		GetHistoryTasks(ctx context.Context, request *GetHistoryTasksRequest) (*GetHistoryTasksResponse, error)
 		CompleteHistoryTask(ctx context.Context, request *CompleteHistoryTaskRequest) error
 		RangeCompleteHistoryTasks(ctx context.Context, request *RangeCompleteHistoryTasksRequest) error
+		// CompleteAndRescheduleTimerTask deletes a timer task and adds the next timer task of the same recurring
+		// timer in one write fenced by the shard range ID, so that a failure leaves exactly one of them in place.
+		// NotFound is returned if the completed timer task doesn't exist, e.g. as it was rescheduled already.
+		CompleteAndRescheduleTimerTask(ctx context.Context, request *CompleteAndRescheduleTimerTaskRequest) error
+		// GetOldestTimerTask returns the timer task of a shard with the smallest task key, without reading a page
+		// of timer tasks.
+		GetOldestTimerTask(ctx context.Context, request *GetOldestTimerTaskRequest) (*GetOldestTimerTaskResponse, error)
+		// GetEarliestTimerFireTime returns the fire time of the oldest timer task of a shard, without deserializing
+		// the task.
+		GetEarliestTimerFireTime(ctx context.Context, request *GetEarliestTimerFireTimeRequest) (*GetEarliestTimerFireTimeResponse, error)
 
 		PutReplicationTaskToDLQ(ctx context.Context, request *PutReplicationTaskToDLQRequest) error
 		GetReplicationTasksFromDLQ(ctx context.Context, request *GetReplicationTasksFromDLQRequest) (*GetHistoryTasksResponse, error)
@@ -250,6 +250,20 @@ func (mr *MockExecutionManagerMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockExecutionManager)(nil).Close))
 }
 
+// CompleteAndRescheduleTimerTask mocks base method.
+func (m *MockExecutionManager) CompleteAndRescheduleTimerTask(ctx context.Context, request *CompleteAndRescheduleTimerTaskRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteAndRescheduleTimerTask", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteAndRescheduleTimerTask indicates an expected call of CompleteAndRescheduleTimerTask.
+func (mr *MockExecutionManagerMockRecorder) CompleteAndRescheduleTimerTask(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteAndRescheduleTimerTask", reflect.TypeOf((*MockExecutionManager)(nil).CompleteAndRescheduleTimerTask), ctx, request)
+}
+
 // CompleteHistoryTask mocks base method.
 func (m *MockExecutionManager) CompleteHistoryTask(ctx context.Context, request *CompleteHistoryTaskRequest) error {
 	m.ctrl.T.Helper()
@@ -395,6 +409,21 @@ func (mr *MockExecutionManagerMockRecorder) GetCurrentExecution(ctx, request any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCurrentExecution", reflect.TypeOf((*MockExecutionManager)(nil).GetCurrentExecution), ctx, request)
 }
 
+// GetEarliestTimerFireTime mocks base method.
+func (m *MockExecutionManager) GetEarliestTimerFireTime(ctx context.Context, request *GetEarliestTimerFireTimeRequest) (*GetEarliestTimerFireTimeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEarliestTimerFireTime", ctx, request)
+	ret0, _ := ret[0].(*GetEarliestTimerFireTimeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEarliestTimerFireTime indicates an expected call of GetEarliestTimerFireTime.
+func (mr *MockExecutionManagerMockRecorder) GetEarliestTimerFireTime(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEarliestTimerFireTime", reflect.TypeOf((*MockExecutionManager)(nil).GetEarliestTimerFireTime), ctx, request)
+}
+
 // GetHistoryBranchUtil mocks base method.
 func (m *MockExecutionManager) GetHistoryBranchUtil() HistoryBranchUtil {
 	m.ctrl.T.Helper()
@@ -438,6 +467,21 @@ func (mr *MockExecutionManagerMockRecorder) GetName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetName", reflect.TypeOf((*MockExecutionManager)(nil).GetName))
 }
 
+// GetOldestTimerTask mocks base method.
+func (m *MockExecutionManager) GetOldestTimerTask(ctx context.Context, request *GetOldestTimerTaskRequest) (*GetOldestTimerTaskResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOldestTimerTask", ctx, request)
+	ret0, _ := ret[0].(*GetOldestTimerTaskResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOldestTimerTask indicates an expected call of GetOldestTimerTask.
+func (mr *MockExecutionManagerMockRecorder) GetOldestTimerTask(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOldestTimerTask", reflect.TypeOf((*MockExecutionManager)(nil).GetOldestTimerTask), ctx, request)
+}
+
 // GetReplicationTasksFromDLQ mocks base method.
 func (m *MockExecutionManager) GetReplicationTasksFromDLQ(ctx context.Context, request *GetReplicationTasksFromDLQRequest) (*GetHistoryTasksResponse, error) {
 	m.ctrl.T.Helper()
@@ -498,6 +542,30 @@ func (mr *MockExecutionManagerMockRecorder) ListConcreteExecutions(ctx, request
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListConcreteExecutions", reflect.TypeOf((*MockExecutionManager)(nil).ListConcreteExecutions), ctx, request)
 }
 
+// MarkShardClosing mocks base method.
+func (m *MockExecutionManager) MarkShardClosing(shardID int32, rangeID int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MarkShardClosing", shardID, rangeID)
+}
+
+// MarkShardClosing indicates an expected call of MarkShardClosing.
+func (mr *MockExecutionManagerMockRecorder) MarkShardClosing(shardID, rangeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkShardClosing", reflect.TypeOf((*MockExecutionManager)(nil).MarkShardClosing), shardID, rangeID)
+}
+
+// MarkShardOpen mocks base method.
+func (m *MockExecutionManager) MarkShardOpen(shardID int32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MarkShardOpen", shardID)
+}
+
+// MarkShardOpen indicates an expected call of MarkShardOpen.
+func (mr *MockExecutionManagerMockRecorder) MarkShardOpen(shardID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkShardOpen", reflect.TypeOf((*MockExecutionManager)(nil).MarkShardOpen), shardID)
+}
+
 // PutReplicationTaskToDLQ mocks base method.
 func (m *MockExecutionManager) PutReplicationTaskToDLQ(ctx context.Context, request *PutReplicationTaskToDLQRequest) error {
 	m.ctrl.T.Helper()
@@ -56,7 +56,13 @@ type (
 		logger                log.Logger
 		pagingTokenSerializer *jsonHistoryTokenSerializer
 		transactionSizeLimit  dynamicconfig.IntPropertyFn
+		readTierTimeouts      ReadTierTimeouts
 	}
+
+	// ReadTierTimeouts are the context timeouts of history task reads by read tier. Tiers without a timeout,
+	// or with a zero timeout, read with the caller's context as is. The read tier is also passed on to the
+	// store, which may serve ReadTierLagTolerant reads from a read replica.
+	ReadTierTimeouts map[ReadTier]dynamicconfig.DurationPropertyFn
 )
 
 var _ ExecutionManager = (*executionManagerImpl)(nil)
@@ -68,6 +74,7 @@ func NewExecutionManager(
 	eventBlobCache XDCCache,
 	logger log.Logger,
 	transactionSizeLimit dynamicconfig.IntPropertyFn,
+	readTierTimeouts ReadTierTimeouts,
 ) ExecutionManager {
 	return &executionManagerImpl{
 		serializer:            serializer,
@@ -76,6 +83,7 @@ func NewExecutionManager(
 		logger:                logger,
 		pagingTokenSerializer: newJSONHistoryTokenSerializer(),
 		transactionSizeLimit:  transactionSizeLimit,
+		readTierTimeouts:      readTierTimeouts,
 	}
 }
 
@@ -848,6 +856,19 @@ func (m *executionManagerImpl) AddHistoryTasks(
 	})
 }
 
+func (m *executionManagerImpl) MarkShardClosing(
+	shardID int32,
+	rangeID int64,
+) {
+	m.persistence.MarkShardClosing(shardID, rangeID)
+}
+
+func (m *executionManagerImpl) MarkShardOpen(
+	shardID int32,
+) {
+	m.persistence.MarkShardOpen(shardID)
+}
+
 func (m *executionManagerImpl) GetHistoryTasks(
 	ctx context.Context,
 	request *GetHistoryTasksRequest,
@@ -860,6 +881,8 @@ func (m *executionManagerImpl) GetHistoryTasks(
 		return nil, err
 	}
 
+	ctx, cancel := m.withReadTierTimeout(ctx, request.ReadTier)
+	defer cancel()
 	resp, err := m.persistence.GetHistoryTasks(ctx, request)
 	if err != nil {
 		return nil, err
@@ -908,6 +931,69 @@ func (m *executionManagerImpl) RangeCompleteHistoryTasks(
 	return m.persistence.RangeCompleteHistoryTasks(ctx, request)
 }
 
+func (m *executionManagerImpl) CompleteAndRescheduleTimerTask(
+	ctx context.Context,
+	request *CompleteAndRescheduleTimerTaskRequest,
+) error {
+	if category := request.NextTask.GetCategory(); category != tasks.CategoryTimer {
+		return serviceerror.NewInvalidArgument(fmt.Sprintf("CompleteAndRescheduleTimerTask: next task has category %v, not a timer task", category.Name()))
+	}
+	blob, err := m.serializer.SerializeTask(request.NextTask)
+	if err != nil {
+		return err
+	}
+
+	return m.persistence.CompleteAndRescheduleTimerTask(ctx, &InternalCompleteAndRescheduleTimerTaskRequest{
+		ShardID:          request.ShardID,
+		RangeID:          request.RangeID,
+		CompletedTaskKey: request.CompletedTaskKey,
+		NextTask: InternalHistoryTask{
+			Key:  request.NextTask.GetKey(),
+			Blob: blob,
+		},
+	})
+}
+
+func (m *executionManagerImpl) GetOldestTimerTask(
+	ctx context.Context,
+	request *GetOldestTimerTaskRequest,
+) (*GetOldestTimerTaskResponse, error) {
+	resp, err := m.persistence.GetOldestTimerTask(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Task == nil {
+		return &GetOldestTimerTaskResponse{}, nil
+	}
+
+	task, err := m.serializer.DeserializeTask(tasks.CategoryTimer, resp.Task.Blob)
+	if err != nil {
+		return nil, err
+	}
+	task.SetVisibilityTime(resp.Task.Key.FireTime)
+	task.SetTaskID(resp.Task.Key.TaskID)
+	return &GetOldestTimerTaskResponse{Task: task}, nil
+}
+
+func (m *executionManagerImpl) GetEarliestTimerFireTime(
+	ctx context.Context,
+	request *GetEarliestTimerFireTimeRequest,
+) (*GetEarliestTimerFireTimeResponse, error) {
+	resp, err := m.persistence.GetOldestTimerTask(ctx, &GetOldestTimerTaskRequest{
+		ShardID: request.ShardID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Task == nil {
+		return &GetEarliestTimerFireTimeResponse{}, nil
+	}
+	return &GetEarliestTimerFireTimeResponse{
+		FireTime: resp.Task.Key.FireTime,
+		Found:    true,
+	}, nil
+}
+
 func (m *executionManagerImpl) PutReplicationTaskToDLQ(
 	ctx context.Context,
 	request *PutReplicationTaskToDLQRequest,
@@ -919,6 +1005,8 @@ func (m *executionManagerImpl) GetReplicationTasksFromDLQ(
 	ctx context.Context,
 	request *GetReplicationTasksFromDLQRequest,
 ) (*GetHistoryTasksResponse, error) {
+	ctx, cancel := m.withReadTierTimeout(ctx, request.ReadTier)
+	defer cancel()
 	resp, err := m.persistence.GetReplicationTasksFromDLQ(ctx, request)
 	if err != nil {
 		return nil, err
@@ -947,6 +1035,19 @@ func (m *executionManagerImpl) GetReplicationTasksFromDLQ(
 	}, nil
 }
 
+// withReadTierTimeout bounds ctx by the timeout configured for the read tier, if any.
+func (m *executionManagerImpl) withReadTierTimeout(
+	ctx context.Context,
+	readTier ReadTier,
+) (context.Context, context.CancelFunc) {
+	if timeout := m.readTierTimeouts[readTier]; timeout != nil {
+		if d := timeout(); d > 0 {
+			return context.WithTimeout(ctx, d)
+		}
+	}
+	return ctx, func() {}
+}
+
 func (m *executionManagerImpl) DeleteReplicationTaskFromDLQ(
 	ctx context.Context,
 	request *DeleteReplicationTaskFromDLQRequest,
@@ -0,0 +1,94 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/server/common/dynamicconfig"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/mock"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/service/history/tasks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExecutionManager_GetHistoryTasksReadTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockExecutionStore(ctrl)
+	manager := persistence.NewExecutionManager(
+		store,
+		serialization.NewSerializer(),
+		nil,
+		log.NewTestLogger(),
+		dynamicconfig.GetIntPropertyFn(4*1024*1024),
+		persistence.ReadTierTimeouts{
+			persistence.ReadTierLatencySensitive: dynamicconfig.GetDurationPropertyFn(time.Second),
+			persistence.ReadTierConsistent:       dynamicconfig.GetDurationPropertyFn(time.Hour),
+		},
+	)
+
+	for _, tc := range []struct {
+		name        string
+		readTier    persistence.ReadTier
+		hasDeadline bool
+		timeout     time.Duration
+	}{
+		{name: "default", readTier: persistence.ReadTierDefault},
+		{name: "latency sensitive", readTier: persistence.ReadTierLatencySensitive, hasDeadline: true, timeout: time.Second},
+		{name: "consistent", readTier: persistence.ReadTierConsistent, hasDeadline: true, timeout: time.Hour},
+		{name: "lag tolerant", readTier: persistence.ReadTierLagTolerant},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			store.EXPECT().GetHistoryTasks(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, request *persistence.GetHistoryTasksRequest) (*persistence.InternalGetHistoryTasksResponse, error) {
+					// the store routes the read by its tier
+					require.Equal(t, tc.readTier, request.ReadTier)
+					deadline, ok := ctx.Deadline()
+					require.Equal(t, tc.hasDeadline, ok)
+					if ok {
+						require.WithinRange(t, deadline, start.Add(tc.timeout), time.Now().Add(tc.timeout))
+					}
+					return &persistence.InternalGetHistoryTasksResponse{}, nil
+				},
+			)
+
+			_, err := manager.GetHistoryTasks(context.Background(), &persistence.GetHistoryTasksRequest{
+				ShardID:             1,
+				TaskCategory:        tasks.CategoryTransfer,
+				InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+				ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+				BatchSize:           10,
+				ReadTier:            tc.readTier,
+			})
+			require.NoError(t, err)
+		})
+	}
+}
@@ -73,6 +73,15 @@ func (d faultInjectionExecutionStore) AppendHistoryNodes(ctx context.Context, re
 	return
 }
 
+// CompleteAndRescheduleTimerTask wraps ExecutionStore.CompleteAndRescheduleTimerTask.
+func (d faultInjectionExecutionStore) CompleteAndRescheduleTimerTask(ctx context.Context, request *_sourcePersistence.InternalCompleteAndRescheduleTimerTaskRequest) (err error) {
+	err = d.generator.generate("CompleteAndRescheduleTimerTask").inject(func() error {
+		err = d.ExecutionStore.CompleteAndRescheduleTimerTask(ctx, request)
+		return err
+	})
+	return
+}
+
 // CompleteHistoryTask wraps ExecutionStore.CompleteHistoryTask.
 func (d faultInjectionExecutionStore) CompleteHistoryTask(ctx context.Context, request *_sourcePersistence.CompleteHistoryTaskRequest) (err error) {
 	err = d.generator.generate("CompleteHistoryTask").inject(func() error {
@@ -190,6 +199,15 @@ func (d faultInjectionExecutionStore) GetHistoryTreeContainingBranch(ctx context
 	return
 }
 
+// GetOldestTimerTask wraps ExecutionStore.GetOldestTimerTask.
+func (d faultInjectionExecutionStore) GetOldestTimerTask(ctx context.Context, request *_sourcePersistence.GetOldestTimerTaskRequest) (ip1 *_sourcePersistence.InternalGetOldestTimerTaskResponse, err error) {
+	err = d.generator.generate("GetOldestTimerTask").inject(func() error {
+		ip1, err = d.ExecutionStore.GetOldestTimerTask(ctx, request)
+		return err
+	})
+	return
+}
+
 // GetReplicationTasksFromDLQ wraps ExecutionStore.GetReplicationTasksFromDLQ.
 func (d faultInjectionExecutionStore) GetReplicationTasksFromDLQ(ctx context.Context, request *_sourcePersistence.GetReplicationTasksFromDLQRequest) (ip1 *_sourcePersistence.InternalGetReplicationTasksFromDLQResponse, err error) {
 	err = d.generator.generate("GetReplicationTasksFromDLQ").inject(func() error {
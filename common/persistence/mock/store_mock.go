@@ -929,6 +929,20 @@ func (mr *MockExecutionStoreMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockExecutionStore)(nil).Close))
 }
 
+// CompleteAndRescheduleTimerTask mocks base method.
+func (m *MockExecutionStore) CompleteAndRescheduleTimerTask(ctx context.Context, request *persistence.InternalCompleteAndRescheduleTimerTaskRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteAndRescheduleTimerTask", ctx, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteAndRescheduleTimerTask indicates an expected call of CompleteAndRescheduleTimerTask.
+func (mr *MockExecutionStoreMockRecorder) CompleteAndRescheduleTimerTask(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteAndRescheduleTimerTask", reflect.TypeOf((*MockExecutionStore)(nil).CompleteAndRescheduleTimerTask), ctx, request)
+}
+
 // CompleteHistoryTask mocks base method.
 func (m *MockExecutionStore) CompleteHistoryTask(ctx context.Context, request *persistence.CompleteHistoryTaskRequest) error {
 	m.ctrl.T.Helper()
@@ -1144,6 +1158,21 @@ func (mr *MockExecutionStoreMockRecorder) GetName() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetName", reflect.TypeOf((*MockExecutionStore)(nil).GetName))
 }
 
+// GetOldestTimerTask mocks base method.
+func (m *MockExecutionStore) GetOldestTimerTask(ctx context.Context, request *persistence.GetOldestTimerTaskRequest) (*persistence.InternalGetOldestTimerTaskResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOldestTimerTask", ctx, request)
+	ret0, _ := ret[0].(*persistence.InternalGetOldestTimerTaskResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOldestTimerTask indicates an expected call of GetOldestTimerTask.
+func (mr *MockExecutionStoreMockRecorder) GetOldestTimerTask(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOldestTimerTask", reflect.TypeOf((*MockExecutionStore)(nil).GetOldestTimerTask), ctx, request)
+}
+
 // GetReplicationTasksFromDLQ mocks base method.
 func (m *MockExecutionStore) GetReplicationTasksFromDLQ(ctx context.Context, request *persistence.GetReplicationTasksFromDLQRequest) (*persistence.InternalGetReplicationTasksFromDLQResponse, error) {
 	m.ctrl.T.Helper()
@@ -1204,6 +1233,30 @@ func (mr *MockExecutionStoreMockRecorder) ListConcreteExecutions(ctx, request an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListConcreteExecutions", reflect.TypeOf((*MockExecutionStore)(nil).ListConcreteExecutions), ctx, request)
 }
 
+// MarkShardClosing mocks base method.
+func (m *MockExecutionStore) MarkShardClosing(shardID int32, rangeID int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MarkShardClosing", shardID, rangeID)
+}
+
+// MarkShardClosing indicates an expected call of MarkShardClosing.
+func (mr *MockExecutionStoreMockRecorder) MarkShardClosing(shardID, rangeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkShardClosing", reflect.TypeOf((*MockExecutionStore)(nil).MarkShardClosing), shardID, rangeID)
+}
+
+// MarkShardOpen mocks base method.
+func (m *MockExecutionStore) MarkShardOpen(shardID int32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MarkShardOpen", shardID)
+}
+
+// MarkShardOpen indicates an expected call of MarkShardOpen.
+func (mr *MockExecutionStoreMockRecorder) MarkShardOpen(shardID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkShardOpen", reflect.TypeOf((*MockExecutionStore)(nil).MarkShardOpen), shardID)
+}
+
 // PutReplicationTaskToDLQ mocks base method.
 func (m *MockExecutionStore) PutReplicationTaskToDLQ(ctx context.Context, request *persistence.PutReplicationTaskToDLQRequest) error {
 	m.ctrl.T.Helper()
@@ -151,10 +151,20 @@ type (
 
 		// Tasks related APIs
 
+		// AddHistoryTasks adds all tasks of the request or none of them. Stores may weaken this when configured to,
+		// e.g. the SQL store with AddHistoryTasksSplitThreshold adds large requests one task category at a time,
+		// and a failed request can leave some categories added.
 		AddHistoryTasks(ctx context.Context, request *InternalAddHistoryTasksRequest) error
+		// MarkShardClosing makes AddHistoryTasks of the shard with rangeID or a lower range ID fail fast with
+		// ShardOwnershipLostError, until MarkShardOpen is called for the shard. Stores may ignore it.
+		MarkShardClosing(shardID int32, rangeID int64)
+		// MarkShardOpen clears the closing mark of the shard set by MarkShardClosing.
+		MarkShardOpen(shardID int32)
 		GetHistoryTasks(ctx context.Context, request *GetHistoryTasksRequest) (*InternalGetHistoryTasksResponse, error)
 		CompleteHistoryTask(ctx context.Context, request *CompleteHistoryTaskRequest) error
 		RangeCompleteHistoryTasks(ctx context.Context, request *RangeCompleteHistoryTasksRequest) error
+		CompleteAndRescheduleTimerTask(ctx context.Context, request *InternalCompleteAndRescheduleTimerTaskRequest) error
+		GetOldestTimerTask(ctx context.Context, request *GetOldestTimerTaskRequest) (*InternalGetOldestTimerTaskResponse, error)
 
 		PutReplicationTaskToDLQ(ctx context.Context, request *PutReplicationTaskToDLQRequest) error
 		GetReplicationTasksFromDLQ(ctx context.Context, request *GetReplicationTasksFromDLQRequest) (*InternalGetReplicationTasksFromDLQResponse, error)
@@ -437,6 +447,16 @@ type (
 		Tasks map[tasks.Category][]InternalHistoryTask `json:",omitempty"`
 	}
 
+	// InternalCompleteAndRescheduleTimerTaskRequest is used to replace a timer task with the next timer task
+	// of a recurring timer
+	InternalCompleteAndRescheduleTimerTaskRequest struct {
+		ShardID int32
+		RangeID int64
+
+		CompletedTaskKey tasks.Key
+		NextTask         InternalHistoryTask
+	}
+
 	// InternalWorkflowMutation is used as generic workflow execution state mutation for Persistence Interface
 	InternalWorkflowMutation struct {
 		// TODO: properly set this on call sites
@@ -579,6 +599,12 @@ type (
 
 	InternalGetReplicationTasksFromDLQResponse = InternalGetHistoryTasksResponse
 
+	// InternalGetOldestTimerTaskResponse is the response for GetOldestTimerTask
+	InternalGetOldestTimerTaskResponse struct {
+		// Task is nil if the shard has no timer tasks
+		Task *InternalHistoryTask
+	}
+
 	// InternalForkHistoryBranchRequest is used to fork a history branch
 	InternalForkHistoryBranchRequest struct {
 		// The new branch token to fork to
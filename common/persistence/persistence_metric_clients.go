@@ -367,6 +367,19 @@ func (p *executionPersistenceClient) AddHistoryTasks(
 	return p.persistence.AddHistoryTasks(ctx, request)
 }
 
+func (p *executionPersistenceClient) MarkShardClosing(
+	shardID int32,
+	rangeID int64,
+) {
+	p.persistence.MarkShardClosing(shardID, rangeID)
+}
+
+func (p *executionPersistenceClient) MarkShardOpen(
+	shardID int32,
+) {
+	p.persistence.MarkShardOpen(shardID)
+}
+
 func (p *executionPersistenceClient) GetHistoryTasks(
 	ctx context.Context,
 	request *GetHistoryTasksRequest,
@@ -460,6 +473,45 @@ func (p *executionPersistenceClient) RangeCompleteHistoryTasks(
 	return p.persistence.RangeCompleteHistoryTasks(ctx, request)
 }
 
+func (p *executionPersistenceClient) CompleteAndRescheduleTimerTask(
+	ctx context.Context,
+	request *CompleteAndRescheduleTimerTaskRequest,
+) (retErr error) {
+	caller := headers.GetCallerInfo(ctx).CallerName
+	startTime := time.Now().UTC()
+	defer func() {
+		p.healthSignals.Record(request.ShardID, caller, time.Since(startTime), retErr)
+		p.recordRequestMetrics(metrics.PersistenceCompleteAndRescheduleTimerTaskScope, caller, time.Since(startTime), retErr)
+	}()
+	return p.persistence.CompleteAndRescheduleTimerTask(ctx, request)
+}
+
+func (p *executionPersistenceClient) GetOldestTimerTask(
+	ctx context.Context,
+	request *GetOldestTimerTaskRequest,
+) (_ *GetOldestTimerTaskResponse, retErr error) {
+	caller := headers.GetCallerInfo(ctx).CallerName
+	startTime := time.Now().UTC()
+	defer func() {
+		p.healthSignals.Record(request.ShardID, caller, time.Since(startTime), retErr)
+		p.recordRequestMetrics(metrics.PersistenceGetOldestTimerTaskScope, caller, time.Since(startTime), retErr)
+	}()
+	return p.persistence.GetOldestTimerTask(ctx, request)
+}
+
+func (p *executionPersistenceClient) GetEarliestTimerFireTime(
+	ctx context.Context,
+	request *GetEarliestTimerFireTimeRequest,
+) (_ *GetEarliestTimerFireTimeResponse, retErr error) {
+	caller := headers.GetCallerInfo(ctx).CallerName
+	startTime := time.Now().UTC()
+	defer func() {
+		p.healthSignals.Record(request.ShardID, caller, time.Since(startTime), retErr)
+		p.recordRequestMetrics(metrics.PersistenceGetEarliestTimerFireTimeScope, caller, time.Since(startTime), retErr)
+	}()
+	return p.persistence.GetEarliestTimerFireTime(ctx, request)
+}
+
 func (p *executionPersistenceClient) PutReplicationTaskToDLQ(
 	ctx context.Context,
 	request *PutReplicationTaskToDLQRequest,
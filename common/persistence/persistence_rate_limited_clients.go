@@ -414,6 +414,19 @@ func (p *executionRateLimitedPersistenceClient) AddHistoryTasks(
 	return p.persistence.AddHistoryTasks(ctx, request)
 }
 
+func (p *executionRateLimitedPersistenceClient) MarkShardClosing(
+	shardID int32,
+	rangeID int64,
+) {
+	p.persistence.MarkShardClosing(shardID, rangeID)
+}
+
+func (p *executionRateLimitedPersistenceClient) MarkShardOpen(
+	shardID int32,
+) {
+	p.persistence.MarkShardOpen(shardID)
+}
+
 func (p *executionRateLimitedPersistenceClient) GetHistoryTasks(
 	ctx context.Context,
 	request *GetHistoryTasksRequest,
@@ -469,6 +482,36 @@ func (p *executionRateLimitedPersistenceClient) RangeCompleteHistoryTasks(
 	return p.persistence.RangeCompleteHistoryTasks(ctx, request)
 }
 
+func (p *executionRateLimitedPersistenceClient) CompleteAndRescheduleTimerTask(
+	ctx context.Context,
+	request *CompleteAndRescheduleTimerTaskRequest,
+) error {
+	if err := allow(ctx, "CompleteAndRescheduleTimerTask", request.ShardID, p.systemRateLimiter, p.namespaceRateLimiter, p.shardRateLimiter); err != nil {
+		return err
+	}
+	return p.persistence.CompleteAndRescheduleTimerTask(ctx, request)
+}
+
+func (p *executionRateLimitedPersistenceClient) GetOldestTimerTask(
+	ctx context.Context,
+	request *GetOldestTimerTaskRequest,
+) (*GetOldestTimerTaskResponse, error) {
+	if err := allow(ctx, "GetOldestTimerTask", request.ShardID, p.systemRateLimiter, p.namespaceRateLimiter, p.shardRateLimiter); err != nil {
+		return nil, err
+	}
+	return p.persistence.GetOldestTimerTask(ctx, request)
+}
+
+func (p *executionRateLimitedPersistenceClient) GetEarliestTimerFireTime(
+	ctx context.Context,
+	request *GetEarliestTimerFireTimeRequest,
+) (*GetEarliestTimerFireTimeResponse, error) {
+	if err := allow(ctx, "GetEarliestTimerFireTime", request.ShardID, p.systemRateLimiter, p.namespaceRateLimiter, p.shardRateLimiter); err != nil {
+		return nil, err
+	}
+	return p.persistence.GetEarliestTimerFireTime(ctx, request)
+}
+
 func (p *executionRateLimitedPersistenceClient) PutReplicationTaskToDLQ(
 	ctx context.Context,
 	request *PutReplicationTaskToDLQRequest,
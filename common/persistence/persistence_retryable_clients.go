@@ -366,6 +366,19 @@ func (p *executionRetryablePersistenceClient) AddHistoryTasks(
 	return backoff.ThrottleRetryContext(ctx, op, p.policy, p.isRetryable)
 }
 
+func (p *executionRetryablePersistenceClient) MarkShardClosing(
+	shardID int32,
+	rangeID int64,
+) {
+	p.persistence.MarkShardClosing(shardID, rangeID)
+}
+
+func (p *executionRetryablePersistenceClient) MarkShardOpen(
+	shardID int32,
+) {
+	p.persistence.MarkShardOpen(shardID)
+}
+
 func (p *executionRetryablePersistenceClient) GetHistoryTasks(
 	ctx context.Context,
 	request *GetHistoryTasksRequest,
@@ -403,6 +416,47 @@ func (p *executionRetryablePersistenceClient) RangeCompleteHistoryTasks(
 	return backoff.ThrottleRetryContext(ctx, op, p.policy, p.isRetryable)
 }
 
+func (p *executionRetryablePersistenceClient) CompleteAndRescheduleTimerTask(
+	ctx context.Context,
+	request *CompleteAndRescheduleTimerTaskRequest,
+) error {
+	op := func(ctx context.Context) error {
+		return p.persistence.CompleteAndRescheduleTimerTask(ctx, request)
+	}
+
+	return backoff.ThrottleRetryContext(ctx, op, p.policy, p.isRetryable)
+}
+
+func (p *executionRetryablePersistenceClient) GetOldestTimerTask(
+	ctx context.Context,
+	request *GetOldestTimerTaskRequest,
+) (*GetOldestTimerTaskResponse, error) {
+	var response *GetOldestTimerTaskResponse
+	op := func(ctx context.Context) error {
+		var err error
+		response, err = p.persistence.GetOldestTimerTask(ctx, request)
+		return err
+	}
+
+	err := backoff.ThrottleRetryContext(ctx, op, p.policy, p.isRetryable)
+	return response, err
+}
+
+func (p *executionRetryablePersistenceClient) GetEarliestTimerFireTime(
+	ctx context.Context,
+	request *GetEarliestTimerFireTimeRequest,
+) (*GetEarliestTimerFireTimeResponse, error) {
+	var response *GetEarliestTimerFireTimeResponse
+	op := func(ctx context.Context) error {
+		var err error
+		response, err = p.persistence.GetEarliestTimerFireTime(ctx, request)
+		return err
+	}
+
+	err := backoff.ThrottleRetryContext(ctx, op, p.policy, p.isRetryable)
+	return response, err
+}
+
 func (p *executionRetryablePersistenceClient) PutReplicationTaskToDLQ(
 	ctx context.Context,
 	request *PutReplicationTaskToDLQRequest,
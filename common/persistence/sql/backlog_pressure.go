@@ -0,0 +1,106 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"sync"
+
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+// backlogPressureWarningsPerSecond is the rate backlog pressure warnings of all shards are logged at
+const backlogPressureWarningsPerSecond = 1
+
+type (
+	// backlogPressureDetector tracks the consecutive full pages returned by history task reads of every shard and
+	// task category, and reports shards whose reads return more consecutive full pages than threshold, as their
+	// task processing is falling behind.
+	backlogPressureDetector struct {
+		threshold      int
+		logger         log.Logger
+		metricsHandler metrics.Handler
+
+		sync.Mutex
+		// fullPages only holds the shards and categories whose last read returned a full page
+		fullPages map[backlogPressureKey]int
+	}
+
+	backlogPressureKey struct {
+		shardID  int32
+		category tasks.Category
+	}
+)
+
+// newBacklogPressureDetector returns nil if threshold isn't positive, which disables the detection.
+func newBacklogPressureDetector(
+	threshold int,
+	logger log.Logger,
+	metricsHandler metrics.Handler,
+) *backlogPressureDetector {
+	if threshold <= 0 {
+		return nil
+	}
+	return &backlogPressureDetector{
+		threshold:      threshold,
+		logger:         log.NewThrottledLogger(logger, func() float64 { return backlogPressureWarningsPerSecond }),
+		metricsHandler: metricsHandler,
+		fullPages:      make(map[backlogPressureKey]int),
+	}
+}
+
+// recordRead records whether a read of the shard's tasks of category returned a full page.
+func (d *backlogPressureDetector) recordRead(
+	shardID int32,
+	category tasks.Category,
+	fullPage bool,
+) {
+	if d == nil {
+		return
+	}
+
+	key := backlogPressureKey{shardID: shardID, category: category}
+	d.Lock()
+	if !fullPage {
+		delete(d.fullPages, key)
+		d.Unlock()
+		return
+	}
+	d.fullPages[key]++
+	fullPages := d.fullPages[key]
+	d.Unlock()
+
+	if fullPages <= d.threshold {
+		return
+	}
+	metrics.PersistenceTaskBacklogPressure.With(d.metricsHandler).Record(1, metrics.TaskCategoryTag(category.Name()))
+	d.logger.Warn("History task reads of shard keep returning full pages, task processing may be falling behind",
+		tag.ShardID(shardID),
+		tag.TaskCategoryID(category.ID()),
+		tag.Counter(fullPages),
+	)
+}
@@ -70,6 +70,15 @@ func (m *SqlStore) Close() {
 	}
 }
 
+// readOnlyDb returns the database for reads that tolerate replication lag. It is the read replica if the plugin
+// supports one and it is configured, see sqlplugin.ReadOnlyDB, and Db otherwise.
+func (m *SqlStore) readOnlyDb() sqlplugin.DB {
+	if db, ok := m.Db.(sqlplugin.ReadOnlyDB); ok {
+		return db.ReadOnly()
+	}
+	return m.Db
+}
+
 func (m *SqlStore) txExecute(ctx context.Context, operation string, f func(tx sqlplugin.Tx) error) error {
 	tx, err := m.Db.BeginTx(ctx)
 	if err != nil {
@@ -28,13 +28,19 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.temporal.io/api/serviceerror"
 	enumsspb "go.temporal.io/server/api/enums/v1"
 	persistencespb "go.temporal.io/server/api/persistence/v1"
+	"go.temporal.io/server/common/clock"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/dynamicconfig"
 	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
 	p "go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/persistence/sql/sqlplugin"
 	"go.temporal.io/server/common/primitives"
@@ -43,6 +49,33 @@ import (
 type sqlExecutionStore struct {
 	SqlStore
 	p.HistoryBranchUtilImpl
+
+	metricsHandler          metrics.Handler
+	timeSource              clock.TimeSource
+	lenientPageTokens       bool
+	urlSafePageTokens       bool
+	binaryPageTokens        bool
+	taskCategoryValidation  string
+	partialResultsPageSize  int
+	defaultTaskDataEncoding string
+	taskDataChecksums       string
+	// shardLockedRangeCompletes makes range completes run in a transaction holding a read lock on the shard row
+	shardLockedRangeCompletes bool
+	// timerTaskDeleteBatchSize is read before every batch, so that it can be changed during a range completion
+	timerTaskDeleteBatchSize dynamicconfig.IntPropertyFn
+	replicationDLQ           ReplicationDLQStore
+	// strictTaskOrder makes history task reads check the order of their tasks, see enforceTaskOrder
+	strictTaskOrder bool
+	// addHistoryTasksSplitThreshold is the number of tasks above which AddHistoryTasks uses a transaction per
+	// task category, disabled when zero
+	addHistoryTasksSplitThreshold int
+	// backlogPressure is nil if backlog pressure detection is disabled
+	backlogPressure *backlogPressureDetector
+	// readAhead is nil if reading transfer tasks ahead is disabled
+	readAhead *taskReadAheadCache
+
+	closingShardsLock sync.RWMutex
+	closingShards     map[int32]int64
 }
 
 var _ p.ExecutionStore = (*sqlExecutionStore)(nil)
@@ -50,15 +83,63 @@ var _ p.ExecutionStore = (*sqlExecutionStore)(nil)
 // NewSQLExecutionStore creates an instance of ExecutionStore
 func NewSQLExecutionStore(
 	db sqlplugin.DB,
+	cfg *config.SQL,
 	logger log.Logger,
+	metricsHandler metrics.Handler,
 ) (p.ExecutionStore, error) {
 
+	return newSQLExecutionStore(db, cfg, logger, metricsHandler), nil
+}
+
+// NewSQLExecutionStoreWithReplicationDLQ creates an instance of ExecutionStore that stores the replication DLQ
+// in replicationDLQ instead of the replication_tasks_dlq table
+func NewSQLExecutionStoreWithReplicationDLQ(
+	db sqlplugin.DB,
+	cfg *config.SQL,
+	replicationDLQ ReplicationDLQStore,
+	logger log.Logger,
+	metricsHandler metrics.Handler,
+) (p.ExecutionStore, error) {
+
+	store := newSQLExecutionStore(db, cfg, logger, metricsHandler)
+	store.replicationDLQ = replicationDLQ
+	return store, nil
+}
+
+func newSQLExecutionStore(
+	db sqlplugin.DB,
+	cfg *config.SQL,
+	logger log.Logger,
+	metricsHandler metrics.Handler,
+) *sqlExecutionStore {
+	timerTaskDeleteBatchSize := cfg.TimerTaskRangeDeleteBatchSize
+	if timerTaskDeleteBatchSize == nil {
+		timerTaskDeleteBatchSize = dynamicconfig.GetIntPropertyFn(0)
+	}
 	return &sqlExecutionStore{
-		SqlStore: NewSqlStore(db, logger),
-	}, nil
+		SqlStore:                      NewSqlStore(db, logger),
+		metricsHandler:                metricsHandler,
+		timeSource:                    clock.NewRealTimeSource(),
+		lenientPageTokens:             cfg.LenientPageTokens,
+		urlSafePageTokens:             cfg.URLSafePageTokens,
+		binaryPageTokens:              cfg.BinaryPageTokens,
+		taskCategoryValidation:        cfg.TaskCategoryValidation,
+		partialResultsPageSize:        cfg.PartialResultsPageSize,
+		defaultTaskDataEncoding:       cfg.DefaultTaskDataEncoding,
+		taskDataChecksums:             cfg.TaskDataChecksums,
+		shardLockedRangeCompletes:     cfg.ShardLockedRangeCompletes,
+		timerTaskDeleteBatchSize:      timerTaskDeleteBatchSize,
+		replicationDLQ:                newSQLReplicationDLQStore(db),
+		strictTaskOrder:               cfg.StrictTaskOrder,
+		addHistoryTasksSplitThreshold: cfg.AddHistoryTasksSplitThreshold,
+		backlogPressure:               newBacklogPressureDetector(cfg.BacklogPressureFullPageThreshold, logger, metricsHandler),
+		readAhead:                     newTaskReadAheadCache(cfg.TransferTaskReadAheadSize),
+		closingShards:                 make(map[int32]int64),
+	}
 }
 
-// txExecuteShardLocked executes f under transaction and with read lock on shard row
+// txExecuteShardLocked executes f under transaction and with read lock on shard row.
+// Errors of f wrapping errInvalidTaskData are returned as Internal, all other errors as txExecute returns them.
 func (m *sqlExecutionStore) txExecuteShardLocked(
 	ctx context.Context,
 	operation string,
@@ -67,16 +148,24 @@ func (m *sqlExecutionStore) txExecuteShardLocked(
 	fn func(tx sqlplugin.Tx) error,
 ) error {
 
-	return m.txExecute(ctx, operation, func(tx sqlplugin.Tx) error {
+	var invalidTaskDataErr error
+	err := m.txExecute(ctx, operation, func(tx sqlplugin.Tx) error {
 		if err := readLockShard(ctx, tx, shardID, rangeID); err != nil {
 			return err
 		}
 		err := fn(tx)
 		if err != nil {
+			if errors.Is(err, errInvalidTaskData) {
+				invalidTaskDataErr = err
+			}
 			return err
 		}
 		return nil
 	})
+	if invalidTaskDataErr != nil {
+		return serviceerror.NewInternal(fmt.Sprintf("%v: %v", operation, invalidTaskDataErr))
+	}
+	return err
 }
 
 func (m *sqlExecutionStore) CreateWorkflowExecution(
@@ -444,7 +533,7 @@ func (m *sqlExecutionStore) updateWorkflowExecutionTx(
 		return serviceerror.NewUnavailable(fmt.Sprintf("UpdateWorkflowExecution: unknown mode: %v", request.Mode))
 	}
 
-	if err := applyWorkflowMutationTx(ctx, tx, shardID, &updateWorkflow); err != nil {
+	if err := m.applyWorkflowMutationTx(ctx, tx, shardID, &updateWorkflow); err != nil {
 		return err
 	}
 
@@ -555,7 +644,7 @@ func (m *sqlExecutionStore) conflictResolveWorkflowExecutionTx(
 		return serviceerror.NewUnavailable(fmt.Sprintf("ConflictResolveWorkflowExecution: unknown mode: %v", request.Mode))
 	}
 
-	if err := applyWorkflowSnapshotTxAsReset(ctx,
+	if err := m.applyWorkflowSnapshotTxAsReset(ctx,
 		tx,
 		shardID,
 		&resetWorkflow,
@@ -564,7 +653,7 @@ func (m *sqlExecutionStore) conflictResolveWorkflowExecutionTx(
 	}
 
 	if currentWorkflow != nil {
-		if err := applyWorkflowMutationTx(ctx,
+		if err := m.applyWorkflowMutationTx(ctx,
 			tx,
 			shardID,
 			currentWorkflow,
@@ -734,7 +823,7 @@ func (m *sqlExecutionStore) setWorkflowExecutionTx(
 	shardID := request.ShardID
 	setSnapshot := request.SetWorkflowSnapshot
 
-	return applyWorkflowSnapshotTxAsReset(ctx,
+	return m.applyWorkflowSnapshotTxAsReset(ctx,
 		tx,
 		shardID,
 		&setSnapshot,
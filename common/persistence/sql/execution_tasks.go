@@ -25,78 +25,595 @@
 package sql
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"math"
+	"slices"
+	"strings"
 	"time"
 
+	commonpb "go.temporal.io/api/common/v1"
 	"go.temporal.io/api/serviceerror"
+	enumsspb "go.temporal.io/server/api/enums/v1"
+	"go.temporal.io/server/common/log/tag"
+	"go.temporal.io/server/common/metrics"
 	p "go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/persistence/serialization"
 	"go.temporal.io/server/common/persistence/sql/sqlplugin"
 	"go.temporal.io/server/service/history/tasks"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
+const (
+	taskCategoryValidationError = "error"
+	taskCategoryValidationSkip  = "skip"
+
+	taskDataChecksumsError = "error"
+	taskDataChecksumsSkip  = "skip"
+
+	// scheduledTaskPageTokenV2 is the first byte of binary scheduled task page tokens
+	scheduledTaskPageTokenV2 byte = 0x02
+
+	// completeTransferTasksChunkSize is the maximum number of task IDs GetAndCompleteTransferTasks deletes with one
+	// statement, to stay below the bind parameter limit of the databases
+	completeTransferTasksChunkSize = 500
+)
+
+var (
+	// legacyTaskTypeDecoders decodes the task type of tasks stored in the legacy per-category tables
+	legacyTaskTypeDecoders = map[int]func(blob *commonpb.DataBlob) (enumsspb.TaskType, error){
+		tasks.CategoryIDTransfer: func(blob *commonpb.DataBlob) (enumsspb.TaskType, error) {
+			info, err := serialization.TransferTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetTaskType(), err
+		},
+		tasks.CategoryIDTimer: func(blob *commonpb.DataBlob) (enumsspb.TaskType, error) {
+			info, err := serialization.TimerTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetTaskType(), err
+		},
+		tasks.CategoryIDReplication: func(blob *commonpb.DataBlob) (enumsspb.TaskType, error) {
+			info, err := serialization.ReplicationTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetTaskType(), err
+		},
+		tasks.CategoryIDVisibility: func(blob *commonpb.DataBlob) (enumsspb.TaskType, error) {
+			info, err := serialization.VisibilityTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetTaskType(), err
+		},
+	}
+
+	// legacyTaskNamespaceDecoders decode the namespace ID of tasks stored in the legacy per-category tables
+	legacyTaskNamespaceDecoders = map[int]func(blob *commonpb.DataBlob) (string, error){
+		tasks.CategoryIDTransfer: func(blob *commonpb.DataBlob) (string, error) {
+			info, err := serialization.TransferTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetNamespaceId(), err
+		},
+		tasks.CategoryIDTimer: func(blob *commonpb.DataBlob) (string, error) {
+			info, err := serialization.TimerTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetNamespaceId(), err
+		},
+		tasks.CategoryIDReplication: func(blob *commonpb.DataBlob) (string, error) {
+			info, err := serialization.ReplicationTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetNamespaceId(), err
+		},
+		tasks.CategoryIDVisibility: func(blob *commonpb.DataBlob) (string, error) {
+			info, err := serialization.VisibilityTaskInfoFromBlob(blob.Data, blob.EncodingType.String())
+			return info.GetNamespaceId(), err
+		},
+	}
+
+	// taskCategoryIDByType maps the task types stored in the legacy per-category tables to their category
+	taskCategoryIDByType = map[enumsspb.TaskType]int{
+		enumsspb.TASK_TYPE_TRANSFER_WORKFLOW_TASK:         tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_ACTIVITY_TASK:         tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_CLOSE_EXECUTION:       tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_CANCEL_EXECUTION:      tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_START_CHILD_EXECUTION: tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_SIGNAL_EXECUTION:      tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_RESET_WORKFLOW:        tasks.CategoryIDTransfer,
+		enumsspb.TASK_TYPE_TRANSFER_DELETE_EXECUTION:      tasks.CategoryIDTransfer,
+
+		enumsspb.TASK_TYPE_WORKFLOW_TASK_TIMEOUT:      tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_ACTIVITY_TIMEOUT:           tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_USER_TIMER:                 tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_WORKFLOW_RUN_TIMEOUT:       tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_WORKFLOW_EXECUTION_TIMEOUT: tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_DELETE_HISTORY_EVENT:       tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_ACTIVITY_RETRY_TIMER:       tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_WORKFLOW_BACKOFF_TIMER:     tasks.CategoryIDTimer,
+		enumsspb.TASK_TYPE_STATE_MACHINE_TIMER:        tasks.CategoryIDTimer,
+
+		enumsspb.TASK_TYPE_REPLICATION_HISTORY:                   tasks.CategoryIDReplication,
+		enumsspb.TASK_TYPE_REPLICATION_SYNC_ACTIVITY:             tasks.CategoryIDReplication,
+		enumsspb.TASK_TYPE_REPLICATION_SYNC_WORKFLOW_STATE:       tasks.CategoryIDReplication,
+		enumsspb.TASK_TYPE_REPLICATION_SYNC_HSM:                  tasks.CategoryIDReplication,
+		enumsspb.TASK_TYPE_REPLICATION_SYNC_VERSIONED_TRANSITION: tasks.CategoryIDReplication,
+
+		enumsspb.TASK_TYPE_VISIBILITY_START_EXECUTION:  tasks.CategoryIDVisibility,
+		enumsspb.TASK_TYPE_VISIBILITY_UPSERT_EXECUTION: tasks.CategoryIDVisibility,
+		enumsspb.TASK_TYPE_VISIBILITY_CLOSE_EXECUTION:  tasks.CategoryIDVisibility,
+		enumsspb.TASK_TYPE_VISIBILITY_DELETE_EXECUTION: tasks.CategoryIDVisibility,
+	}
+)
+
+// AddHistoryTasks adds the tasks of request in one transaction, so that either all or none of them are added.
+// When the request has more tasks than the configured AddHistoryTasksSplitThreshold, the guarantee is weaker:
+// the tasks are added in one transaction per task category, and a failure can leave the categories before it
+// added. The error then lists the categories that were added, see partialAddHistoryTasksError.
 func (m *sqlExecutionStore) AddHistoryTasks(
 	ctx context.Context,
 	request *p.InternalAddHistoryTasksRequest,
+) error {
+	if m.isShardClosing(request.ShardID, request.RangeID) {
+		return &p.ShardOwnershipLostError{
+			ShardID: request.ShardID,
+			Msg:     fmt.Sprintf("Failed to add history tasks. Shard %v is closing.", request.ShardID),
+		}
+	}
+
+	if m.addHistoryTasksSplitThreshold > 0 &&
+		len(request.Tasks) > 1 &&
+		countHistoryTasks(request.Tasks) > m.addHistoryTasksSplitThreshold {
+		return m.addHistoryTasksByCategory(ctx, request)
+	}
+
+	return m.addHistoryTasksTx(ctx, request.ShardID, request.RangeID, request.Tasks)
+}
+
+// addHistoryTasksTx adds historyTasks in one transaction that read locks the shard row and checks the range ID.
+// Tasks whose key already exists with the same data are skipped: tasks are immutable, so such a task was added
+// before, e.g. by a retried request. A task whose key exists with different data fails the transaction.
+// Existing tasks are selected before the insert, since a failed insert aborts the transaction on PostgreSQL.
+func (m *sqlExecutionStore) addHistoryTasksTx(
+	ctx context.Context,
+	shardID int32,
+	rangeID int64,
+	historyTasks map[tasks.Category][]p.InternalHistoryTask,
 ) error {
 	return m.txExecuteShardLocked(ctx,
 		"AddHistoryTasks",
-		request.ShardID,
-		request.RangeID,
+		shardID,
+		rangeID,
 		func(tx sqlplugin.Tx) error {
-			return applyTasks(ctx,
+			newTasks, err := skipExistingTasks(ctx, tx, shardID, historyTasks)
+			if err != nil {
+				return err
+			}
+			return m.applyTasks(ctx,
 				tx,
-				request.ShardID,
-				request.Tasks,
+				shardID,
+				newTasks,
 			)
 		})
 }
 
+// addHistoryTasksByCategory adds the tasks of request in one transaction per task category, in category ID
+// order, each of which read locks the shard row and checks the range ID. It stops at the first category that
+// fails, keeping the categories added before; if there are any, the error is a partialAddHistoryTasksError.
+func (m *sqlExecutionStore) addHistoryTasksByCategory(
+	ctx context.Context,
+	request *p.InternalAddHistoryTasksRequest,
+) error {
+	var applied []tasks.Category
+	for _, category := range sortedTaskCategories(request.Tasks) {
+		err := m.addHistoryTasksTx(ctx,
+			request.ShardID,
+			request.RangeID,
+			map[tasks.Category][]p.InternalHistoryTask{category: request.Tasks[category]},
+		)
+		if err != nil {
+			if len(applied) == 0 {
+				return err
+			}
+			return &partialAddHistoryTasksError{applied: applied, err: err}
+		}
+		applied = append(applied, category)
+	}
+	return nil
+}
+
+// partialAddHistoryTasksError is the failure of an AddHistoryTasks request split by task category after some
+// categories were added. It lists the added categories, whose tasks stay added, and wraps the error of the
+// category that failed.
+type partialAddHistoryTasksError struct {
+	applied []tasks.Category
+	err     error
+}
+
+func (e *partialAddHistoryTasksError) Error() string {
+	names := make([]string, len(e.applied))
+	for i, category := range e.applied {
+		names[i] = category.Name()
+	}
+	return fmt.Sprintf("AddHistoryTasks failed after adding the tasks of categories [%v]. Error: %v",
+		strings.Join(names, ", "), e.err)
+}
+
+func (e *partialAddHistoryTasksError) Unwrap() error {
+	return e.err
+}
+
+func countHistoryTasks(historyTasks map[tasks.Category][]p.InternalHistoryTask) int {
+	var count int
+	for _, categoryTasks := range historyTasks {
+		count += len(categoryTasks)
+	}
+	return count
+}
+
+// skipExistingTasks returns historyTasks without the tasks whose key exists in tx with the same data. A task key
+// that exists with different data means that the task ID was allocated twice, and fails with an error wrapping
+// errInvalidTaskData.
+func skipExistingTasks(
+	ctx context.Context,
+	tx sqlplugin.Tx,
+	shardID int32,
+	historyTasks map[tasks.Category][]p.InternalHistoryTask,
+) (map[tasks.Category][]p.InternalHistoryTask, error) {
+	newTasks := make(map[tasks.Category][]p.InternalHistoryTask, len(historyTasks))
+	for _, category := range sortedTaskCategories(historyTasks) {
+		categoryTasks := historyTasks[category]
+		if len(categoryTasks) == 0 {
+			continue
+		}
+		existing, err := selectExistingTasks(ctx, tx, shardID, category, categoryTasks)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range categoryTasks {
+			row, ok := existing[task.Key.TaskID]
+			if !ok {
+				newTasks[category] = append(newTasks[category], task)
+				continue
+			}
+			if !bytes.Equal(row.data, task.Blob.Data) || row.encoding != task.Blob.EncodingType.String() {
+				return nil, fmt.Errorf("%w: %v task %v already exists in shard %v with different data",
+					errInvalidTaskData, category.Name(), task.Key, shardID)
+			}
+		}
+	}
+	return newTasks, nil
+}
+
+// existingTask is the data of a task row selected by selectExistingTasks
+type existingTask struct {
+	data     []byte
+	encoding string
+}
+
+// selectExistingTasks returns the rows of the tasks of a category that exist in tx, by task ID. Immediate tasks
+// are selected with one range select over their task IDs, and scheduled tasks with a select per task, since the
+// scheduled tables are ordered by visibility timestamp first.
+func selectExistingTasks(
+	ctx context.Context,
+	tx sqlplugin.Tx,
+	shardID int32,
+	category tasks.Category,
+	categoryTasks []p.InternalHistoryTask,
+) (map[int64]existingTask, error) {
+	existing := make(map[int64]existingTask)
+	if category.Type() == tasks.CategoryTypeScheduled {
+		for _, task := range categoryTasks {
+			row, ok, err := selectScheduledTask(ctx, tx, shardID, category.ID(), task.Key)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				existing[task.Key.TaskID] = row
+			}
+		}
+		return existing, nil
+	}
+
+	minTaskID, maxTaskID := taskIDRange(categoryTasks)
+	pageSize := int(maxTaskID - minTaskID + 1)
+	var err error
+	// These task categories exist before the general history_immediate_tasks table is created,
+	// so they have their own tables.
+	switch category.ID() {
+	case tasks.CategoryIDTransfer:
+		var rows []sqlplugin.TransferTasksRow
+		rows, err = tx.RangeSelectFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+			ShardID:            shardID,
+			InclusiveMinTaskID: minTaskID,
+			ExclusiveMaxTaskID: maxTaskID + 1,
+			PageSize:           pageSize,
+		})
+		for _, row := range rows {
+			existing[row.TaskID] = existingTask{data: row.Data, encoding: row.DataEncoding}
+		}
+	case tasks.CategoryIDVisibility:
+		var rows []sqlplugin.VisibilityTasksRow
+		rows, err = tx.RangeSelectFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+			ShardID:            shardID,
+			InclusiveMinTaskID: minTaskID,
+			ExclusiveMaxTaskID: maxTaskID + 1,
+			PageSize:           pageSize,
+		})
+		for _, row := range rows {
+			existing[row.TaskID] = existingTask{data: row.Data, encoding: row.DataEncoding}
+		}
+	case tasks.CategoryIDReplication:
+		var rows []sqlplugin.ReplicationTasksRow
+		rows, err = tx.RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+			ShardID:            shardID,
+			InclusiveMinTaskID: minTaskID,
+			ExclusiveMaxTaskID: maxTaskID + 1,
+			PageSize:           pageSize,
+		})
+		for _, row := range rows {
+			existing[row.TaskID] = existingTask{data: row.Data, encoding: row.DataEncoding}
+		}
+	default:
+		var rows []sqlplugin.HistoryImmediateTasksRow
+		rows, err = tx.RangeSelectFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksRangeFilter{
+			ShardID:            shardID,
+			CategoryID:         int32(category.ID()),
+			InclusiveMinTaskID: minTaskID,
+			ExclusiveMaxTaskID: maxTaskID + 1,
+			PageSize:           pageSize,
+		})
+		for _, row := range rows {
+			existing[row.TaskID] = existingTask{data: row.Data, encoding: row.DataEncoding}
+		}
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("AddHistoryTasks failed. Failed to select existing %v tasks. Error: %v", category.Name(), err))
+	}
+	return existing, nil
+}
+
+// selectScheduledTask returns the row of the scheduled task with key, if it exists in tx
+func selectScheduledTask(
+	ctx context.Context,
+	tx sqlplugin.Tx,
+	shardID int32,
+	categoryID int,
+	key tasks.Key,
+) (existingTask, bool, error) {
+	// the range holds the tasks with the visibility timestamp of key, which is stored with microsecond precision,
+	// from the task ID of key on
+	exclusiveMaxVisibilityTimestamp := key.FireTime.Truncate(time.Microsecond).Add(time.Microsecond)
+	var row existingTask
+	var taskID int64
+	var found bool
+	var err error
+	// This is for backward compatiblity.
+	// These task categories exists before the general history_scheduled_tasks table is created,
+	// so they have their own tables.
+	if categoryID == tasks.CategoryIDTimer {
+		var rows []sqlplugin.TimerTasksRow
+		rows, err = tx.RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+			ShardID:                         shardID,
+			InclusiveMinTaskID:              key.TaskID,
+			InclusiveMinVisibilityTimestamp: key.FireTime,
+			ExclusiveMaxVisibilityTimestamp: exclusiveMaxVisibilityTimestamp,
+			PageSize:                        1,
+		})
+		if found = len(rows) == 1; found {
+			taskID, row = rows[0].TaskID, existingTask{data: rows[0].Data, encoding: rows[0].DataEncoding}
+		}
+	} else {
+		var rows []sqlplugin.HistoryScheduledTasksRow
+		rows, err = tx.RangeSelectFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksRangeFilter{
+			ShardID:                         shardID,
+			CategoryID:                      int32(categoryID),
+			InclusiveMinTaskID:              key.TaskID,
+			InclusiveMinVisibilityTimestamp: key.FireTime,
+			ExclusiveMaxVisibilityTimestamp: exclusiveMaxVisibilityTimestamp,
+			PageSize:                        1,
+		})
+		if found = len(rows) == 1; found {
+			taskID, row = rows[0].TaskID, existingTask{data: rows[0].Data, encoding: rows[0].DataEncoding}
+		}
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return existingTask{}, false, serviceerror.NewUnavailable(fmt.Sprintf("AddHistoryTasks failed. Failed to select existing scheduled tasks. Error: %v", err))
+	}
+	return row, found && taskID == key.TaskID, nil
+}
+
+// MarkShardClosing makes AddHistoryTasks with rangeID or a lower range ID fail fast with ShardOwnershipLostError
+// for the shard, without starting a transaction, until MarkShardOpen is called for it. Writes with a higher
+// range ID come from a newer owner of the shard and are not affected.
+func (m *sqlExecutionStore) MarkShardClosing(shardID int32, rangeID int64) {
+	m.closingShardsLock.Lock()
+	defer m.closingShardsLock.Unlock()
+	if closingRangeID, ok := m.closingShards[shardID]; !ok || closingRangeID < rangeID {
+		m.closingShards[shardID] = rangeID
+	}
+}
+
+// MarkShardOpen clears the closing mark set by MarkShardClosing, e.g. when the shard is reacquired.
+func (m *sqlExecutionStore) MarkShardOpen(shardID int32) {
+	m.closingShardsLock.Lock()
+	defer m.closingShardsLock.Unlock()
+	delete(m.closingShards, shardID)
+}
+
+func (m *sqlExecutionStore) isShardClosing(shardID int32, rangeID int64) bool {
+	m.closingShardsLock.RLock()
+	defer m.closingShardsLock.RUnlock()
+	closingRangeID, ok := m.closingShards[shardID]
+	return ok && rangeID <= closingRangeID
+}
+
+// GetHistoryTasks returns the tasks of a shard and category in task key order: immediate tasks by task ID and
+// scheduled tasks by visibility timestamp, then task ID. Task keys are unique within a shard and category, so
+// this order is total and the same for every plugin. The other readers of history tasks follow the same order;
+// readers over several shards or targets order the tasks of each shard or target, and readers that filter
+// tasks keep the order of the tasks they return.
+// Only reads through GetHistoryTasks record read metrics and count toward backlog pressure; the store's own
+// readers of history tasks use getHistoryTasks.
 func (m *sqlExecutionStore) GetHistoryTasks(
 	ctx context.Context,
 	request *p.GetHistoryTasksRequest,
 ) (*p.InternalGetHistoryTasksResponse, error) {
+	var resp *p.InternalGetHistoryTasksResponse
+	var err error
+	if m.partialResultsPageSize > 0 && m.partialResultsPageSize < request.BatchSize {
+		resp, err = m.getHistoryTasksInPages(ctx, request)
+	} else {
+		resp, err = m.getHistoryTasks(ctx, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.enforceTaskOrder("GetHistoryTasks", request.ShardID, resp.Tasks)
+
+	metrics.PersistenceHistoryTasksRead.With(m.metricsHandler).Record(
+		int64(len(resp.Tasks)),
+		metrics.TaskCategoryTag(request.TaskCategory.Name()),
+	)
+	if request.BatchSize > 0 {
+		m.backlogPressure.recordRead(request.ShardID, request.TaskCategory, len(resp.Tasks) >= request.BatchSize)
+	}
+	return resp, nil
+}
+
+// getHistoryTasksInPages reads the requested batch in pages of at most partialResultsPageSize tasks.
+// If the context deadline is exceeded after at least one page was read, the tasks read so far are
+// returned with the page token of the last page read.
+func (m *sqlExecutionStore) getHistoryTasksInPages(
+	ctx context.Context,
+	request *p.GetHistoryTasksRequest,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	pageRequest := *request
+	resp := &p.InternalGetHistoryTasksResponse{NextPageToken: request.NextPageToken}
+	for len(resp.Tasks) < request.BatchSize {
+		pageRequest.BatchSize = min(m.partialResultsPageSize, request.BatchSize-len(resp.Tasks))
+		pageRequest.NextPageToken = resp.NextPageToken
+		pageResp, err := m.getHistoryTasks(ctx, &pageRequest)
+		if err != nil {
+			if len(resp.Tasks) > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return resp, nil
+			}
+			return nil, err
+		}
+		resp.Tasks = append(resp.Tasks, pageResp.Tasks...)
+		resp.NextPageToken = pageResp.NextPageToken
+		if len(resp.NextPageToken) == 0 {
+			break
+		}
+	}
+	return resp, nil
+}
+
+func (m *sqlExecutionStore) getHistoryTasks(
+	ctx context.Context,
+	request *p.GetHistoryTasksRequest,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	var resp *p.InternalGetHistoryTasksResponse
+	var err error
 	switch request.TaskCategory.Type() {
 	case tasks.CategoryTypeImmediate:
-		return m.getHistoryImmediateTasks(ctx, request)
+		resp, err = m.getHistoryImmediateTasks(ctx, request)
 	case tasks.CategoryTypeScheduled:
-		return m.getHistoryScheduledTasks(ctx, request)
+		resp, err = m.getHistoryScheduledTasks(ctx, request)
 	default:
 		return nil, serviceerror.NewInternal(fmt.Sprintf("Unknown task category type: %v", request.TaskCategory))
 	}
+	if err != nil {
+		return nil, err
+	}
+	switch m.taskCategoryValidation {
+	case taskCategoryValidationError, taskCategoryValidationSkip:
+		return m.validateTaskCategory(request, resp)
+	default:
+		return resp, nil
+	}
 }
 
 func (m *sqlExecutionStore) CompleteHistoryTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
 ) error {
+	_, err := m.CompleteHistoryTaskWithCount(ctx, request)
+	return err
+}
+
+// CompleteHistoryTaskWithCount completes a history task like CompleteHistoryTask, and returns the number of
+// task rows deleted. 0 means the task was already gone, e.g. because it was completed twice.
+func (m *sqlExecutionStore) CompleteHistoryTaskWithCount(
+	ctx context.Context,
+	request *p.CompleteHistoryTaskRequest,
+) (int64, error) {
+	var rowsDeleted int64
+	var err error
 	switch request.TaskCategory.Type() {
 	case tasks.CategoryTypeImmediate:
-		return m.completeHistoryImmediateTask(ctx, request)
+		rowsDeleted, err = m.completeHistoryImmediateTask(ctx, request)
 	case tasks.CategoryTypeScheduled:
-		return m.completeHistoryScheduledTask(ctx, request)
+		rowsDeleted, err = m.completeHistoryScheduledTask(ctx, request)
 	default:
-		return serviceerror.NewInternal(fmt.Sprintf("Unknown task category type: %v", request.TaskCategory))
+		return 0, serviceerror.NewInternal(fmt.Sprintf("Unknown task category type: %v", request.TaskCategory))
+	}
+	if err != nil {
+		return 0, err
 	}
+
+	if !request.TaskCreationTime.IsZero() {
+		metrics.PersistenceTaskProcessingLatency.With(m.metricsHandler).Record(
+			time.Since(request.TaskCreationTime),
+			metrics.TaskCategoryTag(request.TaskCategory.Name()),
+		)
+	}
+	return rowsDeleted, nil
 }
 
+// RangeCompleteHistoryTasks deletes the history tasks of a range. It runs under the caller's context without
+// a timeout of its own, so callers deleting large ranges, e.g. admin maintenance, control how long it may take
+// through the deadline of ctx.
 func (m *sqlExecutionStore) RangeCompleteHistoryTasks(
 	ctx context.Context,
 	request *p.RangeCompleteHistoryTasksRequest,
 ) error {
-	switch request.TaskCategory.Type() {
-	case tasks.CategoryTypeImmediate:
-		return m.rangeCompleteHistoryImmediateTasks(ctx, request)
-	case tasks.CategoryTypeScheduled:
-		return m.rangeCompleteHistoryScheduledTasks(ctx, request)
-	default:
-		return serviceerror.NewInternal(fmt.Sprintf("Unknown task category type: %v", request.TaskCategory))
+	return m.executeRangeComplete(ctx, "RangeCompleteHistoryTasks", request, func(db sqlplugin.TableCRUD) error {
+		switch request.TaskCategory.Type() {
+		case tasks.CategoryTypeImmediate:
+			return m.rangeCompleteHistoryImmediateTasks(ctx, db, request)
+		case tasks.CategoryTypeScheduled:
+			return m.rangeCompleteHistoryScheduledTasks(ctx, db, request)
+		default:
+			return serviceerror.NewInternal(fmt.Sprintf("Unknown task category type: %v", request.TaskCategory))
+		}
+	})
+}
+
+// executeRangeComplete runs fn, which range completes the tasks of request, against the database. If range
+// completes are shard locked, fn runs in a transaction holding a read lock on the shard row instead, like
+// AddHistoryTasks, so that a stale shard owner fails with ShardOwnershipLostError instead of deleting tasks.
+func (m *sqlExecutionStore) executeRangeComplete(
+	ctx context.Context,
+	operation string,
+	request *p.RangeCompleteHistoryTasksRequest,
+	fn func(db sqlplugin.TableCRUD) error,
+) error {
+	if !m.shardLockedRangeCompletes {
+		return fn(m.Db)
+	}
+	if request.RangeID == 0 {
+		return serviceerror.NewInvalidArgument(fmt.Sprintf(
+			"%v failed. RangeID is required as range completes are shard locked. ShardID: %v", operation, request.ShardID,
+		))
+	}
+	return m.txExecuteShardLocked(ctx, operation, request.ShardID, request.RangeID, func(tx sqlplugin.Tx) error {
+		return fn(tx)
+	})
+}
+
+// taskReadDb returns the database of a history task read at readTier. Only lag tolerant reads may be served by the
+// read replica: queue processors ack up to the end of the range they read, so tasks missing from a lagging replica
+// would be lost.
+func (m *sqlExecutionStore) taskReadDb(readTier p.ReadTier) sqlplugin.DB {
+	if readTier == p.ReadTierLagTolerant {
+		return m.readOnlyDb()
 	}
+	return m.Db
 }
 
 func (m *sqlExecutionStore) getHistoryImmediateTasks(
@@ -116,12 +633,12 @@ func (m *sqlExecutionStore) getHistoryImmediateTasks(
 		return m.getReplicationTasks(ctx, request)
 	}
 
-	inclusiveMinTaskID, exclusiveMaxTaskID, err := getImmediateTaskReadRange(request)
+	inclusiveMinTaskID, exclusiveMaxTaskID, err := m.getImmediateTaskReadRange(request)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := m.Db.RangeSelectFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksRangeFilter{
+	rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksRangeFilter{
 		ShardID:            request.ShardID,
 		CategoryID:         int32(categoryID),
 		InclusiveMinTaskID: inclusiveMinTaskID,
@@ -136,17 +653,24 @@ func (m *sqlExecutionStore) getHistoryImmediateTasks(
 		}
 	}
 	resp := &p.InternalGetHistoryTasksResponse{
-		Tasks: make([]p.InternalHistoryTask, len(rows)),
+		Tasks: make([]p.InternalHistoryTask, 0, len(rows)),
 	}
 	if len(rows) == 0 {
 		return resp, nil
 	}
 
-	for i, row := range rows {
-		resp.Tasks[i] = p.InternalHistoryTask{
-			Key:  tasks.NewImmediateKey(row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetHistoryTasks", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
 		}
+		if !ok {
+			continue
+		}
+		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+			Key:  tasks.NewImmediateKey(row.TaskID),
+			Blob: m.newTaskDataBlob("GetHistoryTasks", row.Data, row.DataEncoding),
+		})
 	}
 	if len(rows) == request.BatchSize {
 		resp.NextPageToken = getImmediateTaskNextPageToken(
@@ -161,7 +685,7 @@ func (m *sqlExecutionStore) getHistoryImmediateTasks(
 func (m *sqlExecutionStore) completeHistoryImmediateTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
-) error {
+) (int64, error) {
 	// This is for backward compatiblity.
 	// These task categories exist before the general history_immediate_tasks table is created,
 	// so they have their own tables.
@@ -175,20 +699,28 @@ func (m *sqlExecutionStore) completeHistoryImmediateTask(
 		return m.completeReplicationTask(ctx, request)
 	}
 
-	if _, err := m.Db.DeleteFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksFilter{
+	result, err := m.Db.DeleteFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksFilter{
 		ShardID:    request.ShardID,
 		CategoryID: int32(categoryID),
 		TaskID:     request.TaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(
 			fmt.Sprintf("CompleteHistoryTask operation failed. CategoryID: %v. Error: %v", categoryID, err),
 		)
 	}
-	return nil
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(
+			fmt.Sprintf("CompleteHistoryTask operation failed. CategoryID: %v. Error: %v", categoryID, err),
+		)
+	}
+	return rowsDeleted, nil
 }
 
 func (m *sqlExecutionStore) rangeCompleteHistoryImmediateTasks(
 	ctx context.Context,
+	db sqlplugin.TableCRUD,
 	request *p.RangeCompleteHistoryTasksRequest,
 ) error {
 	// This is for backward compatiblity.
@@ -197,14 +729,14 @@ func (m *sqlExecutionStore) rangeCompleteHistoryImmediateTasks(
 	categoryID := request.TaskCategory.ID()
 	switch categoryID {
 	case tasks.CategoryIDTransfer:
-		return m.rangeCompleteTransferTasks(ctx, request)
+		return m.rangeCompleteTransferTasks(ctx, db, request)
 	case tasks.CategoryIDVisibility:
-		return m.rangeCompleteVisibilityTasks(ctx, request)
+		return m.rangeCompleteVisibilityTasks(ctx, db, request)
 	case tasks.CategoryIDReplication:
-		return m.rangeCompleteReplicationTasks(ctx, request)
+		return m.rangeCompleteReplicationTasks(ctx, db, request)
 	}
 
-	if _, err := m.Db.RangeDeleteFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksRangeFilter{
+	if _, err := db.RangeDeleteFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksRangeFilter{
 		ShardID:            request.ShardID,
 		CategoryID:         int32(categoryID),
 		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
@@ -229,16 +761,14 @@ func (m *sqlExecutionStore) getHistoryScheduledTasks(
 		return m.getTimerTasks(ctx, request)
 	}
 
-	pageToken := &scheduledTaskPageToken{TaskID: math.MinInt64, Timestamp: request.InclusiveMinTaskKey.FireTime}
-	if len(request.NextPageToken) > 0 {
-		if err := pageToken.deserialize(request.NextPageToken); err != nil {
-			return nil, serviceerror.NewInternal(
-				fmt.Sprintf("categoryID: %v. error deserializing scheduledTaskPageToken: %v", categoryID, err),
-			)
-		}
+	pageToken, err := m.getScheduledTaskPageToken(request)
+	if err != nil {
+		return nil, serviceerror.NewInternal(
+			fmt.Sprintf("categoryID: %v. error deserializing scheduledTaskPageToken: %v", categoryID, err),
+		)
 	}
 
-	rows, err := m.Db.RangeSelectFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksRangeFilter{
+	rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksRangeFilter{
 		ShardID:                         request.ShardID,
 		CategoryID:                      int32(categoryID),
 		InclusiveMinVisibilityTimestamp: pageToken.Timestamp,
@@ -255,18 +785,22 @@ func (m *sqlExecutionStore) getHistoryScheduledTasks(
 
 	resp := &p.InternalGetHistoryTasksResponse{Tasks: make([]p.InternalHistoryTask, 0, len(rows))}
 	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetHistoryTasks", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
 		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
 			Key:  tasks.NewKey(row.VisibilityTimestamp, row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+			Blob: m.newTaskDataBlob("GetHistoryTasks", row.Data, row.DataEncoding),
 		})
 	}
 
-	if len(resp.Tasks) == request.BatchSize {
-		pageToken = &scheduledTaskPageToken{
-			TaskID:    rows[request.BatchSize-1].TaskID + 1,
-			Timestamp: rows[request.BatchSize-1].VisibilityTimestamp,
-		}
-		nextToken, err := pageToken.serialize()
+	if len(rows) == request.BatchSize {
+		pageToken = nextScheduledTaskPageToken(rows[request.BatchSize-1].VisibilityTimestamp, rows[request.BatchSize-1].TaskID)
+		nextToken, err := pageToken.serialize(m.urlSafePageTokens, m.binaryPageTokens)
 		if err != nil {
 			return nil, serviceerror.NewInternal(fmt.Sprintf("GetHistoryTasks: error serializing page token: %v", err))
 		}
@@ -279,7 +813,7 @@ func (m *sqlExecutionStore) getHistoryScheduledTasks(
 func (m *sqlExecutionStore) completeHistoryScheduledTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
-) error {
+) (int64, error) {
 	// This is for backward compatiblity.
 	// These task categories exist before the general history_scheduled_tasks table is created,
 	// so they have their own tables.
@@ -288,19 +822,25 @@ func (m *sqlExecutionStore) completeHistoryScheduledTask(
 		return m.completeTimerTask(ctx, request)
 	}
 
-	if _, err := m.Db.DeleteFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksFilter{
+	result, err := m.Db.DeleteFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksFilter{
 		ShardID:             request.ShardID,
 		CategoryID:          int32(categoryID),
 		VisibilityTimestamp: request.TaskKey.FireTime,
 		TaskID:              request.TaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("CompleteHistoryTask operation failed. CategoryID: %v. Error: %v", categoryID, err))
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteHistoryTask operation failed. CategoryID: %v. Error: %v", categoryID, err))
 	}
-	return nil
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteHistoryTask operation failed. CategoryID: %v. Error: %v", categoryID, err))
+	}
+	return rowsDeleted, nil
 }
 
 func (m *sqlExecutionStore) rangeCompleteHistoryScheduledTasks(
 	ctx context.Context,
+	db sqlplugin.TableCRUD,
 	request *p.RangeCompleteHistoryTasksRequest,
 ) error {
 	// This is for backward compatiblity.
@@ -308,12 +848,13 @@ func (m *sqlExecutionStore) rangeCompleteHistoryScheduledTasks(
 	// so they have their own tables.
 	categoryID := request.TaskCategory.ID()
 	if categoryID == tasks.CategoryIDTimer {
-		return m.rangeCompleteTimerTasks(ctx, request)
+		_, err := m.rangeCompleteTimerTasks(ctx, db, request)
+		return err
 	}
 
 	start := request.InclusiveMinTaskKey.FireTime
 	end := request.ExclusiveMaxTaskKey.FireTime
-	if _, err := m.Db.RangeDeleteFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksRangeFilter{
+	if _, err := db.RangeDeleteFromHistoryScheduledTasks(ctx, sqlplugin.HistoryScheduledTasksRangeFilter{
 		ShardID:                         request.ShardID,
 		CategoryID:                      int32(categoryID),
 		InclusiveMinVisibilityTimestamp: start,
@@ -324,16 +865,40 @@ func (m *sqlExecutionStore) rangeCompleteHistoryScheduledTasks(
 	return nil
 }
 
+// getTransferTasks reads transfer tasks strictly in task_id order. The
+// transfer_tasks table has no priority or task type column (the type only
+// lives inside the encoded blob), so there is nothing to order by besides
+// the primary key.
 func (m *sqlExecutionStore) getTransferTasks(
 	ctx context.Context,
 	request *p.GetHistoryTasksRequest,
 ) (*p.InternalGetHistoryTasksResponse, error) {
-	inclusiveMinTaskID, exclusiveMaxTaskID, err := getImmediateTaskReadRange(request)
+	switch request.ReadTier {
+	case p.ReadTierLagTolerant:
+		// replica reads are not read ahead, as their tasks could be served to reads on the primary
+		return m.readTransferTasks(ctx, m.readOnlyDb(), nil, request)
+	case p.ReadTierConsistent:
+		// consistent reads neither read ahead nor break the read ahead of the shard's queue reader
+		return m.readTransferTasks(ctx, m.Db, nil, request)
+	default:
+		return m.readTransferTasks(ctx, m.Db, m.readAhead, request)
+	}
+}
+
+// readTransferTasks reads a page of transfer tasks through db, which is either the primary or the read
+// replica. readAhead is nil for reads that must not be served from memory.
+func (m *sqlExecutionStore) readTransferTasks(
+	ctx context.Context,
+	db sqlplugin.HistoryTransferTask,
+	readAhead *taskReadAheadCache,
+	request *p.GetHistoryTasksRequest,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	inclusiveMinTaskID, exclusiveMaxTaskID, err := m.getImmediateTaskReadRange(request)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := m.Db.RangeSelectFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+	rows, err := readAhead.selectTransferTasks(ctx, db, sqlplugin.TransferTasksRangeFilter{
 		ShardID:            request.ShardID,
 		InclusiveMinTaskID: inclusiveMinTaskID,
 		ExclusiveMaxTaskID: exclusiveMaxTaskID,
@@ -345,17 +910,24 @@ func (m *sqlExecutionStore) getTransferTasks(
 		}
 	}
 	resp := &p.InternalGetHistoryTasksResponse{
-		Tasks: make([]p.InternalHistoryTask, len(rows)),
+		Tasks: make([]p.InternalHistoryTask, 0, len(rows)),
 	}
 	if len(rows) == 0 {
 		return resp, nil
 	}
 
-	for i, row := range rows {
-		resp.Tasks[i] = p.InternalHistoryTask{
-			Key:  tasks.NewImmediateKey(row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetTransferTasks", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
 		}
+		if !ok {
+			continue
+		}
+		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+			Key:  tasks.NewImmediateKey(row.TaskID),
+			Blob: m.newTaskDataBlob("GetTransferTasks", row.Data, row.DataEncoding),
+		})
 	}
 	if len(rows) == request.BatchSize {
 		resp.NextPageToken = getImmediateTaskNextPageToken(
@@ -367,24 +939,89 @@ func (m *sqlExecutionStore) getTransferTasks(
 	return resp, nil
 }
 
+// GetTransferTasksMultiShard reads the transfer tasks of several shards in the same task ID range with a single
+// query, returning at most batchSize tasks per shard. Every shard with more tasks in the range gets a page token
+// to continue with GetHistoryTasks for that shard. Shards without tasks in the range are omitted. The tasks of
+// each shard are in task ID order.
+func (m *sqlExecutionStore) GetTransferTasksMultiShard(
+	ctx context.Context,
+	shardIDs []int32,
+	inclusiveMinTaskID int64,
+	exclusiveMaxTaskID int64,
+	batchSize int,
+) (map[int32]*p.InternalGetHistoryTasksResponse, error) {
+	respByShard := make(map[int32]*p.InternalGetHistoryTasksResponse, len(shardIDs))
+	if len(shardIDs) == 0 {
+		return respByShard, nil
+	}
+	if batchSize <= 0 {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf("GetTransferTasksMultiShard: batch size %v must be positive", batchSize))
+	}
+
+	rows, err := m.Db.RangeSelectFromTransferTasksMultiShard(ctx, sqlplugin.TransferTasksMultiShardRangeFilter{
+		ShardIDs:           shardIDs,
+		InclusiveMinTaskID: inclusiveMinTaskID,
+		ExclusiveMaxTaskID: exclusiveMaxTaskID,
+		PageSize:           batchSize,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetTransferTasksMultiShard operation failed. Select failed. Error: %v", err))
+	}
+
+	rowCounts := make(map[int32]int, len(shardIDs))
+	for _, row := range rows {
+		resp, ok := respByShard[row.ShardID]
+		if !ok {
+			resp = &p.InternalGetHistoryTasksResponse{}
+			respByShard[row.ShardID] = resp
+		}
+		rowCounts[row.ShardID]++
+		if rowCounts[row.ShardID] == batchSize {
+			resp.NextPageToken = getImmediateTaskNextPageToken(row.TaskID, exclusiveMaxTaskID)
+		}
+
+		ok, err := m.verifyTaskDataChecksum("GetTransferTasksMultiShard", row.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+			Key:  tasks.NewImmediateKey(row.TaskID),
+			Blob: m.newTaskDataBlob("GetTransferTasksMultiShard", row.Data, row.DataEncoding),
+		})
+	}
+	for shardID, resp := range respByShard {
+		m.enforceTaskOrder("GetTransferTasksMultiShard", shardID, resp.Tasks)
+	}
+	return respByShard, nil
+}
+
 func (m *sqlExecutionStore) completeTransferTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
-) error {
-	if _, err := m.Db.DeleteFromTransferTasks(ctx, sqlplugin.TransferTasksFilter{
+) (int64, error) {
+	result, err := m.Db.DeleteFromTransferTasks(ctx, sqlplugin.TransferTasksFilter{
 		ShardID: request.ShardID,
 		TaskID:  request.TaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("CompleteTransferTask operation failed. Error: %v", err))
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteTransferTask operation failed. Error: %v", err))
 	}
-	return nil
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteTransferTask operation failed. Error: %v", err))
+	}
+	return rowsDeleted, nil
 }
 
 func (m *sqlExecutionStore) rangeCompleteTransferTasks(
 	ctx context.Context,
+	db sqlplugin.TableCRUD,
 	request *p.RangeCompleteHistoryTasksRequest,
 ) error {
-	if _, err := m.Db.RangeDeleteFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+	if _, err := db.RangeDeleteFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
 		ShardID:            request.ShardID,
 		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
 		ExclusiveMaxTaskID: request.ExclusiveMaxTaskKey.TaskID,
@@ -394,18 +1031,64 @@ func (m *sqlExecutionStore) rangeCompleteTransferTasks(
 	return nil
 }
 
+// GetAndCompleteTransferTasks reads a page of transfer tasks and returns it together with a complete function.
+// The complete function deletes the given tasks in a single transaction, with one statement per
+// completeTransferTasksChunkSize tasks. It only accepts IDs of tasks in the returned page, so a caller cannot
+// complete tasks it has not read. Tasks that are not completed are returned again by subsequent reads.
+func (m *sqlExecutionStore) GetAndCompleteTransferTasks(
+	ctx context.Context,
+	request *p.GetHistoryTasksRequest,
+) (*p.InternalGetHistoryTasksResponse, func(ctx context.Context, taskIDs []int64) error, error) {
+	if request.TaskCategory.ID() != tasks.CategoryIDTransfer {
+		return nil, nil, serviceerror.NewInvalidArgument(
+			fmt.Sprintf("GetAndCompleteTransferTasks operation failed. Unexpected task category: %v", request.TaskCategory.Name()),
+		)
+	}
+	resp, err := m.GetHistoryTasks(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pageTaskIDs := make(map[int64]struct{}, len(resp.Tasks))
+	for _, task := range resp.Tasks {
+		pageTaskIDs[task.Key.TaskID] = struct{}{}
+	}
+	complete := func(ctx context.Context, taskIDs []int64) error {
+		for _, taskID := range taskIDs {
+			if _, ok := pageTaskIDs[taskID]; !ok {
+				return serviceerror.NewInvalidArgument(
+					fmt.Sprintf("GetAndCompleteTransferTasks operation failed. Task %v was not read. ShardID: %v", taskID, request.ShardID),
+				)
+			}
+		}
+		if len(taskIDs) == 0 {
+			return nil
+		}
+		return m.txExecute(ctx, "GetAndCompleteTransferTasks", func(tx sqlplugin.Tx) error {
+			for chunk := range slices.Chunk(taskIDs, completeTransferTasksChunkSize) {
+				if _, err := tx.DeleteTaskIDsFromTransferTasks(ctx, sqlplugin.TransferTasksIDsFilter{
+					ShardID: request.ShardID,
+					TaskIDs: chunk,
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return resp, complete, nil
+}
+
 func (m *sqlExecutionStore) getTimerTasks(
 	ctx context.Context,
 	request *p.GetHistoryTasksRequest,
 ) (*p.InternalGetHistoryTasksResponse, error) {
-	pageToken := &scheduledTaskPageToken{TaskID: math.MinInt64, Timestamp: request.InclusiveMinTaskKey.FireTime}
-	if len(request.NextPageToken) > 0 {
-		if err := pageToken.deserialize(request.NextPageToken); err != nil {
-			return nil, serviceerror.NewInternal(fmt.Sprintf("error deserializing timerTaskPageToken: %v", err))
-		}
+	pageToken, err := m.getScheduledTaskPageToken(request)
+	if err != nil {
+		return nil, serviceerror.NewInternal(fmt.Sprintf("error deserializing timerTaskPageToken: %v", err))
 	}
 
-	rows, err := m.Db.RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+	rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
 		ShardID:                         request.ShardID,
 		InclusiveMinVisibilityTimestamp: pageToken.Timestamp,
 		InclusiveMinTaskID:              pageToken.TaskID,
@@ -419,18 +1102,22 @@ func (m *sqlExecutionStore) getTimerTasks(
 
 	resp := &p.InternalGetHistoryTasksResponse{Tasks: make([]p.InternalHistoryTask, 0, len(rows))}
 	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetTimerTasks", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
 		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
 			Key:  tasks.NewKey(row.VisibilityTimestamp, row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+			Blob: m.newTaskDataBlob("GetTimerTasks", row.Data, row.DataEncoding),
 		})
 	}
 
-	if len(resp.Tasks) == request.BatchSize {
-		pageToken = &scheduledTaskPageToken{
-			TaskID:    rows[request.BatchSize-1].TaskID + 1,
-			Timestamp: rows[request.BatchSize-1].VisibilityTimestamp,
-		}
-		nextToken, err := pageToken.serialize()
+	if len(rows) == request.BatchSize {
+		pageToken = nextScheduledTaskPageToken(rows[request.BatchSize-1].VisibilityTimestamp, rows[request.BatchSize-1].TaskID)
+		nextToken, err := pageToken.serialize(m.urlSafePageTokens, m.binaryPageTokens)
 		if err != nil {
 			return nil, serviceerror.NewInternal(fmt.Sprintf("GetTimerTasks: error serializing page token: %v", err))
 		}
@@ -440,46 +1127,168 @@ func (m *sqlExecutionStore) getTimerTasks(
 	return resp, nil
 }
 
+// GetTimerTasksByType reads the timer tasks of the requested range whose task type is one of taskTypes, and
+// all of them if taskTypes is empty. The task type is stored only in the task blob, so rows are read in pages
+// of request.BatchSize and filtered after decoding until request.BatchSize matching tasks are found.
+// The returned page token continues after the last returned task.
+func (m *sqlExecutionStore) GetTimerTasksByType(
+	ctx context.Context,
+	request *p.GetHistoryTasksRequest,
+	taskTypes []enumsspb.TaskType,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	if len(taskTypes) == 0 {
+		return m.getTimerTasks(ctx, request)
+	}
+
+	pageToken, err := m.getScheduledTaskPageToken(request)
+	if err != nil {
+		return nil, serviceerror.NewInternal(fmt.Sprintf("error deserializing timerTaskPageToken: %v", err))
+	}
+
+	decodeTaskType := legacyTaskTypeDecoders[tasks.CategoryIDTimer]
+	resp := &p.InternalGetHistoryTasksResponse{}
+	for {
+		rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+			ShardID:                         request.ShardID,
+			InclusiveMinVisibilityTimestamp: pageToken.Timestamp,
+			InclusiveMinTaskID:              pageToken.TaskID,
+			ExclusiveMaxVisibilityTimestamp: request.ExclusiveMaxTaskKey.FireTime,
+			PageSize:                        request.BatchSize,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetTimerTasksByType operation failed. Select failed. Error: %v", err))
+		}
+
+		for _, row := range rows {
+			pageToken = nextScheduledTaskPageToken(row.VisibilityTimestamp, row.TaskID)
+			ok, err := m.verifyTaskDataChecksum("GetTimerTasksByType", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			blob := m.newTaskDataBlob("GetTimerTasksByType", row.Data, row.DataEncoding)
+			taskType, err := decodeTaskType(blob)
+			if err != nil {
+				return nil, serviceerror.NewInternal(fmt.Sprintf("GetTimerTasksByType: failed to decode timer task %v: %v", row.TaskID, err))
+			}
+			if !slices.Contains(taskTypes, taskType) {
+				continue
+			}
+			resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+				Key:  tasks.NewKey(row.VisibilityTimestamp, row.TaskID),
+				Blob: blob,
+			})
+			if len(resp.Tasks) == request.BatchSize {
+				nextToken, err := pageToken.serialize(m.urlSafePageTokens, m.binaryPageTokens)
+				if err != nil {
+					return nil, serviceerror.NewInternal(fmt.Sprintf("GetTimerTasksByType: error serializing page token: %v", err))
+				}
+				resp.NextPageToken = nextToken
+				m.enforceTaskOrder("GetTimerTasksByType", request.ShardID, resp.Tasks)
+				return resp, nil
+			}
+		}
+
+		if len(rows) < request.BatchSize {
+			m.enforceTaskOrder("GetTimerTasksByType", request.ShardID, resp.Tasks)
+			return resp, nil
+		}
+	}
+}
+
 func (m *sqlExecutionStore) completeTimerTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
-) error {
-	if _, err := m.Db.DeleteFromTimerTasks(ctx, sqlplugin.TimerTasksFilter{
+) (int64, error) {
+	result, err := m.Db.DeleteFromTimerTasks(ctx, sqlplugin.TimerTasksFilter{
 		ShardID:             request.ShardID,
 		VisibilityTimestamp: request.TaskKey.FireTime,
 		TaskID:              request.TaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("CompleteTimerTask operation failed. Error: %v", err))
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteTimerTask operation failed. Error: %v", err))
 	}
-	return nil
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteTimerTask operation failed. Error: %v", err))
+	}
+	return rowsDeleted, nil
 }
 
 func (m *sqlExecutionStore) rangeCompleteTimerTasks(
 	ctx context.Context,
+	db sqlplugin.TableCRUD,
 	request *p.RangeCompleteHistoryTasksRequest,
-) error {
+) (int64, error) {
 	start := request.InclusiveMinTaskKey.FireTime
 	end := request.ExclusiveMaxTaskKey.FireTime
-	if _, err := m.Db.RangeDeleteFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
-		ShardID:                         request.ShardID,
-		InclusiveMinVisibilityTimestamp: start,
-		ExclusiveMaxVisibilityTimestamp: end,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("RangeCompleteTimerTask operation failed. Error: %v", err))
+	var totalRowsDeleted int64
+	for {
+		batchSize := m.timerTaskDeleteBatchSize()
+		result, err := db.RangeDeleteFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+			ShardID:                         request.ShardID,
+			InclusiveMinVisibilityTimestamp: start,
+			ExclusiveMaxVisibilityTimestamp: end,
+			PageSize:                        batchSize,
+		})
+		if err != nil {
+			return 0, serviceerror.NewUnavailable(fmt.Sprintf("RangeCompleteTimerTask operation failed. Error: %v", err))
+		}
+		rowsDeleted, err := result.RowsAffected()
+		if err != nil {
+			return 0, serviceerror.NewUnavailable(fmt.Sprintf("RangeCompleteTimerTask operation failed. Error: %v", err))
+		}
+		metrics.PersistenceTimerTasksDeletedPerBatch.With(m.metricsHandler).Record(rowsDeleted)
+		totalRowsDeleted += rowsDeleted
+
+		if batchSize <= 0 || rowsDeleted < int64(batchSize) {
+			return totalRowsDeleted, nil
+		}
 	}
-	return nil
+}
+
+// CompleteAndRescheduleTimerTask completes a timer task and inserts the next timer task of a recurring timer in
+// one transaction under the shard lock, so that a failure leaves either the completed timer or its next occurrence
+// in place. NotFound is returned if the completed timer task doesn't exist, e.g. as it was rescheduled already.
+func (m *sqlExecutionStore) CompleteAndRescheduleTimerTask(
+	ctx context.Context,
+	request *p.InternalCompleteAndRescheduleTimerTaskRequest,
+) error {
+	return m.txExecuteShardLocked(ctx, "CompleteAndRescheduleTimerTask", request.ShardID, request.RangeID, func(tx sqlplugin.Tx) error {
+		result, err := tx.DeleteFromTimerTasks(ctx, sqlplugin.TimerTasksFilter{
+			ShardID:             request.ShardID,
+			VisibilityTimestamp: request.CompletedTaskKey.FireTime,
+			TaskID:              request.CompletedTaskKey.TaskID,
+		})
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return serviceerror.NewNotFound(
+				fmt.Sprintf("CompleteAndRescheduleTimerTask failed. Timer task not found. ShardID: %v, TaskKey: %v", request.ShardID, request.CompletedTaskKey),
+			)
+		}
+
+		return createTimerTasks(ctx, tx, request.ShardID, []p.InternalHistoryTask{request.NextTask}, m.writeTaskDataChecksums())
+	})
 }
 
 func (m *sqlExecutionStore) getReplicationTasks(
 	ctx context.Context,
 	request *p.GetHistoryTasksRequest,
 ) (*p.InternalGetHistoryTasksResponse, error) {
-	inclusiveMinTaskID, exclusiveMaxTaskID, err := getImmediateTaskReadRange(request)
+	inclusiveMinTaskID, exclusiveMaxTaskID, err := m.getImmediateTaskReadRange(request)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := m.Db.RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+	rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
 		ShardID:            request.ShardID,
 		InclusiveMinTaskID: inclusiveMinTaskID,
 		ExclusiveMaxTaskID: exclusiveMaxTaskID,
@@ -488,7 +1297,7 @@ func (m *sqlExecutionStore) getReplicationTasks(
 
 	switch err {
 	case nil:
-		return m.populateGetReplicationTasksResponse(rows, request.ExclusiveMaxTaskKey.TaskID, request.BatchSize)
+		return m.populateGetReplicationTasksResponse(request.ShardID, rows, request.ExclusiveMaxTaskKey.TaskID, request.BatchSize)
 	case sql.ErrNoRows:
 		return &p.InternalGetHistoryTasksResponse{}, nil
 	default:
@@ -496,20 +1305,287 @@ func (m *sqlExecutionStore) getReplicationTasks(
 	}
 }
 
-func getImmediateTaskReadRange(
+// GetReplicationTasksWithDeadline reads up to request.BatchSize replication tasks using sub-pages of at
+// most subPageSize tasks. It stops reading once the batch is full or maxDuration has elapsed on the store's
+// time source, and returns the tasks read so far with a page token to continue from. The first sub-page is
+// always read, so every call makes progress regardless of maxDuration.
+func (m *sqlExecutionStore) GetReplicationTasksWithDeadline(
+	ctx context.Context,
+	request *p.GetHistoryTasksRequest,
+	subPageSize int,
+	maxDuration time.Duration,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	inclusiveMinTaskID, exclusiveMaxTaskID, err := m.getImmediateTaskReadRange(request)
+	if err != nil {
+		return nil, err
+	}
+	if subPageSize <= 0 || subPageSize > request.BatchSize {
+		subPageSize = request.BatchSize
+	}
+
+	deadline := m.timeSource.Now().Add(maxDuration)
+	resp := &p.InternalGetHistoryTasksResponse{}
+	for len(resp.Tasks) < request.BatchSize {
+		pageSize := min(subPageSize, request.BatchSize-len(resp.Tasks))
+		rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+			ShardID:            request.ShardID,
+			InclusiveMinTaskID: inclusiveMinTaskID,
+			ExclusiveMaxTaskID: exclusiveMaxTaskID,
+			PageSize:           pageSize,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationTasks operation failed. Select failed: %v", err))
+		}
+		for _, row := range rows {
+			ok, err := m.verifyTaskDataChecksum("GetReplicationTasks", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+				Key:  tasks.NewImmediateKey(row.TaskID),
+				Blob: m.newTaskDataBlob("GetReplicationTasks", row.Data, row.DataEncoding),
+			})
+		}
+		if len(rows) < pageSize {
+			// reached the end of the requested range
+			m.enforceTaskOrder("GetReplicationTasksWithDeadline", request.ShardID, resp.Tasks)
+			return resp, nil
+		}
+
+		lastTaskID := rows[len(rows)-1].TaskID
+		resp.NextPageToken = getImmediateTaskNextPageToken(lastTaskID, exclusiveMaxTaskID)
+		if resp.NextPageToken == nil || !m.timeSource.Now().Before(deadline) {
+			m.enforceTaskOrder("GetReplicationTasksWithDeadline", request.ShardID, resp.Tasks)
+			return resp, nil
+		}
+		inclusiveMinTaskID = lastTaskID + 1
+	}
+	m.enforceTaskOrder("GetReplicationTasksWithDeadline", request.ShardID, resp.Tasks)
+	return resp, nil
+}
+
+// GetReplicationTaskIDs returns the task IDs of the replication tasks of a shard in
+// [inclusiveMinTaskID, exclusiveMaxTaskID), in task ID order and at most pageSize of them. The task data isn't
+// read, so that ack and cleanup bookkeeping doesn't pay for decoding tasks it doesn't look at. The next page
+// starts after the last returned task ID.
+func (m *sqlExecutionStore) GetReplicationTaskIDs(
+	ctx context.Context,
+	shardID int32,
+	inclusiveMinTaskID int64,
+	exclusiveMaxTaskID int64,
+	pageSize int,
+) ([]int64, error) {
+	if pageSize <= 0 {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf("GetReplicationTaskIDs: invalid page size: %v", pageSize))
+	}
+	taskIDs, err := m.Db.RangeSelectTaskIDsFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: inclusiveMinTaskID,
+		ExclusiveMaxTaskID: exclusiveMaxTaskID,
+		PageSize:           pageSize,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationTaskIDs operation failed. Error: %v", err))
+	}
+	return taskIDs, nil
+}
+
+// getImmediateTaskReadRange returns the task ID range to read for an immediate task request. The upper bound is
+// always the request's ExclusiveMaxTaskKey, it is never extended based on the batch size, so no task at or
+// beyond it is returned even when fewer than BatchSize tasks are found.
+func (m *sqlExecutionStore) getImmediateTaskReadRange(
 	request *p.GetHistoryTasksRequest,
 ) (inclusiveMinTaskID int64, exclusiveMaxTaskID int64, err error) {
 	inclusiveMinTaskID = request.InclusiveMinTaskKey.TaskID
 	if len(request.NextPageToken) > 0 {
-		inclusiveMinTaskID, err = deserializePageToken(request.NextPageToken)
+		pageTokenMinTaskID, err := deserializePageToken(request.NextPageToken)
 		if err != nil {
-			return 0, 0, err
+			if err := m.handleCorruptPageToken("GetHistoryTasks", request, err); err != nil {
+				return 0, 0, err
+			}
+			return inclusiveMinTaskID, request.ExclusiveMaxTaskKey.TaskID, nil
 		}
+		inclusiveMinTaskID = pageTokenMinTaskID
 	}
 
 	return inclusiveMinTaskID, request.ExclusiveMaxTaskKey.TaskID, nil
 }
 
+// handleCorruptPageToken returns err unless lenient page tokens are enabled, in which case the
+// corrupt token is reported and the caller should restart from the beginning of the requested range.
+func (m *sqlExecutionStore) handleCorruptPageToken(
+	operation string,
+	request *p.GetHistoryTasksRequest,
+	err error,
+) error {
+	if !m.lenientPageTokens {
+		return err
+	}
+
+	m.logger.Warn("Unable to deserialize page token, reading from the beginning of the requested range",
+		tag.Operation(operation),
+		tag.ShardID(request.ShardID),
+		tag.TaskCategoryID(request.TaskCategory.ID()),
+		tag.Error(err),
+	)
+	metrics.PersistenceCorruptPageTokens.With(m.metricsHandler).Record(1, metrics.OperationTag(operation))
+	return nil
+}
+
+// validateTaskCategory checks that every task read from one of the legacy per-category tables has a task type
+// belonging to that category. Tasks read from the generic history task tables are not checked as those tables
+// are keyed by category. The page token of resp is kept as is, so a page whose tasks are all skipped still
+// lets the caller continue paging.
+func (m *sqlExecutionStore) validateTaskCategory(
+	request *p.GetHistoryTasksRequest,
+	resp *p.InternalGetHistoryTasksResponse,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	categoryID := request.TaskCategory.ID()
+	decodeTaskType, ok := legacyTaskTypeDecoders[categoryID]
+	if !ok {
+		return resp, nil
+	}
+
+	operation := fmt.Sprintf("Get%vTasks", request.TaskCategory.Name())
+	validTasks := resp.Tasks[:0]
+	for _, task := range resp.Tasks {
+		taskType, err := decodeTaskType(task.Blob)
+		if err != nil {
+			return nil, serviceerror.NewInternal(fmt.Sprintf("%v operation failed. Unable to decode task %v: %v", operation, task.Key, err))
+		}
+		if taskCategoryIDByType[taskType] == categoryID {
+			validTasks = append(validTasks, task)
+			continue
+		}
+
+		if m.taskCategoryValidation != taskCategoryValidationSkip {
+			return nil, serviceerror.NewInternal(fmt.Sprintf(
+				"%v operation failed. Task %v has type %v which does not belong to task category %v",
+				operation,
+				task.Key,
+				taskType,
+				request.TaskCategory.Name(),
+			))
+		}
+		m.logger.Warn("Skipping history task whose type does not belong to the task category of its table",
+			tag.Operation(operation),
+			tag.ShardID(request.ShardID),
+			tag.TaskCategoryID(categoryID),
+			tag.TaskID(task.Key.TaskID),
+			tag.TaskType(taskType),
+		)
+		metrics.PersistenceTaskCategoryMismatches.With(m.metricsHandler).Record(1, metrics.OperationTag(operation))
+	}
+	resp.Tasks = validTasks
+	return resp, nil
+}
+
+// newTaskDataBlob creates the data blob of a task row. Rows without a data encoding are assumed to use the
+// configured default encoding, if any.
+func (m *sqlExecutionStore) newTaskDataBlob(
+	operation string,
+	data []byte,
+	dataEncoding string,
+) *commonpb.DataBlob {
+	if dataEncoding == "" && m.defaultTaskDataEncoding != "" {
+		dataEncoding = m.defaultTaskDataEncoding
+		metrics.PersistenceTaskEncodingFallbacks.With(m.metricsHandler).Record(1, metrics.OperationTag(operation))
+	}
+	return p.NewDataBlob(data, dataEncoding)
+}
+
+// writeTaskDataChecksums reports whether task data checksums are enabled, in which case new task rows store
+// a checksum of their data.
+func (m *sqlExecutionStore) writeTaskDataChecksums() bool {
+	return m.taskDataChecksums == taskDataChecksumsError || m.taskDataChecksums == taskDataChecksumsSkip
+}
+
+// enforceTaskOrder checks, with strict task order, that historyTasks are in task key order. Tasks out of order
+// are reported and sorted in place, which keeps the order of any tasks with equal keys.
+func (m *sqlExecutionStore) enforceTaskOrder(
+	operation string,
+	shardID int32,
+	historyTasks []p.InternalHistoryTask,
+) {
+	compareKeys := func(a, b p.InternalHistoryTask) int {
+		return a.Key.CompareTo(b.Key)
+	}
+	if !m.strictTaskOrder || slices.IsSortedFunc(historyTasks, compareKeys) {
+		return
+	}
+
+	metrics.PersistenceTaskOrderViolations.With(m.metricsHandler).Record(1, metrics.OperationTag(operation))
+	m.logger.Error("History tasks were read out of task key order",
+		tag.Operation(operation),
+		tag.ShardID(shardID),
+	)
+	slices.SortStableFunc(historyTasks, compareKeys)
+}
+
+// verifyTaskDataChecksum reports whether the data of a task row should be returned. Rows without a checksum
+// and all rows when checksums are disabled are returned as is. On a mismatch, either a DataLoss error is
+// returned or, if corrupt tasks are configured to be skipped, false is returned.
+func (m *sqlExecutionStore) verifyTaskDataChecksum(
+	operation string,
+	shardID int32,
+	taskID int64,
+	data []byte,
+	checksum *int64,
+) (bool, error) {
+	if checksum == nil || !m.writeTaskDataChecksums() {
+		return true, nil
+	}
+	if *checksum == *taskDataChecksum(true, data) {
+		return true, nil
+	}
+
+	if m.taskDataChecksums != taskDataChecksumsSkip {
+		return false, serviceerror.NewDataLoss(fmt.Sprintf(
+			"%v operation failed. Data of task %v of shard %v does not match its checksum",
+			operation,
+			taskID,
+			shardID,
+		))
+	}
+	m.logger.Warn("Skipping history task whose data does not match its checksum",
+		tag.Operation(operation),
+		tag.ShardID(shardID),
+		tag.TaskID(taskID),
+	)
+	metrics.PersistenceTaskDataChecksumMismatches.With(m.metricsHandler).Record(1, metrics.OperationTag(operation))
+	return false, nil
+}
+
+// taskDataChecksum returns the CRC32 checksum of task data to store with a task row, or nil if disabled.
+func taskDataChecksum(
+	enabled bool,
+	data []byte,
+) *int64 {
+	if !enabled {
+		return nil
+	}
+	checksum := int64(crc32.ChecksumIEEE(data))
+	return &checksum
+}
+
+func (m *sqlExecutionStore) getScheduledTaskPageToken(
+	request *p.GetHistoryTasksRequest,
+) (*scheduledTaskPageToken, error) {
+	pageToken := &scheduledTaskPageToken{TaskID: math.MinInt64, Timestamp: request.InclusiveMinTaskKey.FireTime}
+	if len(request.NextPageToken) > 0 {
+		if err := pageToken.deserialize(request.NextPageToken); err != nil {
+			if err := m.handleCorruptPageToken("GetHistoryTasks", request, err); err != nil {
+				return nil, err
+			}
+			return &scheduledTaskPageToken{TaskID: math.MinInt64, Timestamp: request.InclusiveMinTaskKey.FireTime}, nil
+		}
+	}
+	return pageToken, nil
+}
+
 func getImmediateTaskNextPageToken(
 	lastTaskID int64,
 	exclusiveMaxTaskID int64,
@@ -522,6 +1598,7 @@ func getImmediateTaskNextPageToken(
 }
 
 func (m *sqlExecutionStore) populateGetReplicationTasksResponse(
+	shardID int32,
 	rows []sqlplugin.ReplicationTasksRow,
 	exclusiveMaxTaskID int64,
 	batchSize int,
@@ -530,41 +1607,19 @@ func (m *sqlExecutionStore) populateGetReplicationTasksResponse(
 		return &p.InternalGetHistoryTasksResponse{}, nil
 	}
 
-	var replicationTasks = make([]p.InternalHistoryTask, len(rows))
-	for i, row := range rows {
-		replicationTasks[i] = p.InternalHistoryTask{
-			Key:  tasks.NewImmediateKey(row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+	var replicationTasks = make([]p.InternalHistoryTask, 0, len(rows))
+	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetReplicationTasks", shardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
 		}
-	}
-	var nextPageToken []byte
-	if len(rows) == batchSize {
-		nextPageToken = getImmediateTaskNextPageToken(
-			rows[len(rows)-1].TaskID,
-			exclusiveMaxTaskID,
-		)
-	}
-	return &p.InternalGetHistoryTasksResponse{
-		Tasks:         replicationTasks,
-		NextPageToken: nextPageToken,
-	}, nil
-}
-
-func (m *sqlExecutionStore) populateGetReplicationDLQTasksResponse(
-	rows []sqlplugin.ReplicationDLQTasksRow,
-	exclusiveMaxTaskID int64,
-	batchSize int,
-) (*p.InternalGetHistoryTasksResponse, error) {
-	if len(rows) == 0 {
-		return &p.InternalGetHistoryTasksResponse{}, nil
-	}
-
-	var dlqTasks = make([]p.InternalHistoryTask, len(rows))
-	for i, row := range rows {
-		dlqTasks[i] = p.InternalHistoryTask{
-			Key:  tasks.NewImmediateKey(row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+		if !ok {
+			continue
 		}
+		replicationTasks = append(replicationTasks, p.InternalHistoryTask{
+			Key:  tasks.NewImmediateKey(row.TaskID),
+			Blob: m.newTaskDataBlob("GetReplicationTasks", row.Data, row.DataEncoding),
+		})
 	}
 	var nextPageToken []byte
 	if len(rows) == batchSize {
@@ -574,7 +1629,7 @@ func (m *sqlExecutionStore) populateGetReplicationDLQTasksResponse(
 		)
 	}
 	return &p.InternalGetHistoryTasksResponse{
-		Tasks:         dlqTasks,
+		Tasks:         replicationTasks,
 		NextPageToken: nextPageToken,
 	}, nil
 }
@@ -582,149 +1637,57 @@ func (m *sqlExecutionStore) populateGetReplicationDLQTasksResponse(
 func (m *sqlExecutionStore) completeReplicationTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
-) error {
-	if _, err := m.Db.DeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksFilter{
+) (int64, error) {
+	result, err := m.Db.DeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksFilter{
 		ShardID: request.ShardID,
 		TaskID:  request.TaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("CompleteReplicationTask operation failed. Error: %v", err))
-	}
-	return nil
-}
-
-func (m *sqlExecutionStore) rangeCompleteReplicationTasks(
-	ctx context.Context,
-	request *p.RangeCompleteHistoryTasksRequest,
-) error {
-	if _, err := m.Db.RangeDeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
-		ShardID:            request.ShardID,
-		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
-		ExclusiveMaxTaskID: request.ExclusiveMaxTaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("RangeCompleteReplicationTask operation failed. Error: %v", err))
-	}
-	return nil
-}
-
-func (m *sqlExecutionStore) PutReplicationTaskToDLQ(
-	ctx context.Context,
-	request *p.PutReplicationTaskToDLQRequest,
-) error {
-	replicationTask := request.TaskInfo
-	blob, err := serialization.ReplicationTaskInfoToBlob(replicationTask)
-
+	})
 	if err != nil {
-		return err
-	}
-
-	_, err = m.Db.InsertIntoReplicationDLQTasks(ctx, []sqlplugin.ReplicationDLQTasksRow{{
-		SourceClusterName: request.SourceClusterName,
-		ShardID:           request.ShardID,
-		TaskID:            replicationTask.GetTaskId(),
-		Data:              blob.Data,
-		DataEncoding:      blob.EncodingType.String(),
-	}})
-
-	// Tasks are immutable. So it's fine if we already persisted it before.
-	// This can happen when tasks are retried (ack and cleanup can have lag on source side).
-	if err != nil && !m.Db.IsDupEntryError(err) {
-		return serviceerror.NewUnavailable(fmt.Sprintf("Failed to create replication tasks. Error: %v", err))
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteReplicationTask operation failed. Error: %v", err))
 	}
-
-	return nil
-}
-
-func (m *sqlExecutionStore) GetReplicationTasksFromDLQ(
-	ctx context.Context,
-	request *p.GetReplicationTasksFromDLQRequest,
-) (*p.InternalGetHistoryTasksResponse, error) {
-	inclusiveMinTaskID, exclusiveMaxTaskID, err := getImmediateTaskReadRange(&request.GetHistoryTasksRequest)
+	rowsDeleted, err := result.RowsAffected()
 	if err != nil {
-		return nil, err
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteReplicationTask operation failed. Error: %v", err))
 	}
-
-	rows, err := m.Db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
-		ShardID:            request.ShardID,
-		InclusiveMinTaskID: inclusiveMinTaskID,
-		ExclusiveMaxTaskID: exclusiveMaxTaskID,
-		PageSize:           request.BatchSize,
-		SourceClusterName:  request.SourceClusterName,
-	})
-
-	switch err {
-	case nil:
-		return m.populateGetReplicationDLQTasksResponse(rows, request.ExclusiveMaxTaskKey.TaskID, request.BatchSize)
-	case sql.ErrNoRows:
-		return &p.InternalGetHistoryTasksResponse{}, nil
-	default:
-		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationTasks operation failed. Select failed: %v", err))
-	}
-}
-
-func (m *sqlExecutionStore) DeleteReplicationTaskFromDLQ(
-	ctx context.Context,
-	request *p.DeleteReplicationTaskFromDLQRequest,
-) error {
-	if _, err := m.Db.DeleteFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksFilter{
-		ShardID:           request.ShardID,
-		TaskID:            request.TaskKey.TaskID,
-		SourceClusterName: request.SourceClusterName,
-	}); err != nil {
-		return err
-	}
-	return nil
+	return rowsDeleted, nil
 }
 
-func (m *sqlExecutionStore) RangeDeleteReplicationTaskFromDLQ(
+func (m *sqlExecutionStore) rangeCompleteReplicationTasks(
 	ctx context.Context,
-	request *p.RangeDeleteReplicationTaskFromDLQRequest,
+	db sqlplugin.TableCRUD,
+	request *p.RangeCompleteHistoryTasksRequest,
 ) error {
-	if _, err := m.Db.RangeDeleteFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+	if _, err := db.RangeDeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
 		ShardID:            request.ShardID,
-		SourceClusterName:  request.SourceClusterName,
 		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
 		ExclusiveMaxTaskID: request.ExclusiveMaxTaskKey.TaskID,
 	}); err != nil {
-		return err
+		return serviceerror.NewUnavailable(fmt.Sprintf("RangeCompleteReplicationTask operation failed. Error: %v", err))
 	}
 	return nil
 }
 
-func (m *sqlExecutionStore) IsReplicationDLQEmpty(
-	ctx context.Context,
-	request *p.GetReplicationTasksFromDLQRequest,
-) (bool, error) {
-	res, err := m.Db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
-		ShardID:            request.ShardID,
-		SourceClusterName:  request.SourceClusterName,
-		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
-		ExclusiveMaxTaskID: math.MaxInt64,
-		PageSize:           1,
-	})
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			// The queue is empty
-			return true, nil
-		}
-		return false, err
-	}
-	return len(res) == 0, nil
-}
-
+// getVisibilityTasks reads a page of at most request.BatchSize visibility tasks, with a page token to continue
+// from like replication task reads. If request.BatchSize is 0, the whole range is read at once and no page
+// token is returned.
 func (m *sqlExecutionStore) getVisibilityTasks(
 	ctx context.Context,
 	request *p.GetHistoryTasksRequest,
 ) (*p.InternalGetHistoryTasksResponse, error) {
-	inclusiveMinTaskID, exclusiveMaxTaskID, err := getImmediateTaskReadRange(request)
+	inclusiveMinTaskID, exclusiveMaxTaskID, err := m.getImmediateTaskReadRange(request)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := m.Db.RangeSelectFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+	pageSize := request.BatchSize
+	if pageSize <= 0 {
+		pageSize = math.MaxInt32
+	}
+	rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
 		ShardID:            request.ShardID,
 		InclusiveMinTaskID: inclusiveMinTaskID,
 		ExclusiveMaxTaskID: exclusiveMaxTaskID,
-		PageSize:           request.BatchSize,
+		PageSize:           pageSize,
 	})
 	if err != nil {
 		if err != sql.ErrNoRows {
@@ -732,17 +1695,24 @@ func (m *sqlExecutionStore) getVisibilityTasks(
 		}
 	}
 	resp := &p.InternalGetHistoryTasksResponse{
-		Tasks: make([]p.InternalHistoryTask, len(rows)),
+		Tasks: make([]p.InternalHistoryTask, 0, len(rows)),
 	}
 	if len(rows) == 0 {
 		return resp, nil
 	}
 
-	for i, row := range rows {
-		resp.Tasks[i] = p.InternalHistoryTask{
-			Key:  tasks.NewImmediateKey(row.TaskID),
-			Blob: p.NewDataBlob(row.Data, row.DataEncoding),
+	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetVisibilityTasks", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
 		}
+		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+			Key:  tasks.NewImmediateKey(row.TaskID),
+			Blob: m.newTaskDataBlob("GetVisibilityTasks", row.Data, row.DataEncoding),
+		})
 	}
 	if len(rows) == request.BatchSize {
 		resp.NextPageToken = getImmediateTaskNextPageToken(
@@ -757,21 +1727,27 @@ func (m *sqlExecutionStore) getVisibilityTasks(
 func (m *sqlExecutionStore) completeVisibilityTask(
 	ctx context.Context,
 	request *p.CompleteHistoryTaskRequest,
-) error {
-	if _, err := m.Db.DeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksFilter{
+) (int64, error) {
+	result, err := m.Db.DeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksFilter{
 		ShardID: request.ShardID,
 		TaskID:  request.TaskKey.TaskID,
-	}); err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("CompleteVisibilityTask operation failed. Error: %v", err))
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteVisibilityTask operation failed. Error: %v", err))
 	}
-	return nil
+	rowsDeleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("CompleteVisibilityTask operation failed. Error: %v", err))
+	}
+	return rowsDeleted, nil
 }
 
 func (m *sqlExecutionStore) rangeCompleteVisibilityTasks(
 	ctx context.Context,
+	db sqlplugin.TableCRUD,
 	request *p.RangeCompleteHistoryTasksRequest,
 ) error {
-	if _, err := m.Db.RangeDeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+	if _, err := db.RangeDeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
 		ShardID:            request.ShardID,
 		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
 		ExclusiveMaxTaskID: request.ExclusiveMaxTaskKey.TaskID,
@@ -786,10 +1762,96 @@ type scheduledTaskPageToken struct {
 	Timestamp time.Time
 }
 
-func (t *scheduledTaskPageToken) serialize() ([]byte, error) {
-	return json.Marshal(t)
+// nextScheduledTaskPageToken returns the page token continuing right after the task with the given key. Pages
+// continue from the same timestamp with the next task ID, so that a page ending in the middle of tasks sharing
+// a timestamp neither skips nor repeats any of them. After the largest possible task ID, the next page starts
+// at the next timestamp that can be persisted instead, as the task ID can't be incremented.
+func nextScheduledTaskPageToken(lastTimestamp time.Time, lastTaskID int64) *scheduledTaskPageToken {
+	if lastTaskID == math.MaxInt64 {
+		return &scheduledTaskPageToken{
+			TaskID:    math.MinInt64,
+			Timestamp: lastTimestamp.Truncate(p.ScheduledTaskMinPrecision).Add(p.ScheduledTaskMinPrecision),
+		}
+	}
+	return &scheduledTaskPageToken{
+		TaskID:    lastTaskID + 1,
+		Timestamp: lastTimestamp,
+	}
+}
+
+func (t *scheduledTaskPageToken) serialize(urlSafe bool, binary bool) ([]byte, error) {
+	var payload []byte
+	if binary {
+		payload = t.serializeV2()
+	} else {
+		var err error
+		if payload, err = json.Marshal(t); err != nil {
+			return nil, err
+		}
+	}
+	if !urlSafe {
+		return payload, nil
+	}
+	return []byte(base64.RawURLEncoding.EncodeToString(payload)), nil
 }
 
 func (t *scheduledTaskPageToken) deserialize(payload []byte) error {
+	// tokens are raw JSON objects, raw binary tokens starting with their version byte, or either of them URL-safe
+	// base64 encoded, which never starts with '{' or the version byte
+	if len(payload) > 0 && payload[0] != '{' && payload[0] != scheduledTaskPageTokenV2 {
+		decoded, err := base64.RawURLEncoding.DecodeString(string(payload))
+		if err != nil {
+			return err
+		}
+		payload = decoded
+	}
+	if len(payload) > 0 && payload[0] == scheduledTaskPageTokenV2 {
+		return t.deserializeV2(payload)
+	}
 	return json.Unmarshal(payload, t)
 }
+
+// serializeV2 encodes the token as its version byte followed by a protobuf message with the task ID as field 1
+// and the timestamp in Unix nanoseconds as field 2, both varints.
+func (t *scheduledTaskPageToken) serializeV2() []byte {
+	payload := []byte{scheduledTaskPageTokenV2}
+	payload = protowire.AppendTag(payload, 1, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, uint64(t.TaskID))
+	payload = protowire.AppendTag(payload, 2, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, protowire.EncodeZigZag(t.Timestamp.UnixNano()))
+	return payload
+}
+
+func (t *scheduledTaskPageToken) deserializeV2(payload []byte) error {
+	if len(payload) == 0 || payload[0] != scheduledTaskPageTokenV2 {
+		return errors.New("not a binary scheduled task page token")
+	}
+	*t = scheduledTaskPageToken{Timestamp: time.Unix(0, 0).UTC()}
+	payload = payload[1:]
+	for len(payload) > 0 {
+		number, wireType, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		payload = payload[n:]
+		if wireType != protowire.VarintType {
+			if n = protowire.ConsumeFieldValue(number, wireType, payload); n < 0 {
+				return protowire.ParseError(n)
+			}
+			payload = payload[n:]
+			continue
+		}
+		value, n := protowire.ConsumeVarint(payload)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		payload = payload[n:]
+		switch number {
+		case 1:
+			t.TaskID = int64(value)
+		case 2:
+			t.Timestamp = time.Unix(0, protowire.DecodeZigZag(value)).UTC()
+		}
+	}
+	return nil
+}
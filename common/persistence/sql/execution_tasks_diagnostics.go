@@ -0,0 +1,371 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/definition"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+type (
+	// ReplicationStreamHealth summarizes the replication tasks of a shard. GapCount is the number of task IDs
+	// between MinTaskID and MaxTaskID that have no task, 0 for a contiguous stream.
+	ReplicationStreamHealth struct {
+		MinTaskID int64
+		MaxTaskID int64
+		TaskCount int64
+		GapCount  int64
+	}
+
+	// HistoryTaskRowInfo is a history task together with the metadata of the row it is stored in.
+	// DataEncoding is the encoding stored in the row, which is empty for rows written without one, while the
+	// encoding of Task.Blob falls back to the configured default. SourceClusterName and Reason are only set
+	// for tasks read from the replication DLQ.
+	HistoryTaskRowInfo struct {
+		Task              p.InternalHistoryTask
+		DataEncoding      string
+		DataLength        int
+		DataChecksum      *int64
+		SourceClusterName string
+		Reason            string
+	}
+)
+
+// pendingTransferTaskExecutionsPageSize is the page size transfer tasks are read with by
+// GetExecutionsWithPendingTransferTasks
+const pendingTransferTaskExecutionsPageSize = 1000
+
+// GetReplicationStreamHealth returns the task ID range, the number of tasks and the number of missing task IDs
+// of the replication tasks of a shard, computed with a single aggregate query.
+func (m *sqlExecutionStore) GetReplicationStreamHealth(
+	ctx context.Context,
+	shardID int32,
+) (ReplicationStreamHealth, error) {
+	stats, err := m.readOnlyDb().SelectStatsFromReplicationTasks(ctx, shardID)
+	if err != nil {
+		return ReplicationStreamHealth{}, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationStreamHealth operation failed. Error: %v", err))
+	}
+	health := ReplicationStreamHealth{
+		MinTaskID: stats.MinTaskID,
+		MaxTaskID: stats.MaxTaskID,
+		TaskCount: stats.TaskCount,
+	}
+	if stats.TaskCount > 0 {
+		health.GapCount = stats.MaxTaskID - stats.MinTaskID + 1 - stats.TaskCount
+	}
+	return health, nil
+}
+
+// GetTransferTaskCount returns the number of transfer tasks of a shard with a task ID in
+// [inclusiveMinTaskID, exclusiveMaxTaskID). The count is computed by the database on the primary key index,
+// without reading any task data.
+func (m *sqlExecutionStore) GetTransferTaskCount(
+	ctx context.Context,
+	shardID int32,
+	inclusiveMinTaskID int64,
+	exclusiveMaxTaskID int64,
+) (int64, error) {
+	count, err := m.readOnlyDb().RangeCountFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: inclusiveMinTaskID,
+		ExclusiveMaxTaskID: exclusiveMaxTaskID,
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("GetTransferTaskCount operation failed. Error: %v", err))
+	}
+	return count, nil
+}
+
+// GetShardTaskCountsByNamespace returns the number of transfer, timer, replication and visibility tasks of a
+// shard by namespace ID and category. Namespaces without tasks are omitted.
+// The task tables have no namespace column, so every task of the shard is read, in pages of pageSize tasks, and
+// decoded. The cost is a full scan of the shard's tasks; this is meant for capacity analysis, not for frequent
+// polling.
+func (m *sqlExecutionStore) GetShardTaskCountsByNamespace(
+	ctx context.Context,
+	shardID int32,
+	pageSize int,
+) (map[string]map[tasks.Category]int64, error) {
+	if pageSize <= 0 {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf("GetShardTaskCountsByNamespace: page size %v must be positive", pageSize))
+	}
+
+	taskCounts := make(map[string]map[tasks.Category]int64)
+	for _, category := range []tasks.Category{
+		tasks.CategoryTransfer,
+		tasks.CategoryTimer,
+		tasks.CategoryReplication,
+		tasks.CategoryVisibility,
+	} {
+		decodeNamespaceID := legacyTaskNamespaceDecoders[category.ID()]
+		request := &p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        category,
+			InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+			ExclusiveMaxTaskKey: tasks.NewImmediateKey(math.MaxInt64),
+			BatchSize:           pageSize,
+		}
+		if category.Type() == tasks.CategoryTypeScheduled {
+			request.InclusiveMinTaskKey = tasks.MinimumKey
+			request.ExclusiveMaxTaskKey = tasks.MaximumKey
+		}
+		for {
+			resp, err := m.getHistoryTasks(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			for _, task := range resp.Tasks {
+				namespaceID, err := decodeNamespaceID(task.Blob)
+				if err != nil {
+					return nil, serviceerror.NewInternal(fmt.Sprintf(
+						"GetShardTaskCountsByNamespace: failed to decode %v task %v: %v",
+						category.Name(),
+						task.Key.TaskID,
+						err,
+					))
+				}
+				if taskCounts[namespaceID] == nil {
+					taskCounts[namespaceID] = make(map[tasks.Category]int64)
+				}
+				taskCounts[namespaceID][category]++
+			}
+			if len(resp.NextPageToken) == 0 {
+				break
+			}
+			request.NextPageToken = resp.NextPageToken
+		}
+	}
+	return taskCounts, nil
+}
+
+// GetExecutionsWithPendingTransferTasks returns up to limit distinct workflow executions of a shard that have
+// pending transfer tasks, in the order of their first pending transfer task.
+// The transfer_tasks table has no execution columns, so the executions can't be grouped by the database; transfer
+// tasks are read in task ID order and decoded until limit executions were found, or all tasks were read.
+func (m *sqlExecutionStore) GetExecutionsWithPendingTransferTasks(
+	ctx context.Context,
+	shardID int32,
+	limit int,
+) ([]definition.WorkflowKey, error) {
+	if limit <= 0 {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf("GetExecutionsWithPendingTransferTasks: limit %v must be positive", limit))
+	}
+
+	var executions []definition.WorkflowKey
+	seen := make(map[definition.WorkflowKey]struct{})
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(math.MaxInt64),
+		BatchSize:           pendingTransferTaskExecutionsPageSize,
+	}
+	for {
+		resp, err := m.getHistoryTasks(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range resp.Tasks {
+			info, err := serialization.TransferTaskInfoFromBlob(task.Blob.Data, task.Blob.EncodingType.String())
+			if err != nil {
+				return nil, serviceerror.NewInternal(fmt.Sprintf(
+					"GetExecutionsWithPendingTransferTasks: failed to decode transfer task %v: %v",
+					task.Key.TaskID,
+					err,
+				))
+			}
+			execution := definition.NewWorkflowKey(info.GetNamespaceId(), info.GetWorkflowId(), info.GetRunId())
+			if _, ok := seen[execution]; ok {
+				continue
+			}
+			seen[execution] = struct{}{}
+			executions = append(executions, execution)
+			if len(executions) == limit {
+				return executions, nil
+			}
+		}
+		if len(resp.NextPageToken) == 0 {
+			return executions, nil
+		}
+		request.NextPageToken = resp.NextPageToken
+	}
+}
+
+// GetOldestTimerTask returns the timer task of a shard with the smallest task key with a single
+// ORDER BY visibility_timestamp, task_id LIMIT 1 query. The task data is returned as stored, without verifying
+// its checksum, so that a corrupted task doesn't hide the tasks behind it.
+func (m *sqlExecutionStore) GetOldestTimerTask(
+	ctx context.Context,
+	request *p.GetOldestTimerTaskRequest,
+) (*p.InternalGetOldestTimerTaskResponse, error) {
+	row, err := m.Db.SelectOldestFromTimerTasks(ctx, request.ShardID)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return &p.InternalGetOldestTimerTaskResponse{}, nil
+	default:
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetOldestTimerTask operation failed. Error: %v", err))
+	}
+
+	return &p.InternalGetOldestTimerTaskResponse{
+		Task: &p.InternalHistoryTask{
+			Key:  tasks.NewKey(row.VisibilityTimestamp, row.TaskID),
+			Blob: m.newTaskDataBlob("GetOldestTimerTask", row.Data, row.DataEncoding),
+		},
+	}, nil
+}
+
+// GetTransferTaskRowInfo returns a transfer task together with the metadata of its row, for debugging. The
+// task data is returned as stored, without verifying its checksum.
+func (m *sqlExecutionStore) GetTransferTaskRowInfo(
+	ctx context.Context,
+	shardID int32,
+	taskID int64,
+) (*HistoryTaskRowInfo, error) {
+	rows, err := m.readOnlyDb().RangeSelectFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: taskID,
+		ExclusiveMaxTaskID: taskID + 1,
+		PageSize:           1,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetTransferTaskRowInfo operation failed. Error: %v", err))
+	}
+	if len(rows) == 0 {
+		return nil, serviceerror.NewNotFound(fmt.Sprintf("transfer task %v of shard %v not found", taskID, shardID))
+	}
+	row := rows[0]
+	return m.newHistoryTaskRowInfo("GetTransferTaskRowInfo", tasks.NewImmediateKey(row.TaskID), row.Data, row.DataEncoding, row.DataChecksum), nil
+}
+
+// GetTimerTaskRowInfo returns a timer task together with the metadata of its row, for debugging. The task
+// data is returned as stored, without verifying its checksum.
+func (m *sqlExecutionStore) GetTimerTaskRowInfo(
+	ctx context.Context,
+	shardID int32,
+	taskKey tasks.Key,
+) (*HistoryTaskRowInfo, error) {
+	rows, err := m.readOnlyDb().RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: taskKey.FireTime,
+		InclusiveMinTaskID:              taskKey.TaskID,
+		ExclusiveMaxVisibilityTimestamp: taskKey.FireTime.Add(time.Microsecond),
+		PageSize:                        1,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetTimerTaskRowInfo operation failed. Error: %v", err))
+	}
+	if len(rows) == 0 || rows[0].TaskID != taskKey.TaskID || !rows[0].VisibilityTimestamp.Equal(taskKey.FireTime) {
+		return nil, serviceerror.NewNotFound(fmt.Sprintf("timer task %v of shard %v not found", taskKey, shardID))
+	}
+	row := rows[0]
+	return m.newHistoryTaskRowInfo("GetTimerTaskRowInfo", tasks.NewKey(row.VisibilityTimestamp, row.TaskID), row.Data, row.DataEncoding, row.DataChecksum), nil
+}
+
+// GetReplicationTaskRowInfo returns a replication task together with the metadata of its row, for debugging.
+// The task data is returned as stored, without verifying its checksum.
+func (m *sqlExecutionStore) GetReplicationTaskRowInfo(
+	ctx context.Context,
+	shardID int32,
+	taskID int64,
+) (*HistoryTaskRowInfo, error) {
+	rows, err := m.readOnlyDb().RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: taskID,
+		ExclusiveMaxTaskID: taskID + 1,
+		PageSize:           1,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationTaskRowInfo operation failed. Error: %v", err))
+	}
+	if len(rows) == 0 {
+		return nil, serviceerror.NewNotFound(fmt.Sprintf("replication task %v of shard %v not found", taskID, shardID))
+	}
+	row := rows[0]
+	return m.newHistoryTaskRowInfo("GetReplicationTaskRowInfo", tasks.NewImmediateKey(row.TaskID), row.Data, row.DataEncoding, row.DataChecksum), nil
+}
+
+// GetReplicationDLQTaskRowInfo returns a task of the replication DLQ of a source cluster together with the
+// metadata of its row, for debugging. The task data is returned as stored, without verifying its checksum.
+func (m *sqlExecutionStore) GetReplicationDLQTaskRowInfo(
+	ctx context.Context,
+	shardID int32,
+	sourceClusterName string,
+	taskID int64,
+) (*HistoryTaskRowInfo, error) {
+	rows, err := m.replicationDLQ.GetTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            shardID,
+		SourceClusterName:  sourceClusterName,
+		InclusiveMinTaskID: taskID,
+		ExclusiveMaxTaskID: taskID + 1,
+		PageSize:           1,
+	})
+	if err != nil {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationDLQTaskRowInfo operation failed. Error: %v", err))
+	}
+	if len(rows) == 0 {
+		return nil, serviceerror.NewNotFound(fmt.Sprintf(
+			"replication DLQ task %v of shard %v and source cluster %v not found",
+			taskID,
+			shardID,
+			sourceClusterName,
+		))
+	}
+	row := rows[0]
+	info := m.newHistoryTaskRowInfo("GetReplicationDLQTaskRowInfo", tasks.NewImmediateKey(row.TaskID), row.Data, row.DataEncoding, row.DataChecksum)
+	info.SourceClusterName = sourceClusterName
+	if row.Reason != nil {
+		info.Reason = *row.Reason
+	}
+	return info, nil
+}
+
+func (m *sqlExecutionStore) newHistoryTaskRowInfo(
+	operation string,
+	key tasks.Key,
+	data []byte,
+	dataEncoding string,
+	dataChecksum *int64,
+) *HistoryTaskRowInfo {
+	return &HistoryTaskRowInfo{
+		Task: p.InternalHistoryTask{
+			Key:  key,
+			Blob: m.newTaskDataBlob(operation, data, dataEncoding),
+		},
+		DataEncoding: dataEncoding,
+		DataLength:   len(data),
+		DataChecksum: dataChecksum,
+	}
+}
@@ -0,0 +1,253 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	persistencespb "go.temporal.io/server/api/persistence/v1"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/definition"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func TestGetTransferTaskCount(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	count, err := store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	_, err = db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 3, Data: []byte("transfer"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 5, Data: []byte("transfer"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 10, Data: []byte("transfer"), DataEncoding: "test"},
+		// tasks of other shards are ignored
+		{ShardID: shardID + 1, TaskID: 4, Data: []byte("transfer"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	count, err = store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	count, err = store.GetTransferTaskCount(ctx, shardID, 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestGetShardTaskCountsByNamespace(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	namespaceA := uuid.New()
+	namespaceB := uuid.New()
+
+	for taskID, namespaceID := range []string{namespaceA, namespaceA, namespaceA, namespaceB} {
+		blob, err := serialization.TransferTaskInfoToBlob(&persistencespb.TransferTaskInfo{NamespaceId: namespaceID, TaskId: int64(taskID)})
+		require.NoError(t, err)
+		_, err = db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+			{ShardID: shardID, TaskID: int64(taskID), Data: blob.Data, DataEncoding: blob.EncodingType.String()},
+		})
+		require.NoError(t, err)
+	}
+	for taskID, namespaceID := range []string{namespaceB, namespaceB, namespaceA} {
+		blob, err := serialization.TimerTaskInfoToBlob(&persistencespb.TimerTaskInfo{NamespaceId: namespaceID, TaskId: int64(taskID)})
+		require.NoError(t, err)
+		_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+			{ShardID: shardID, VisibilityTimestamp: now.Add(time.Duration(taskID) * time.Second), TaskID: int64(taskID), Data: blob.Data, DataEncoding: blob.EncodingType.String()},
+		})
+		require.NoError(t, err)
+	}
+	blob, err := serialization.ReplicationTaskInfoToBlob(&persistencespb.ReplicationTaskInfo{NamespaceId: namespaceB, TaskId: 1})
+	require.NoError(t, err)
+	_, err = db.InsertIntoReplicationTasks(ctx, []sqlplugin.ReplicationTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: blob.Data, DataEncoding: blob.EncodingType.String()},
+	})
+	require.NoError(t, err)
+	blob, err = serialization.VisibilityTaskInfoToBlob(&persistencespb.VisibilityTaskInfo{NamespaceId: namespaceA, TaskId: 1})
+	require.NoError(t, err)
+	_, err = db.InsertIntoVisibilityTasks(ctx, []sqlplugin.VisibilityTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: blob.Data, DataEncoding: blob.EncodingType.String()},
+	})
+	require.NoError(t, err)
+	// tasks of other shards are ignored
+	insertReplicationTask(t, db, shardID+1, 1)
+
+	taskCounts, err := store.GetShardTaskCountsByNamespace(ctx, shardID, 2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[tasks.Category]int64{
+		namespaceA: {tasks.CategoryTransfer: 3, tasks.CategoryTimer: 1, tasks.CategoryVisibility: 1},
+		namespaceB: {tasks.CategoryTransfer: 1, tasks.CategoryTimer: 2, tasks.CategoryReplication: 1},
+	}, taskCounts)
+
+	_, err = store.GetShardTaskCountsByNamespace(ctx, shardID, 0)
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+}
+
+func TestGetExecutionsWithPendingTransferTasks(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	executionA := definition.NewWorkflowKey(uuid.New(), "workflow-a", uuid.New())
+	executionB := definition.NewWorkflowKey(executionA.NamespaceID, "workflow-b", uuid.New())
+	executionC := definition.NewWorkflowKey(uuid.New(), "workflow-a", uuid.New())
+	for taskID, execution := range []definition.WorkflowKey{
+		executionB, executionA, executionB, executionB, executionC, executionA,
+	} {
+		blob, err := serialization.TransferTaskInfoToBlob(&persistencespb.TransferTaskInfo{
+			NamespaceId: execution.NamespaceID,
+			WorkflowId:  execution.WorkflowID,
+			RunId:       execution.RunID,
+			TaskId:      int64(taskID),
+		})
+		require.NoError(t, err)
+		_, err = db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+			{ShardID: shardID, TaskID: int64(taskID), Data: blob.Data, DataEncoding: blob.EncodingType.String()},
+		})
+		require.NoError(t, err)
+	}
+
+	executions, err := store.GetExecutionsWithPendingTransferTasks(ctx, shardID, 2)
+	require.NoError(t, err)
+	require.Equal(t, []definition.WorkflowKey{executionB, executionA}, executions)
+
+	executions, err = store.GetExecutionsWithPendingTransferTasks(ctx, shardID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []definition.WorkflowKey{executionB, executionA, executionC}, executions)
+
+	executions, err = store.GetExecutionsWithPendingTransferTasks(ctx, shardID+1, 10)
+	require.NoError(t, err)
+	require.Empty(t, executions)
+
+	_, err = store.GetExecutionsWithPendingTransferTasks(ctx, shardID, 0)
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+}
+
+func TestGetReplicationStreamHealth(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	health, err := store.GetReplicationStreamHealth(ctx, shardID)
+	require.NoError(t, err)
+	require.Equal(t, sql.ReplicationStreamHealth{}, health)
+
+	// 5, 6, 8 and 9 are missing
+	for _, taskID := range []int64{3, 4, 7, 10, 11} {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+	health, err = store.GetReplicationStreamHealth(ctx, shardID)
+	require.NoError(t, err)
+	require.Equal(t, sql.ReplicationStreamHealth{
+		MinTaskID: 3,
+		MaxTaskID: 11,
+		TaskCount: 5,
+		GapCount:  4,
+	}, health)
+}
+
+func TestGetHistoryTaskRowInfo(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	data := []byte("task-data")
+	checksum := int64(crc32.ChecksumIEEE(data))
+	reason := "apply failed"
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: data, DataEncoding: "Proto3", DataChecksum: &checksum},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now, TaskID: 2, Data: data, DataEncoding: "Proto3", DataChecksum: &checksum},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoReplicationTasks(ctx, []sqlplugin.ReplicationTasksRow{
+		{ShardID: shardID, TaskID: 3, Data: data, DataEncoding: "Proto3", DataChecksum: &checksum},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoReplicationDLQTasks(ctx, []sqlplugin.ReplicationDLQTasksRow{
+		{SourceClusterName: "cluster-a", ShardID: shardID, TaskID: 4, Data: data, DataEncoding: "Proto3", DataChecksum: &checksum, Reason: &reason},
+	})
+	require.NoError(t, err)
+
+	requireRowInfo := func(info *sql.HistoryTaskRowInfo, key tasks.Key) {
+		require.Equal(t, 0, info.Task.Key.CompareTo(key))
+		require.Equal(t, data, info.Task.Blob.Data)
+		require.Equal(t, "Proto3", info.DataEncoding)
+		require.Equal(t, len(data), info.DataLength)
+		require.NotNil(t, info.DataChecksum)
+		require.Equal(t, checksum, *info.DataChecksum)
+	}
+
+	info, err := store.GetTransferTaskRowInfo(ctx, shardID, 1)
+	require.NoError(t, err)
+	requireRowInfo(info, tasks.NewImmediateKey(1))
+	require.Empty(t, info.SourceClusterName)
+
+	info, err = store.GetTimerTaskRowInfo(ctx, shardID, tasks.NewKey(now, 2))
+	require.NoError(t, err)
+	requireRowInfo(info, tasks.NewKey(now, 2))
+
+	info, err = store.GetReplicationTaskRowInfo(ctx, shardID, 3)
+	require.NoError(t, err)
+	requireRowInfo(info, tasks.NewImmediateKey(3))
+
+	info, err = store.GetReplicationDLQTaskRowInfo(ctx, shardID, "cluster-a", 4)
+	require.NoError(t, err)
+	requireRowInfo(info, tasks.NewImmediateKey(4))
+	require.Equal(t, "cluster-a", info.SourceClusterName)
+	require.Equal(t, reason, info.Reason)
+
+	_, err = store.GetTransferTaskRowInfo(ctx, shardID, 2)
+	require.ErrorAs(t, err, new(*serviceerror.NotFound))
+	_, err = store.GetTimerTaskRowInfo(ctx, shardID, tasks.NewKey(now, 1))
+	require.ErrorAs(t, err, new(*serviceerror.NotFound))
+	_, err = store.GetReplicationDLQTaskRowInfo(ctx, shardID, "cluster-b", 4)
+	require.ErrorAs(t, err, new(*serviceerror.NotFound))
+}
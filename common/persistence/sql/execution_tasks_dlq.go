@@ -0,0 +1,510 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+
+	"go.temporal.io/api/serviceerror"
+	persistencespb "go.temporal.io/server/api/persistence/v1"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func (m *sqlExecutionStore) populateGetReplicationDLQTasksResponse(
+	shardID int32,
+	rows []sqlplugin.ReplicationDLQTasksRow,
+	exclusiveMaxTaskID int64,
+	batchSize int,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	if len(rows) == 0 {
+		return &p.InternalGetHistoryTasksResponse{}, nil
+	}
+
+	var dlqTasks = make([]p.InternalHistoryTask, 0, len(rows))
+	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetReplicationTasksFromDLQ", shardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		dlqTasks = append(dlqTasks, p.InternalHistoryTask{
+			Key:  tasks.NewImmediateKey(row.TaskID),
+			Blob: m.newTaskDataBlob("GetReplicationTasksFromDLQ", row.Data, row.DataEncoding),
+		})
+	}
+	var nextPageToken []byte
+	if len(rows) == batchSize {
+		nextPageToken = getImmediateTaskNextPageToken(
+			rows[len(rows)-1].TaskID,
+			exclusiveMaxTaskID,
+		)
+	}
+	return &p.InternalGetHistoryTasksResponse{
+		Tasks:         dlqTasks,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (m *sqlExecutionStore) PutReplicationTaskToDLQ(
+	ctx context.Context,
+	request *p.PutReplicationTaskToDLQRequest,
+) error {
+	replicationTask := request.TaskInfo
+	blob, err := serialization.ReplicationTaskInfoToBlob(replicationTask)
+
+	if err != nil {
+		return err
+	}
+
+	var reason *string
+	if request.Reason != "" {
+		reason = &request.Reason
+	}
+	// Tasks are immutable. So it's fine if we already persisted it before.
+	// This can happen when tasks are retried (ack and cleanup can have lag on source side).
+	if err := m.replicationDLQ.PutTasks(ctx, []sqlplugin.ReplicationDLQTasksRow{{
+		SourceClusterName: request.SourceClusterName,
+		ShardID:           request.ShardID,
+		TaskID:            replicationTask.GetTaskId(),
+		Data:              blob.Data,
+		DataEncoding:      blob.EncodingType.String(),
+		DataChecksum:      taskDataChecksum(m.writeTaskDataChecksums(), blob.Data),
+		Reason:            reason,
+	}}); err != nil {
+		return serviceerror.NewUnavailable(fmt.Sprintf("Failed to create replication tasks. Error: %v", err))
+	}
+
+	return nil
+}
+
+// PutReplicationTasksToDLQ stores several replication tasks of a shard in the replication DLQ of sourceClusterName.
+// With the default DLQ store, the tasks are inserted with a single statement. As tasks are immutable, tasks that
+// are already in the DLQ are ignored: if the statement fails because of them, the tasks are inserted one by one,
+// skipping the duplicates.
+func (m *sqlExecutionStore) PutReplicationTasksToDLQ(
+	ctx context.Context,
+	shardID int32,
+	sourceClusterName string,
+	taskInfos []*persistencespb.ReplicationTaskInfo,
+) error {
+	if len(taskInfos) == 0 {
+		return nil
+	}
+	rows := make([]sqlplugin.ReplicationDLQTasksRow, 0, len(taskInfos))
+	for _, taskInfo := range taskInfos {
+		blob, err := serialization.ReplicationTaskInfoToBlob(taskInfo)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, sqlplugin.ReplicationDLQTasksRow{
+			SourceClusterName: sourceClusterName,
+			ShardID:           shardID,
+			TaskID:            taskInfo.GetTaskId(),
+			Data:              blob.Data,
+			DataEncoding:      blob.EncodingType.String(),
+			DataChecksum:      taskDataChecksum(m.writeTaskDataChecksums(), blob.Data),
+		})
+	}
+
+	if err := m.replicationDLQ.PutTasks(ctx, rows); err != nil {
+		return serviceerror.NewUnavailable(fmt.Sprintf("PutReplicationTasksToDLQ operation failed. Error: %v", err))
+	}
+	return nil
+}
+
+// MoveReplicationTasksToDLQ moves the given replication tasks of a shard into the replication DLQ of
+// sourceClusterName in a single transaction: every task is read, inserted into the DLQ and deleted from
+// the replication_tasks table. Tasks already present in the DLQ are left as is, since tasks are immutable.
+// If any task is missing or any statement fails, nothing is moved.
+func (m *sqlExecutionStore) MoveReplicationTasksToDLQ(
+	ctx context.Context,
+	shardID int32,
+	taskIDs []int64,
+	sourceClusterName string,
+) error {
+	if err := m.requireSQLReplicationDLQ("MoveReplicationTasksToDLQ"); err != nil {
+		return err
+	}
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	return m.txExecute(ctx, "MoveReplicationTasksToDLQ", func(tx sqlplugin.Tx) error {
+		dlqRows := make([]sqlplugin.ReplicationDLQTasksRow, 0, len(taskIDs))
+		seen := make(map[int64]struct{}, len(taskIDs))
+		for _, taskID := range taskIDs {
+			if _, ok := seen[taskID]; ok {
+				continue
+			}
+			seen[taskID] = struct{}{}
+
+			rows, err := tx.RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: taskID,
+				ExclusiveMaxTaskID: taskID + 1,
+				PageSize:           1,
+			})
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if len(rows) == 0 {
+				return serviceerror.NewNotFound(
+					fmt.Sprintf("MoveReplicationTasksToDLQ failed. Replication task not found. ShardID: %v, TaskID: %v", shardID, taskID),
+				)
+			}
+
+			// check for an existing DLQ row up front, as a failed insert aborts the transaction on PostgreSQL
+			existingRows, err := tx.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+				ShardID:            shardID,
+				SourceClusterName:  sourceClusterName,
+				InclusiveMinTaskID: taskID,
+				ExclusiveMaxTaskID: taskID + 1,
+				PageSize:           1,
+			})
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if len(existingRows) == 0 {
+				dlqRows = append(dlqRows, sqlplugin.ReplicationDLQTasksRow{
+					SourceClusterName: sourceClusterName,
+					ShardID:           shardID,
+					TaskID:            taskID,
+					Data:              rows[0].Data,
+					DataEncoding:      rows[0].DataEncoding,
+					DataChecksum:      taskDataChecksum(m.writeTaskDataChecksums(), rows[0].Data),
+				})
+			}
+		}
+
+		if len(dlqRows) != 0 {
+			if _, err := tx.InsertIntoReplicationDLQTasks(ctx, dlqRows); err != nil {
+				return err
+			}
+		}
+		for _, taskID := range taskIDs {
+			if _, err := tx.DeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksFilter{
+				ShardID: shardID,
+				TaskID:  taskID,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetReplicationDLQReasonCounts returns the number of replication DLQ tasks of a shard and source cluster by the
+// reason they were put into the DLQ. Tasks put without a reason are counted under the empty reason.
+func (m *sqlExecutionStore) GetReplicationDLQReasonCounts(
+	ctx context.Context,
+	shardID int32,
+	sourceClusterName string,
+) (map[string]int64, error) {
+	if err := m.requireSQLReplicationDLQ("GetReplicationDLQReasonCounts"); err != nil {
+		return nil, err
+	}
+	rows, err := m.readOnlyDb().SelectReasonCountsFromReplicationDLQTasks(ctx, shardID, sourceClusterName)
+	if err != nil {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationDLQReasonCounts operation failed. Error: %v", err))
+	}
+	reasonCounts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		reasonCounts[row.Reason] += row.TaskCount
+	}
+	return reasonCounts, nil
+}
+
+// GetReplicationDLQTaskCount returns the number of replication DLQ tasks of a shard and source cluster without
+// reading them. If maxTaskID is not nil, only tasks with task IDs below it are counted.
+func (m *sqlExecutionStore) GetReplicationDLQTaskCount(
+	ctx context.Context,
+	shardID int32,
+	sourceClusterName string,
+	maxTaskID *int64,
+) (int64, error) {
+	if err := m.requireSQLReplicationDLQ("GetReplicationDLQTaskCount"); err != nil {
+		return 0, err
+	}
+	exclusiveMaxTaskID := int64(math.MaxInt64)
+	if maxTaskID != nil {
+		exclusiveMaxTaskID = *maxTaskID
+	}
+	count, err := m.readOnlyDb().SelectCountFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            shardID,
+		SourceClusterName:  sourceClusterName,
+		InclusiveMinTaskID: 0,
+		ExclusiveMaxTaskID: exclusiveMaxTaskID,
+	})
+	if err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationDLQTaskCount operation failed. Error: %v", err))
+	}
+	return count, nil
+}
+
+// ArchiveReplicationDLQ moves all replication DLQ tasks of a shard, of every source cluster, into the archive
+// table with the given suffix and returns the number of tasks archived. The archive table is created first if it
+// doesn't exist; copying the tasks and deleting them from the DLQ then happens in a single transaction, so either
+// all tasks are archived or none. The suffix may only consist of up to 32 letters, digits and underscores.
+// Like MoveReplicationTasksToDLQ, this works on the replication_tasks_dlq table directly.
+func (m *sqlExecutionStore) ArchiveReplicationDLQ(
+	ctx context.Context,
+	shardID int32,
+	archiveTableSuffix string,
+) (int64, error) {
+	if err := m.requireSQLReplicationDLQ("ArchiveReplicationDLQ"); err != nil {
+		return 0, err
+	}
+	if _, err := sqlplugin.ReplicationDLQArchiveTableName(archiveTableSuffix); err != nil {
+		return 0, serviceerror.NewInvalidArgument(fmt.Sprintf("ArchiveReplicationDLQ failed. Error: %v", err))
+	}
+	// not part of the transaction, as MySQL implicitly commits DDL statements
+	if err := m.Db.CreateReplicationDLQArchiveTable(ctx, archiveTableSuffix); err != nil {
+		return 0, serviceerror.NewUnavailable(fmt.Sprintf("ArchiveReplicationDLQ operation failed. Failed to create archive table. Error: %v", err))
+	}
+
+	var archived int64
+	err := m.txExecute(ctx, "ArchiveReplicationDLQ", func(tx sqlplugin.Tx) error {
+		result, err := tx.CopyIntoReplicationDLQArchive(ctx, shardID, archiveTableSuffix)
+		if err != nil {
+			return err
+		}
+		copied, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		result, err = tx.DeleteShardFromReplicationDLQTasks(ctx, shardID)
+		if err != nil {
+			return err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if copied != deleted {
+			return serviceerror.NewUnavailable(fmt.Sprintf(
+				"ArchiveReplicationDLQ operation failed. Archived %v tasks, but deleted %v", copied, deleted,
+			))
+		}
+		archived = copied
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// GetReplicationTasksFromDLQ reads a page of replication DLQ tasks. A page is read with a single statement, which
+// MySQL, PostgreSQL and SQLite all evaluate against one consistent snapshot, so tasks inserted concurrently
+// don't change a page while it is read. Consistency across pages comes only from the page token: the next page
+// starts after the last task ID returned, and tasks inserted below it in the meantime are not read.
+func (m *sqlExecutionStore) GetReplicationTasksFromDLQ(
+	ctx context.Context,
+	request *p.GetReplicationTasksFromDLQRequest,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	inclusiveMinTaskID, exclusiveMaxTaskID, err := m.getImmediateTaskReadRange(&request.GetHistoryTasksRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.replicationDLQ.GetTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            request.ShardID,
+		InclusiveMinTaskID: inclusiveMinTaskID,
+		ExclusiveMaxTaskID: exclusiveMaxTaskID,
+		PageSize:           request.BatchSize,
+		SourceClusterName:  request.SourceClusterName,
+	})
+	if err != nil {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetReplicationTasks operation failed. Select failed: %v", err))
+	}
+	resp, err := m.populateGetReplicationDLQTasksResponse(request.ShardID, rows, request.ExclusiveMaxTaskKey.TaskID, request.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	m.enforceTaskOrder("GetReplicationTasksFromDLQ", request.ShardID, resp.Tasks)
+	return resp, nil
+}
+
+func (m *sqlExecutionStore) DeleteReplicationTaskFromDLQ(
+	ctx context.Context,
+	request *p.DeleteReplicationTaskFromDLQRequest,
+) error {
+	return m.replicationDLQ.DeleteTask(ctx, sqlplugin.ReplicationDLQTasksFilter{
+		ShardID:           request.ShardID,
+		TaskID:            request.TaskKey.TaskID,
+		SourceClusterName: request.SourceClusterName,
+	})
+}
+
+func (m *sqlExecutionStore) RangeDeleteReplicationTaskFromDLQ(
+	ctx context.Context,
+	request *p.RangeDeleteReplicationTaskFromDLQRequest,
+) error {
+	return m.replicationDLQ.RangeDeleteTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            request.ShardID,
+		SourceClusterName:  request.SourceClusterName,
+		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
+		ExclusiveMaxTaskID: request.ExclusiveMaxTaskKey.TaskID,
+	})
+}
+
+func (m *sqlExecutionStore) IsReplicationDLQEmpty(
+	ctx context.Context,
+	request *p.GetReplicationTasksFromDLQRequest,
+) (bool, error) {
+	res, err := m.replicationDLQ.GetTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            request.ShardID,
+		SourceClusterName:  request.SourceClusterName,
+		InclusiveMinTaskID: request.InclusiveMinTaskKey.TaskID,
+		ExclusiveMaxTaskID: math.MaxInt64,
+		PageSize:           1,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(res) == 0, nil
+}
+
+// FindOrphanedDLQSourceClusters returns, sorted, the source clusters that have tasks in the shard's replication
+// DLQ but are not among knownClusters, e.g. because they were removed from the cluster metadata. The DLQs of
+// such clusters are never drained by replication and can be deleted.
+func (m *sqlExecutionStore) FindOrphanedDLQSourceClusters(
+	ctx context.Context,
+	shardID int32,
+	knownClusters []string,
+) ([]string, error) {
+	if err := m.requireSQLReplicationDLQ("FindOrphanedDLQSourceClusters"); err != nil {
+		return nil, err
+	}
+	rows, err := m.Db.SelectMinTaskIDFromReplicationDLQTasks(ctx, shardID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("FindOrphanedDLQSourceClusters operation failed. Select failed: %v", err))
+	}
+
+	var orphanedClusters []string
+	for _, row := range rows {
+		if !slices.Contains(knownClusters, row.SourceClusterName) {
+			orphanedClusters = append(orphanedClusters, row.SourceClusterName)
+		}
+	}
+	slices.Sort(orphanedClusters)
+	return orphanedClusters, nil
+}
+
+// PutTimerTaskToDLQ stores a timer task in the timer DLQ of a shard, so that the timer queue can make progress
+// past a task that keeps failing.
+func (m *sqlExecutionStore) PutTimerTaskToDLQ(
+	ctx context.Context,
+	shardID int32,
+	task p.InternalHistoryTask,
+) error {
+	_, err := m.Db.InsertIntoTimerDLQTasks(ctx, []sqlplugin.TimerDLQTasksRow{{
+		ShardID:             shardID,
+		VisibilityTimestamp: task.Key.FireTime,
+		TaskID:              task.Key.TaskID,
+		Data:                task.Blob.Data,
+		DataEncoding:        task.Blob.EncodingType.String(),
+		DataChecksum:        taskDataChecksum(m.writeTaskDataChecksums(), task.Blob.Data),
+	}})
+
+	// Tasks are immutable. So it's fine if we already persisted it before.
+	if err != nil && !m.Db.IsDupEntryError(err) {
+		return serviceerror.NewUnavailable(fmt.Sprintf("PutTimerTaskToDLQ operation failed. Error: %v", err))
+	}
+	return nil
+}
+
+// GetTimerTasksFromDLQ reads timer tasks from the timer DLQ of a shard. The request's task category is ignored.
+func (m *sqlExecutionStore) GetTimerTasksFromDLQ(
+	ctx context.Context,
+	request *p.GetHistoryTasksRequest,
+) (*p.InternalGetHistoryTasksResponse, error) {
+	pageToken, err := m.getScheduledTaskPageToken(request)
+	if err != nil {
+		return nil, serviceerror.NewInternal(fmt.Sprintf("error deserializing timerTaskPageToken: %v", err))
+	}
+
+	rows, err := m.taskReadDb(request.ReadTier).RangeSelectFromTimerDLQTasks(ctx, sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         request.ShardID,
+		InclusiveMinVisibilityTimestamp: pageToken.Timestamp,
+		InclusiveMinTaskID:              pageToken.TaskID,
+		ExclusiveMaxVisibilityTimestamp: request.ExclusiveMaxTaskKey.FireTime,
+		PageSize:                        request.BatchSize,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, serviceerror.NewUnavailable(fmt.Sprintf("GetTimerTasksFromDLQ operation failed. Select failed. Error: %v", err))
+	}
+
+	resp := &p.InternalGetHistoryTasksResponse{Tasks: make([]p.InternalHistoryTask, 0, len(rows))}
+	for _, row := range rows {
+		ok, err := m.verifyTaskDataChecksum("GetTimerTasksFromDLQ", request.ShardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		resp.Tasks = append(resp.Tasks, p.InternalHistoryTask{
+			Key:  tasks.NewKey(row.VisibilityTimestamp, row.TaskID),
+			Blob: m.newTaskDataBlob("GetTimerTasksFromDLQ", row.Data, row.DataEncoding),
+		})
+	}
+
+	if len(rows) == request.BatchSize {
+		pageToken = nextScheduledTaskPageToken(rows[request.BatchSize-1].VisibilityTimestamp, rows[request.BatchSize-1].TaskID)
+		nextToken, err := pageToken.serialize(m.urlSafePageTokens, m.binaryPageTokens)
+		if err != nil {
+			return nil, serviceerror.NewInternal(fmt.Sprintf("GetTimerTasksFromDLQ: error serializing page token: %v", err))
+		}
+		resp.NextPageToken = nextToken
+	}
+
+	m.enforceTaskOrder("GetTimerTasksFromDLQ", request.ShardID, resp.Tasks)
+	return resp, nil
+}
+
+// DeleteTimerTaskFromDLQ deletes a timer task from the timer DLQ of a shard.
+func (m *sqlExecutionStore) DeleteTimerTaskFromDLQ(
+	ctx context.Context,
+	shardID int32,
+	taskKey tasks.Key,
+) error {
+	if _, err := m.Db.DeleteFromTimerDLQTasks(ctx, sqlplugin.TimerDLQTasksFilter{
+		ShardID:             shardID,
+		VisibilityTimestamp: taskKey.FireTime,
+		TaskID:              taskKey.TaskID,
+	}); err != nil {
+		return serviceerror.NewUnavailable(fmt.Sprintf("DeleteTimerTaskFromDLQ operation failed. Error: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,499 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	gosql "database/sql"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	persistencespb "go.temporal.io/server/api/persistence/v1"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/common/resolver"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func TestTimerDLQ(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// timers sharing a fire time are told apart by task ID
+	keys := []tasks.Key{
+		tasks.NewKey(now, 2),
+		tasks.NewKey(now, 1),
+		tasks.NewKey(now.Add(time.Second), 3),
+	}
+	for _, key := range keys {
+		require.NoError(t, store.PutTimerTaskToDLQ(ctx, shardID, p.InternalHistoryTask{
+			Key:  key,
+			Blob: p.NewDataBlob([]byte("timer"), "test"),
+		}))
+	}
+	// putting the same task again is tolerated
+	require.NoError(t, store.PutTimerTaskToDLQ(ctx, shardID, p.InternalHistoryTask{
+		Key:  keys[0],
+		Blob: p.NewDataBlob([]byte("timer"), "test"),
+	}))
+
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(now, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+		BatchSize:           2,
+	}
+	resp, err := store.GetTimerTasksFromDLQ(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 2)
+	require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+	require.Equal(t, int64(2), resp.Tasks[1].Key.TaskID)
+	require.True(t, now.Equal(resp.Tasks[0].Key.FireTime))
+	require.NotEmpty(t, resp.NextPageToken)
+
+	request.NextPageToken = resp.NextPageToken
+	resp, err = store.GetTimerTasksFromDLQ(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(3), resp.Tasks[0].Key.TaskID)
+
+	require.NoError(t, store.DeleteTimerTaskFromDLQ(ctx, shardID, keys[0]))
+
+	request.NextPageToken = nil
+	request.BatchSize = 10
+	resp, err = store.GetTimerTasksFromDLQ(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 2)
+	require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+	require.Equal(t, int64(3), resp.Tasks[1].Key.TaskID)
+}
+
+func TestFindOrphanedDLQSourceClusters(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	orphanedClusters, err := store.FindOrphanedDLQSourceClusters(ctx, shardID, []string{"cluster-a"})
+	require.NoError(t, err)
+	require.Empty(t, orphanedClusters)
+
+	_, err = db.InsertIntoReplicationDLQTasks(ctx, []sqlplugin.ReplicationDLQTasksRow{
+		{SourceClusterName: "cluster-a", ShardID: shardID, TaskID: 1, Data: []byte("task"), DataEncoding: "test"},
+		{SourceClusterName: "removed-2", ShardID: shardID, TaskID: 2, Data: []byte("task"), DataEncoding: "test"},
+		{SourceClusterName: "removed-1", ShardID: shardID, TaskID: 3, Data: []byte("task"), DataEncoding: "test"},
+		{SourceClusterName: "removed-1", ShardID: shardID, TaskID: 4, Data: []byte("task"), DataEncoding: "test"},
+		{SourceClusterName: "removed-3", ShardID: shardID + 1, TaskID: 1, Data: []byte("task"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	orphanedClusters, err = store.FindOrphanedDLQSourceClusters(ctx, shardID, []string{"cluster-a", "cluster-b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"removed-1", "removed-2"}, orphanedClusters)
+
+	orphanedClusters, err = store.FindOrphanedDLQSourceClusters(ctx, shardID, []string{"cluster-a", "removed-1", "removed-2"})
+	require.NoError(t, err)
+	require.Empty(t, orphanedClusters)
+}
+
+func TestGetReplicationDLQReasonCounts(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	for i, reason := range []string{"resend-failed", "resend-failed", "", "apply-failed", "resend-failed"} {
+		err := store.PutReplicationTaskToDLQ(ctx, &p.PutReplicationTaskToDLQRequest{
+			ShardID:           shardID,
+			SourceClusterName: "cluster-a",
+			TaskInfo:          &persistencespb.ReplicationTaskInfo{TaskId: int64(i + 1)},
+			Reason:            reason,
+		})
+		require.NoError(t, err)
+	}
+	err := store.PutReplicationTaskToDLQ(ctx, &p.PutReplicationTaskToDLQRequest{
+		ShardID:           shardID,
+		SourceClusterName: "cluster-b",
+		TaskInfo:          &persistencespb.ReplicationTaskInfo{TaskId: 1},
+		Reason:            "apply-failed",
+	})
+	require.NoError(t, err)
+
+	reasonCounts, err := store.GetReplicationDLQReasonCounts(ctx, shardID, "cluster-a")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{
+		"resend-failed": 3,
+		"apply-failed":  1,
+		"":              1,
+	}, reasonCounts)
+}
+
+func TestGetReplicationDLQTaskCount(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	for _, task := range []struct {
+		sourceClusterName string
+		taskID            int64
+	}{
+		{"cluster-a", 1}, {"cluster-a", 5}, {"cluster-a", 10}, {"cluster-b", 2},
+	} {
+		err := store.PutReplicationTaskToDLQ(ctx, &p.PutReplicationTaskToDLQRequest{
+			ShardID:           shardID,
+			SourceClusterName: task.sourceClusterName,
+			TaskInfo:          &persistencespb.ReplicationTaskInfo{TaskId: task.taskID},
+		})
+		require.NoError(t, err)
+	}
+
+	count, err := store.GetReplicationDLQTaskCount(ctx, shardID, "cluster-a", nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	// the max task ID is exclusive
+	maxTaskID := int64(10)
+	count, err = store.GetReplicationDLQTaskCount(ctx, shardID, "cluster-a", &maxTaskID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = store.GetReplicationDLQTaskCount(ctx, shardID, "cluster-b", nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	count, err = store.GetReplicationDLQTaskCount(ctx, shardID, "cluster-c", nil)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestTaskDataChecksums_ReplicationDLQ(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskDataChecksums: "error"}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	require.NoError(t, store.PutReplicationTaskToDLQ(ctx, &p.PutReplicationTaskToDLQRequest{
+		ShardID:           shardID,
+		SourceClusterName: "cluster-a",
+		TaskInfo:          &persistencespb.ReplicationTaskInfo{TaskId: 1, WorkflowId: "workflow-id"},
+	}))
+	request := &p.GetReplicationTasksFromDLQRequest{
+		GetHistoryTasksRequest: p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        tasks.CategoryReplication,
+			InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+			ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+			BatchSize:           10,
+		},
+		SourceClusterName: "cluster-a",
+	}
+
+	resp, err := store.GetReplicationTasksFromDLQ(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+
+	rows, err := db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            shardID,
+		SourceClusterName:  "cluster-a",
+		InclusiveMinTaskID: 1,
+		ExclusiveMaxTaskID: 2,
+		PageSize:           1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	_, err = db.DeleteFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksFilter{
+		ShardID:           shardID,
+		SourceClusterName: "cluster-a",
+		TaskID:            1,
+	})
+	require.NoError(t, err)
+	rows[0].Data[len(rows[0].Data)-1] ^= 0xff
+	rows[0].ShardID = shardID
+	rows[0].SourceClusterName = "cluster-a"
+	_, err = db.InsertIntoReplicationDLQTasks(ctx, rows)
+	require.NoError(t, err)
+
+	_, err = store.GetReplicationTasksFromDLQ(ctx, request)
+	require.ErrorAs(t, err, new(*serviceerror.DataLoss))
+}
+
+func TestTaskDataChecksums_TimerDLQ(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskDataChecksums: "error"}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	fireTime := time.Now().UTC().Truncate(time.Millisecond)
+
+	require.NoError(t, store.PutTimerTaskToDLQ(ctx, shardID, p.InternalHistoryTask{
+		Key:  tasks.NewKey(fireTime, 1),
+		Blob: p.NewDataBlob([]byte("timer 1"), "test"),
+	}))
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(fireTime, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(fireTime.Add(time.Second), 0),
+		BatchSize:           10,
+	}
+
+	resp, err := store.GetTimerTasksFromDLQ(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+
+	rows, err := db.RangeSelectFromTimerDLQTasks(ctx, sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: fireTime,
+		ExclusiveMaxVisibilityTimestamp: fireTime.Add(time.Second),
+		PageSize:                        1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.NotNil(t, rows[0].DataChecksum)
+	require.NoError(t, store.DeleteTimerTaskFromDLQ(ctx, shardID, tasks.NewKey(fireTime, 1)))
+	rows[0].Data[0] ^= 0xff
+	rows[0].ShardID = shardID
+	_, err = db.InsertIntoTimerDLQTasks(ctx, rows)
+	require.NoError(t, err)
+
+	_, err = store.GetTimerTasksFromDLQ(ctx, request)
+	require.ErrorAs(t, err, new(*serviceerror.DataLoss))
+}
+
+func insertReplicationDLQTask(t *testing.T, db sqlplugin.DB, shardID int32, sourceClusterName string, taskID int64) {
+	info := &persistencespb.ReplicationTaskInfo{
+		NamespaceId: uuid.New(),
+		WorkflowId:  uuid.New(),
+		RunId:       uuid.New(),
+		TaskId:      taskID,
+	}
+	blob, err := serialization.ReplicationTaskInfoToBlob(info)
+	require.NoError(t, err)
+	_, err = db.InsertIntoReplicationDLQTasks(context.Background(), []sqlplugin.ReplicationDLQTasksRow{{
+		SourceClusterName: sourceClusterName,
+		ShardID:           shardID,
+		TaskID:            taskID,
+		Data:              blob.Data,
+		DataEncoding:      blob.EncodingType.String(),
+	}})
+	require.NoError(t, err)
+}
+
+func TestArchiveReplicationDLQ(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.SQL{
+		PluginName:        "sqlite",
+		DatabaseName:      uuid.New(),
+		ConnectAttributes: map[string]string{"mode": "memory", "cache": "private"},
+	}
+	db, err := sql.NewSQLDB(sqlplugin.DbKindMain, cfg, resolver.NewNoopResolver(), log.NewTestLogger(), metrics.NoopMetricsHandler)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	// shares the in-memory database of db
+	adminDB, err := sql.NewSQLAdminDB(sqlplugin.DbKindMain, cfg, resolver.NewNoopResolver(), log.NewTestLogger(), metrics.NoopMetricsHandler)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = adminDB.Close() })
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	selectDLQTasks := func(shardID int32, sourceCluster string) []sqlplugin.ReplicationDLQTasksRow {
+		rows, err := db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+			ShardID:            shardID,
+			SourceClusterName:  sourceCluster,
+			InclusiveMinTaskID: 0,
+			ExclusiveMaxTaskID: math.MaxInt64,
+			PageSize:           1000,
+		})
+		require.NoError(t, err)
+		return rows
+	}
+
+	for taskID := int64(1); taskID <= 3; taskID++ {
+		insertReplicationDLQTask(t, db, shardID, "cluster-a", taskID)
+	}
+	insertReplicationDLQTask(t, db, shardID, "cluster-b", 2)
+	insertReplicationDLQTask(t, db, shardID+1, "cluster-a", 1)
+	expectedA := selectDLQTasks(shardID, "cluster-a")
+	expectedB := selectDLQTasks(shardID, "cluster-b")
+
+	_, err = store.ArchiveReplicationDLQ(ctx, shardID, "2024-01-01")
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+
+	archived, err := store.ArchiveReplicationDLQ(ctx, shardID, "20240101")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), archived)
+	require.Empty(t, selectDLQTasks(shardID, "cluster-a"))
+	require.Empty(t, selectDLQTasks(shardID, "cluster-b"))
+	// tasks of other shards are not archived
+	require.Len(t, selectDLQTasks(shardID+1, "cluster-a"), 1)
+
+	// the live DLQ being empty, archiving again archives nothing into the existing archive table
+	archived, err = store.ArchiveReplicationDLQ(ctx, shardID, "20240101")
+	require.NoError(t, err)
+	require.Zero(t, archived)
+
+	// the archive holds all tasks: restoring them restores the DLQ
+	require.NoError(t, adminDB.Exec(`INSERT INTO replication_tasks_dlq SELECT * FROM `+
+		sqlplugin.ReplicationDLQArchiveTablePrefix+`20240101`))
+	require.Equal(t, expectedA, selectDLQTasks(shardID, "cluster-a"))
+	require.Equal(t, expectedB, selectDLQTasks(shardID, "cluster-b"))
+}
+
+func TestMoveReplicationTasksToDLQ(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	sourceCluster := "source-cluster"
+	infos := make(map[int64]*persistencespb.ReplicationTaskInfo)
+	for taskID := int64(1); taskID <= 5; taskID++ {
+		infos[taskID] = insertReplicationTask(t, db, shardID, taskID)
+	}
+	// a previous attempt already put task 3 into the DLQ
+	insertReplicationDLQTask(t, db, shardID, sourceCluster, 3)
+
+	err := store.MoveReplicationTasksToDLQ(ctx, shardID, []int64{2, 3, 4, 4}, sourceCluster)
+	require.NoError(t, err)
+
+	rows := selectReplicationTasks(t, db, shardID)
+	require.Len(t, rows, 2)
+	require.Equal(t, int64(1), rows[0].TaskID)
+	require.Equal(t, int64(5), rows[1].TaskID)
+
+	dlqRows, err := db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            shardID,
+		SourceClusterName:  sourceCluster,
+		InclusiveMinTaskID: 0,
+		ExclusiveMaxTaskID: math.MaxInt64,
+		PageSize:           10,
+	})
+	require.NoError(t, err)
+	require.Len(t, dlqRows, 3)
+	for i, row := range dlqRows {
+		require.Equal(t, int64(2+i), row.TaskID)
+		info, err := serialization.ReplicationTaskInfoFromBlob(row.Data, row.DataEncoding)
+		require.NoError(t, err)
+		if row.TaskID != 3 {
+			require.Equal(t, infos[row.TaskID].WorkflowId, info.WorkflowId)
+		}
+	}
+
+	// a missing task rolls back the whole batch
+	err = store.MoveReplicationTasksToDLQ(ctx, shardID, []int64{1, 6}, sourceCluster)
+	var notFound *serviceerror.NotFound
+	require.ErrorAs(t, err, &notFound)
+	require.Len(t, selectReplicationTasks(t, db, shardID), 2)
+	dlqRows, err = db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            shardID,
+		SourceClusterName:  sourceCluster,
+		InclusiveMinTaskID: 0,
+		ExclusiveMaxTaskID: math.MaxInt64,
+		PageSize:           10,
+	})
+	require.NoError(t, err)
+	require.Len(t, dlqRows, 3)
+}
+
+type dlqInsertRecordingDB struct {
+	sqlplugin.DB
+	inserts int
+	err     error
+}
+
+func (db *dlqInsertRecordingDB) InsertIntoReplicationDLQTasks(
+	ctx context.Context,
+	rows []sqlplugin.ReplicationDLQTasksRow,
+) (gosql.Result, error) {
+	db.inserts++
+	if db.err != nil {
+		return nil, db.err
+	}
+	return db.DB.InsertIntoReplicationDLQTasks(ctx, rows)
+}
+
+func TestPutReplicationTasksToDLQ(t *testing.T) {
+	ctx := context.Background()
+	db := &dlqInsertRecordingDB{DB: newTestDB(t)}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	sourceCluster := "source-cluster"
+	newTaskInfo := func(taskID int64) *persistencespb.ReplicationTaskInfo {
+		return &persistencespb.ReplicationTaskInfo{
+			NamespaceId: uuid.New(),
+			WorkflowId:  uuid.New(),
+			RunId:       uuid.New(),
+			TaskId:      taskID,
+		}
+	}
+	selectDLQ := func() []sqlplugin.ReplicationDLQTasksRow {
+		rows, err := db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+			ShardID:            shardID,
+			SourceClusterName:  sourceCluster,
+			InclusiveMinTaskID: 0,
+			ExclusiveMaxTaskID: math.MaxInt64,
+			PageSize:           10,
+		})
+		require.NoError(t, err)
+		return rows
+	}
+
+	err := store.PutReplicationTasksToDLQ(ctx, shardID, sourceCluster, []*persistencespb.ReplicationTaskInfo{newTaskInfo(1), newTaskInfo(2)})
+	require.NoError(t, err)
+	require.Equal(t, 1, db.inserts)
+	require.Len(t, selectDLQ(), 2)
+
+	// task 2 is already in the DLQ, task 3 is new
+	db.inserts = 0
+	task2 := selectDLQ()[1]
+	taskInfos := []*persistencespb.ReplicationTaskInfo{newTaskInfo(2), newTaskInfo(3)}
+	err = store.PutReplicationTasksToDLQ(ctx, shardID, sourceCluster, taskInfos)
+	require.NoError(t, err)
+	rows := selectDLQ()
+	require.Len(t, rows, 3)
+	require.Equal(t, task2.Data, rows[1].Data)
+	info, err := serialization.ReplicationTaskInfoFromBlob(rows[2].Data, rows[2].DataEncoding)
+	require.NoError(t, err)
+	require.Equal(t, taskInfos[1].WorkflowId, info.WorkflowId)
+
+	db.err = errors.New("connection reset")
+	err = store.PutReplicationTasksToDLQ(ctx, shardID, sourceCluster, []*persistencespb.ReplicationTaskInfo{newTaskInfo(4)})
+	var unavailable *serviceerror.Unavailable
+	require.ErrorAs(t, err, &unavailable)
+	require.Len(t, selectDLQ(), 3)
+}
@@ -0,0 +1,191 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/log/tag"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+// DeleteAllTasksForShard deletes all transfer, timer, replication and visibility tasks of a shard in one
+// transaction, e.g. when the shard is decommissioned or recovered from corruption, and returns the number of
+// tasks deleted by category. The shard row is write locked with rangeID, so the delete fails with
+// ShardOwnershipLostError if the shard was acquired since, and concurrent AddHistoryTasks calls, which read
+// lock the shard row, wait for it. Tasks of the other categories are not deleted.
+func (m *sqlExecutionStore) DeleteAllTasksForShard(
+	ctx context.Context,
+	shardID int32,
+	rangeID int64,
+) (map[tasks.Category]int64, error) {
+	deletes := []shardTaskDelete{
+		{tasks.CategoryTransfer, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: 0,
+				ExclusiveMaxTaskID: math.MaxInt64,
+			})
+		}},
+		{tasks.CategoryTimer, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+				ShardID:                         shardID,
+				InclusiveMinVisibilityTimestamp: tasks.MinimumKey.FireTime,
+				ExclusiveMaxVisibilityTimestamp: tasks.MaximumKey.FireTime,
+			})
+		}},
+		{tasks.CategoryReplication, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: 0,
+				ExclusiveMaxTaskID: math.MaxInt64,
+			})
+		}},
+		{tasks.CategoryVisibility, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: 0,
+				ExclusiveMaxTaskID: math.MaxInt64,
+			})
+		}},
+	}
+
+	return m.deleteShardTasks(ctx, "DeleteAllTasksForShard", shardID, rangeID, deletes)
+}
+
+// TruncateShardTasksAbove is a recovery operation that discards every task of a shard above a fence, e.g. to
+// re-establish a clean state after two hosts owned the shard at once. Transfer, replication and visibility tasks
+// with task IDs above taskIDFence and timer tasks with visibility timestamps above timestampFence are deleted in
+// one transaction, locking the shard row with rangeID as DeleteAllTasksForShard does, and the number of tasks
+// deleted is returned by category. Visibility timestamps are persisted with ScheduledTaskMinPrecision, so timer
+// tasks in the same millisecond as timestampFence are kept. Tasks of the other categories are not deleted.
+func (m *sqlExecutionStore) TruncateShardTasksAbove(
+	ctx context.Context,
+	shardID int32,
+	rangeID int64,
+	taskIDFence int64,
+	timestampFence time.Time,
+) (map[tasks.Category]int64, error) {
+	if taskIDFence < 0 || taskIDFence == math.MaxInt64 {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf(
+			"TruncateShardTasksAbove failed. Invalid task ID fence: %v", taskIDFence,
+		))
+	}
+	inclusiveMinTaskID := taskIDFence + 1
+	inclusiveMinVisibilityTimestamp := timestampFence.Truncate(p.ScheduledTaskMinPrecision).Add(p.ScheduledTaskMinPrecision)
+	deletes := []shardTaskDelete{
+		{tasks.CategoryTransfer, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: inclusiveMinTaskID,
+				ExclusiveMaxTaskID: math.MaxInt64,
+			})
+		}},
+		{tasks.CategoryTimer, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+				ShardID:                         shardID,
+				InclusiveMinVisibilityTimestamp: inclusiveMinVisibilityTimestamp,
+				ExclusiveMaxVisibilityTimestamp: tasks.MaximumKey.FireTime,
+			})
+		}},
+		{tasks.CategoryReplication, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: inclusiveMinTaskID,
+				ExclusiveMaxTaskID: math.MaxInt64,
+			})
+		}},
+		{tasks.CategoryVisibility, func(tx sqlplugin.Tx) (sql.Result, error) {
+			return tx.RangeDeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+				ShardID:            shardID,
+				InclusiveMinTaskID: inclusiveMinTaskID,
+				ExclusiveMaxTaskID: math.MaxInt64,
+			})
+		}},
+	}
+	deletedByCategory, err := m.deleteShardTasks(ctx, "TruncateShardTasksAbove", shardID, rangeID, deletes)
+	if err != nil {
+		return nil, err
+	}
+	m.logger.Warn("Truncated shard tasks above fence",
+		tag.ShardID(shardID),
+		tag.TaskID(taskIDFence),
+		tag.Timestamp(timestampFence),
+	)
+	return deletedByCategory, nil
+}
+
+// shardTaskDelete deletes tasks of one category of a shard.
+type shardTaskDelete struct {
+	category tasks.Category
+	deleteFn func(tx sqlplugin.Tx) (sql.Result, error)
+}
+
+// deleteShardTasks runs deletes in one transaction with the shard row write locked with rangeID, and returns the
+// number of tasks deleted by category.
+func (m *sqlExecutionStore) deleteShardTasks(
+	ctx context.Context,
+	operation string,
+	shardID int32,
+	rangeID int64,
+	deletes []shardTaskDelete,
+) (map[tasks.Category]int64, error) {
+	// invalidated once the deletes are committed, so that no read can keep the deleted tasks
+	defer m.readAhead.invalidateShard(shardID)
+	var deletedByCategory map[tasks.Category]int64
+	err := m.txExecute(ctx, operation, func(tx sqlplugin.Tx) error {
+		if err := lockShard(ctx, tx, shardID, rangeID); err != nil {
+			return err
+		}
+		deletedByCategory = make(map[tasks.Category]int64, len(deletes))
+		for _, d := range deletes {
+			result, err := d.deleteFn(tx)
+			if err != nil {
+				return serviceerror.NewUnavailable(fmt.Sprintf(
+					"%v operation failed. Category: %v. Error: %v", operation, d.category.Name(), err,
+				))
+			}
+			rowsDeleted, err := result.RowsAffected()
+			if err != nil {
+				return serviceerror.NewUnavailable(fmt.Sprintf(
+					"%v operation failed. Category: %v. Error: %v", operation, d.category.Name(), err,
+				))
+			}
+			deletedByCategory[d.category] = rowsDeleted
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletedByCategory, nil
+}
@@ -0,0 +1,207 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func TestDeleteAllTasksForShard(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(3)
+	insertShard(t, db, shardID, rangeID)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, id := range []int32{shardID, shardID + 1} {
+		_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+			{ShardID: id, TaskID: 1, Data: []byte("transfer"), DataEncoding: "test"},
+			{ShardID: id, TaskID: 2, Data: []byte("transfer"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+		_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+			{ShardID: id, VisibilityTimestamp: now, TaskID: 1, Data: []byte("timer"), DataEncoding: "test"},
+			{ShardID: id, VisibilityTimestamp: now.Add(time.Hour), TaskID: 2, Data: []byte("timer"), DataEncoding: "test"},
+			{ShardID: id, VisibilityTimestamp: now.Add(-time.Hour), TaskID: 3, Data: []byte("timer"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+		insertReplicationTask(t, db, id, 1)
+		_, err = db.InsertIntoVisibilityTasks(ctx, []sqlplugin.VisibilityTasksRow{
+			{ShardID: id, TaskID: 1, Data: []byte("visibility"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := store.DeleteAllTasksForShard(ctx, shardID, rangeID-1)
+	require.ErrorAs(t, err, new(*p.ShardOwnershipLostError))
+	count, err := store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	deleted, err := store.DeleteAllTasksForShard(ctx, shardID, rangeID)
+	require.NoError(t, err)
+	require.Equal(t, map[tasks.Category]int64{
+		tasks.CategoryTransfer:    2,
+		tasks.CategoryTimer:       3,
+		tasks.CategoryReplication: 1,
+		tasks.CategoryVisibility:  1,
+	}, deleted)
+	require.Empty(t, selectReplicationTasks(t, db, shardID))
+	count, err = store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// tasks of other shards are kept
+	count, err = store.GetTransferTaskCount(ctx, shardID+1, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+	require.Len(t, selectReplicationTasks(t, db, shardID+1), 1)
+
+	deleted, err = store.DeleteAllTasksForShard(ctx, shardID, rangeID)
+	require.NoError(t, err)
+	require.Equal(t, map[tasks.Category]int64{
+		tasks.CategoryTransfer:    0,
+		tasks.CategoryTimer:       0,
+		tasks.CategoryReplication: 0,
+		tasks.CategoryVisibility:  0,
+	}, deleted)
+}
+
+func TestTruncateShardTasksAbove(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(3)
+	insertShard(t, db, shardID, rangeID)
+	fence := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, id := range []int32{shardID, shardID + 1} {
+		_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+			{ShardID: id, TaskID: 9, Data: []byte("transfer"), DataEncoding: "test"},
+			{ShardID: id, TaskID: 10, Data: []byte("transfer"), DataEncoding: "test"},
+			{ShardID: id, TaskID: 11, Data: []byte("transfer"), DataEncoding: "test"},
+			{ShardID: id, TaskID: 12, Data: []byte("transfer"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+		_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+			{ShardID: id, VisibilityTimestamp: fence.Add(-time.Hour), TaskID: 20, Data: []byte("timer"), DataEncoding: "test"},
+			{ShardID: id, VisibilityTimestamp: fence, TaskID: 21, Data: []byte("timer"), DataEncoding: "test"},
+			{ShardID: id, VisibilityTimestamp: fence.Add(time.Millisecond), TaskID: 1, Data: []byte("timer"), DataEncoding: "test"},
+			{ShardID: id, VisibilityTimestamp: fence.Add(time.Hour), TaskID: 2, Data: []byte("timer"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+		for _, taskID := range []int64{10, 11} {
+			insertReplicationTask(t, db, id, taskID)
+		}
+		_, err = db.InsertIntoVisibilityTasks(ctx, []sqlplugin.VisibilityTasksRow{
+			{ShardID: id, TaskID: 1, Data: []byte("visibility"), DataEncoding: "test"},
+			{ShardID: id, TaskID: 100, Data: []byte("visibility"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := store.TruncateShardTasksAbove(ctx, shardID, rangeID, -1, fence)
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+	_, err = store.TruncateShardTasksAbove(ctx, shardID, rangeID-1, 10, fence)
+	require.ErrorAs(t, err, new(*p.ShardOwnershipLostError))
+	count, err := store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), count)
+
+	deleted, err := store.TruncateShardTasksAbove(ctx, shardID, rangeID, 10, fence)
+	require.NoError(t, err)
+	require.Equal(t, map[tasks.Category]int64{
+		tasks.CategoryTransfer:    2,
+		tasks.CategoryTimer:       2,
+		tasks.CategoryReplication: 1,
+		tasks.CategoryVisibility:  1,
+	}, deleted)
+
+	// only tasks above the fences are deleted, and only for the shard
+	for _, testCase := range []struct {
+		shardID          int32
+		transferTasks    int64
+		timerTaskIDs     []int64
+		replicationTasks []int64
+		visibilityTasks  []int64
+	}{
+		{shardID, 2, []int64{20, 21}, []int64{10}, []int64{1}},
+		{shardID + 1, 4, []int64{20, 21, 1, 2}, []int64{10, 11}, []int64{1, 100}},
+	} {
+		count, err := store.GetTransferTaskCount(ctx, testCase.shardID, 0, math.MaxInt64)
+		require.NoError(t, err)
+		require.Equal(t, testCase.transferTasks, count)
+		require.Equal(t, testCase.timerTaskIDs, selectTimerTaskIDs(t, db, testCase.shardID))
+		var replicationTaskIDs []int64
+		for _, row := range selectReplicationTasks(t, db, testCase.shardID) {
+			replicationTaskIDs = append(replicationTaskIDs, row.TaskID)
+		}
+		require.Equal(t, testCase.replicationTasks, replicationTaskIDs)
+		visibilityRows, err := db.RangeSelectFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+			ShardID:            testCase.shardID,
+			InclusiveMinTaskID: 0,
+			ExclusiveMaxTaskID: math.MaxInt64,
+			PageSize:           100,
+		})
+		require.NoError(t, err)
+		var visibilityTaskIDs []int64
+		for _, row := range visibilityRows {
+			visibilityTaskIDs = append(visibilityTaskIDs, row.TaskID)
+		}
+		require.Equal(t, testCase.visibilityTasks, visibilityTaskIDs)
+	}
+}
+
+func selectTimerTaskIDs(t *testing.T, db sqlplugin.DB, shardID int32) []int64 {
+	rows, err := db.RangeSelectFromTimerTasks(context.Background(), sqlplugin.TimerTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: tasks.MinimumKey.FireTime,
+		ExclusiveMaxVisibilityTimestamp: tasks.MaximumKey.FireTime,
+		PageSize:                        100,
+	})
+	require.NoError(t, err)
+	var taskIDs []int64
+	for _, row := range rows {
+		taskIDs = append(taskIDs, row.TaskID)
+	}
+	return taskIDs
+}
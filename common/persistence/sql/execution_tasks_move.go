@@ -0,0 +1,233 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+
+	"go.temporal.io/api/serviceerror"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+// MoveTasksBetweenShards moves the immediate tasks of the given category with the given task IDs from srcShardID
+// to dstShardID. Moved tasks get new task IDs from allocateDstTaskIDs, which must return count increasing task IDs
+// allocated by the owner of dstShardID, so that they are above the destination's ack level and don't collide
+// with tasks it adds later. Tasks keep their relative order. The destination task IDs are returned in the order
+// of taskIDs.
+// The tasks are read, inserted under dstShardID and deleted from srcShardID in a single transaction that read
+// locks both shards with their range IDs, so either all of them are moved or none is. A ShardOwnershipLostError
+// is returned if either range ID is stale, a ConditionFailedError if one of the destination task IDs is already
+// used, and a NotFound error if one of the tasks does not exist in srcShardID.
+// Both shards must be stored in the database of this store. Moving tasks to or from a shard stored in another
+// database can't be done in a single transaction and is not supported.
+func (m *sqlExecutionStore) MoveTasksBetweenShards(
+	ctx context.Context,
+	srcShardID int32,
+	srcRangeID int64,
+	dstShardID int32,
+	dstRangeID int64,
+	taskIDs []int64,
+	category tasks.Category,
+	allocateDstTaskIDs func(count int) ([]int64, error),
+) ([]int64, error) {
+	if category.Type() != tasks.CategoryTypeImmediate {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf("MoveTasksBetweenShards: unsupported task category: %v", category))
+	}
+	if srcShardID == dstShardID {
+		return nil, serviceerror.NewInvalidArgument(fmt.Sprintf("MoveTasksBetweenShards: source and destination shard are both %v", srcShardID))
+	}
+	for _, shardID := range []int32{srcShardID, dstShardID} {
+		if _, err := m.Db.SelectFromShards(ctx, sqlplugin.ShardsFilter{ShardID: shardID}); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, serviceerror.NewUnimplemented(fmt.Sprintf(
+					"MoveTasksBetweenShards: shard %v is not stored in this database, moving tasks across databases is not supported",
+					shardID,
+				))
+			}
+			return nil, serviceerror.NewUnavailable(fmt.Sprintf("MoveTasksBetweenShards operation failed. Failed to get shard %v. Error: %v", shardID, err))
+		}
+	}
+
+	allocatedTaskIDs, err := allocateDstTaskIDs(len(taskIDs))
+	if err != nil {
+		return nil, err
+	}
+	if len(allocatedTaskIDs) != len(taskIDs) {
+		return nil, serviceerror.NewInternal(fmt.Sprintf(
+			"MoveTasksBetweenShards: allocated %v task IDs for %v tasks", len(allocatedTaskIDs), len(taskIDs),
+		))
+	}
+	// the smallest source task ID gets the smallest destination task ID and so on
+	srcTaskIDs := slices.Sorted(slices.Values(taskIDs))
+	slices.Sort(allocatedTaskIDs)
+	dstTaskIDs := make([]int64, len(taskIDs))
+	for i, taskID := range taskIDs {
+		dstTaskIDs[i] = allocatedTaskIDs[sort.Search(len(srcTaskIDs), func(j int) bool { return srcTaskIDs[j] >= taskID })]
+	}
+
+	// the shards are locked in shard ID order, so that concurrent moves between the same shards can't deadlock
+	rangeIDs := map[int32]int64{srcShardID: srcRangeID, dstShardID: dstRangeID}
+	shardIDs := []int32{min(srcShardID, dstShardID), max(srcShardID, dstShardID)}
+	// invalidated once the move is committed, as moved tasks may be anywhere in the read range of either shard
+	defer m.readAhead.invalidate(srcShardID, category)
+	defer m.readAhead.invalidate(dstShardID, category)
+	err = m.txExecute(ctx, "MoveTasksBetweenShards", func(tx sqlplugin.Tx) error {
+		for _, shardID := range shardIDs {
+			if err := readLockShard(ctx, tx, shardID, rangeIDs[shardID]); err != nil {
+				return err
+			}
+		}
+		for i, taskID := range taskIDs {
+			if err := m.moveImmediateTask(ctx, tx, category.ID(), srcShardID, taskID, dstShardID, dstTaskIDs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dstTaskIDs, nil
+}
+
+func (m *sqlExecutionStore) moveImmediateTask(
+	ctx context.Context,
+	tx sqlplugin.Tx,
+	categoryID int,
+	srcShardID int32,
+	taskID int64,
+	dstShardID int32,
+	dstTaskID int64,
+) error {
+	var rowCount int
+	var insertErr error
+	var deleteResult sql.Result
+	var deleteErr error
+	// These task categories exist before the general history_immediate_tasks table is created,
+	// so they have their own tables.
+	switch categoryID {
+	case tasks.CategoryIDTransfer:
+		rows, err := tx.RangeSelectFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+			ShardID:            srcShardID,
+			InclusiveMinTaskID: taskID,
+			ExclusiveMaxTaskID: taskID + 1,
+			PageSize:           1,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if rowCount = len(rows); rowCount == 1 {
+			rows[0].ShardID = dstShardID
+			rows[0].TaskID = dstTaskID
+			if _, insertErr = tx.InsertIntoTransferTasks(ctx, rows); insertErr == nil {
+				deleteResult, deleteErr = tx.DeleteFromTransferTasks(ctx, sqlplugin.TransferTasksFilter{ShardID: srcShardID, TaskID: taskID})
+			}
+		}
+	case tasks.CategoryIDVisibility:
+		rows, err := tx.RangeSelectFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksRangeFilter{
+			ShardID:            srcShardID,
+			InclusiveMinTaskID: taskID,
+			ExclusiveMaxTaskID: taskID + 1,
+			PageSize:           1,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if rowCount = len(rows); rowCount == 1 {
+			rows[0].ShardID = dstShardID
+			rows[0].TaskID = dstTaskID
+			if _, insertErr = tx.InsertIntoVisibilityTasks(ctx, rows); insertErr == nil {
+				deleteResult, deleteErr = tx.DeleteFromVisibilityTasks(ctx, sqlplugin.VisibilityTasksFilter{ShardID: srcShardID, TaskID: taskID})
+			}
+		}
+	case tasks.CategoryIDReplication:
+		rows, err := tx.RangeSelectFromReplicationTasks(ctx, sqlplugin.ReplicationTasksRangeFilter{
+			ShardID:            srcShardID,
+			InclusiveMinTaskID: taskID,
+			ExclusiveMaxTaskID: taskID + 1,
+			PageSize:           1,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if rowCount = len(rows); rowCount == 1 {
+			rows[0].ShardID = dstShardID
+			rows[0].TaskID = dstTaskID
+			if _, insertErr = tx.InsertIntoReplicationTasks(ctx, rows); insertErr == nil {
+				deleteResult, deleteErr = tx.DeleteFromReplicationTasks(ctx, sqlplugin.ReplicationTasksFilter{ShardID: srcShardID, TaskID: taskID})
+			}
+		}
+	default:
+		rows, err := tx.RangeSelectFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksRangeFilter{
+			ShardID:            srcShardID,
+			CategoryID:         int32(categoryID),
+			InclusiveMinTaskID: taskID,
+			ExclusiveMaxTaskID: taskID + 1,
+			PageSize:           1,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if rowCount = len(rows); rowCount == 1 {
+			rows[0].ShardID = dstShardID
+			rows[0].TaskID = dstTaskID
+			if _, insertErr = tx.InsertIntoHistoryImmediateTasks(ctx, rows); insertErr == nil {
+				deleteResult, deleteErr = tx.DeleteFromHistoryImmediateTasks(ctx, sqlplugin.HistoryImmediateTasksFilter{
+					ShardID:    srcShardID,
+					CategoryID: int32(categoryID),
+					TaskID:     taskID,
+				})
+			}
+		}
+	}
+
+	switch {
+	case rowCount == 0:
+		return serviceerror.NewNotFound(fmt.Sprintf("MoveTasksBetweenShards: task %v not found in shard %v", taskID, srcShardID))
+	case insertErr != nil && m.Db.IsDupEntryError(insertErr):
+		return &p.ConditionFailedError{
+			Msg: fmt.Sprintf("MoveTasksBetweenShards: task %v already exists in shard %v", dstTaskID, dstShardID),
+		}
+	case insertErr != nil:
+		return insertErr
+	case deleteErr != nil:
+		return deleteErr
+	}
+	// the task may have been completed since it was read, and must not be run again by the destination
+	rowsDeleted, err := deleteResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsDeleted != 1 {
+		return serviceerror.NewNotFound(fmt.Sprintf("MoveTasksBetweenShards: task %v not found in shard %v", taskID, srcShardID))
+	}
+	return nil
+}
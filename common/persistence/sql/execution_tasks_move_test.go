@@ -0,0 +1,124 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func TestMoveTasksBetweenShards(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	srcShardID := int32(1)
+	dstShardID := int32(2)
+	insertShard(t, db, srcShardID, 1)
+	insertShard(t, db, dstShardID, 1)
+
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: srcShardID, TaskID: 1, Data: []byte("task 1"), DataEncoding: "test"},
+		{ShardID: srcShardID, TaskID: 2, Data: []byte("task 2"), DataEncoding: "test"},
+		{ShardID: srcShardID, TaskID: 3, Data: []byte("task 3"), DataEncoding: "test"},
+		{ShardID: srcShardID, TaskID: 4, Data: []byte("task 4"), DataEncoding: "test"},
+		{ShardID: srcShardID, TaskID: 5, Data: []byte("task 5"), DataEncoding: "test"},
+		{ShardID: dstShardID, TaskID: 4, Data: []byte("dst task 4"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	transferTasks := func(shardID int32) map[int64]string {
+		rows, err := db.RangeSelectFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+			ShardID:            shardID,
+			InclusiveMinTaskID: 0,
+			ExclusiveMaxTaskID: math.MaxInt64,
+			PageSize:           100,
+		})
+		require.NoError(t, err)
+		tasksByID := make(map[int64]string, len(rows))
+		for _, row := range rows {
+			tasksByID[row.TaskID] = string(row.Data)
+		}
+		return tasksByID
+	}
+
+	allocateTaskIDs := func(taskIDs ...int64) func(int) ([]int64, error) {
+		return func(count int) ([]int64, error) {
+			require.Len(t, taskIDs, count)
+			return taskIDs, nil
+		}
+	}
+
+	// moved tasks get the destination task IDs in the order of their source task IDs
+	dstTaskIDs, err := store.MoveTasksBetweenShards(ctx, srcShardID, 1, dstShardID, 1, []int64{3, 1}, tasks.CategoryTransfer, allocateTaskIDs(100, 101))
+	require.NoError(t, err)
+	require.Equal(t, []int64{101, 100}, dstTaskIDs)
+	require.Equal(t, map[int64]string{2: "task 2", 4: "task 4", 5: "task 5"}, transferTasks(srcShardID))
+	require.Equal(t, map[int64]string{4: "dst task 4", 100: "task 1", 101: "task 3"}, transferTasks(dstShardID))
+
+	// task 2 collides with a task of the destination shard, so task 5 must not be moved either
+	_, err = store.MoveTasksBetweenShards(ctx, srcShardID, 1, dstShardID, 1, []int64{5, 2}, tasks.CategoryTransfer, allocateTaskIDs(4, 102))
+	var conditionFailedErr *p.ConditionFailedError
+	require.ErrorAs(t, err, &conditionFailedErr)
+	require.Equal(t, map[int64]string{2: "task 2", 4: "task 4", 5: "task 5"}, transferTasks(srcShardID))
+	require.Equal(t, map[int64]string{4: "dst task 4", 100: "task 1", 101: "task 3"}, transferTasks(dstShardID))
+
+	_, err = store.MoveTasksBetweenShards(ctx, srcShardID, 1, dstShardID, 1, []int64{2, 6}, tasks.CategoryTransfer, allocateTaskIDs(102, 103))
+	var notFoundErr *serviceerror.NotFound
+	require.ErrorAs(t, err, &notFoundErr)
+	require.Equal(t, map[int64]string{2: "task 2", 4: "task 4", 5: "task 5"}, transferTasks(srcShardID))
+
+	// both shards must still be owned by the caller
+	for _, rangeIDs := range [][2]int64{{2, 1}, {1, 2}} {
+		_, err = store.MoveTasksBetweenShards(ctx, srcShardID, rangeIDs[0], dstShardID, rangeIDs[1], []int64{2}, tasks.CategoryTransfer, allocateTaskIDs(104))
+		var shardOwnershipLostErr *p.ShardOwnershipLostError
+		require.ErrorAs(t, err, &shardOwnershipLostErr)
+		require.Equal(t, map[int64]string{2: "task 2", 4: "task 4", 5: "task 5"}, transferTasks(srcShardID))
+		require.Equal(t, map[int64]string{4: "dst task 4", 100: "task 1", 101: "task 3"}, transferTasks(dstShardID))
+	}
+
+	// allocation failures are returned as is
+	allocationErr := serviceerror.NewUnavailable("allocation failed")
+	_, err = store.MoveTasksBetweenShards(ctx, srcShardID, 1, dstShardID, 1, []int64{2}, tasks.CategoryTransfer, func(int) ([]int64, error) {
+		return nil, allocationErr
+	})
+	require.Equal(t, allocationErr, err)
+
+	// shard 3 is not stored in this database
+	_, err = store.MoveTasksBetweenShards(ctx, srcShardID, 1, 3, 1, []int64{2}, tasks.CategoryTransfer, allocateTaskIDs(105))
+	var unimplementedErr *serviceerror.Unimplemented
+	require.ErrorAs(t, err, &unimplementedErr)
+	require.Equal(t, map[int64]string{2: "task 2", 4: "task 4", 5: "task 5"}, transferTasks(srcShardID))
+}
@@ -0,0 +1,2056 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	gosql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/require"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	enumsspb "go.temporal.io/server/api/enums/v1"
+	persistencespb "go.temporal.io/server/api/persistence/v1"
+	"go.temporal.io/server/common/clock"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/metrics/metricstest"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
+	"go.temporal.io/server/common/resolver"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func newTestDB(t *testing.T) sqlplugin.DB {
+	cfg := &config.SQL{
+		PluginName:        "sqlite",
+		DatabaseName:      uuid.New(),
+		ConnectAttributes: map[string]string{"mode": "memory", "cache": "private"},
+	}
+	db, err := sql.NewSQLDB(sqlplugin.DbKindMain, cfg, resolver.NewNoopResolver(), log.NewTestLogger(), metrics.NoopMetricsHandler)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func insertReplicationTask(t *testing.T, db sqlplugin.DB, shardID int32, taskID int64) *persistencespb.ReplicationTaskInfo {
+	info := &persistencespb.ReplicationTaskInfo{
+		NamespaceId: uuid.New(),
+		WorkflowId:  uuid.New(),
+		RunId:       uuid.New(),
+		TaskId:      taskID,
+	}
+	blob, err := serialization.ReplicationTaskInfoToBlob(info)
+	require.NoError(t, err)
+	_, err = db.InsertIntoReplicationTasks(context.Background(), []sqlplugin.ReplicationTasksRow{{
+		ShardID:      shardID,
+		TaskID:       taskID,
+		Data:         blob.Data,
+		DataEncoding: blob.EncodingType.String(),
+	}})
+	require.NoError(t, err)
+	return info
+}
+
+func selectReplicationTasks(t *testing.T, db sqlplugin.DB, shardID int32) []sqlplugin.ReplicationTasksRow {
+	rows, err := db.RangeSelectFromReplicationTasks(context.Background(), sqlplugin.ReplicationTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: 0,
+		ExclusiveMaxTaskID: 1 << 62,
+		PageSize:           1000,
+	})
+	require.NoError(t, err)
+	return rows
+}
+
+func selectReplicationTaskIDs(t *testing.T, db sqlplugin.DB, shardID int32) []int64 {
+	var taskIDs []int64
+	for _, row := range selectReplicationTasks(t, db, shardID) {
+		taskIDs = append(taskIDs, row.TaskID)
+	}
+	return taskIDs
+}
+
+type readReplicaDB struct {
+	sqlplugin.DB
+	replica sqlplugin.DB
+}
+
+func (db *readReplicaDB) ReadOnly() sqlplugin.DB {
+	return db.replica
+}
+
+func TestReadReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	primary := newTestDB(t)
+	replica := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(
+		&readReplicaDB{DB: primary, replica: replica},
+		&config.SQL{TransferTaskReadAheadSize: 10},
+		log.NewTestLogger(),
+		metrics.NoopMetricsHandler,
+	)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, primary, shardID, rangeID)
+
+	// the replica lags behind the primary
+	categories := []tasks.Category{tasks.CategoryTransfer, tasks.CategoryVisibility, tasks.CategoryReplication}
+	addRequest := &p.InternalAddHistoryTasksRequest{
+		ShardID: shardID,
+		RangeID: rangeID,
+		Tasks:   make(map[tasks.Category][]p.InternalHistoryTask),
+	}
+	for _, category := range categories {
+		addRequest.Tasks[category] = []p.InternalHistoryTask{
+			{Key: tasks.NewImmediateKey(1), Blob: p.NewDataBlob([]byte("task"), "test")},
+			{Key: tasks.NewImmediateKey(2), Blob: p.NewDataBlob([]byte("task"), "test")},
+		}
+	}
+	require.NoError(t, store.AddHistoryTasks(ctx, addRequest))
+	_, err := replica.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("task"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = replica.InsertIntoVisibilityTasks(ctx, []sqlplugin.VisibilityTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("task"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = replica.InsertIntoReplicationTasks(ctx, []sqlplugin.ReplicationTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("task"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	getHistoryTasks := func(category tasks.Category, readTier p.ReadTier, minTaskID int64, batchSize int) []p.InternalHistoryTask {
+		resp, err := store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        category,
+			InclusiveMinTaskKey: tasks.NewImmediateKey(minTaskID),
+			ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+			BatchSize:           batchSize,
+			ReadTier:            readTier,
+		})
+		require.NoError(t, err)
+		return resp.Tasks
+	}
+	for _, category := range categories {
+		require.Len(t, getHistoryTasks(category, p.ReadTierLagTolerant, 0, 10), 1, category.Name())
+		for _, readTier := range []p.ReadTier{p.ReadTierDefault, p.ReadTierLatencySensitive, p.ReadTierConsistent} {
+			require.Len(t, getHistoryTasks(category, readTier, 0, 10), 2, category.Name())
+		}
+	}
+
+	// the transfer tasks read ahead by a queue read are the primary's, not the replica's
+	require.Len(t, getHistoryTasks(tasks.CategoryTransfer, p.ReadTierLagTolerant, 0, 1), 1)
+	require.Len(t, getHistoryTasks(tasks.CategoryTransfer, p.ReadTierDefault, 0, 1), 1)
+	require.Len(t, getHistoryTasks(tasks.CategoryTransfer, p.ReadTierLagTolerant, 2, 1), 0)
+	require.Len(t, getHistoryTasks(tasks.CategoryTransfer, p.ReadTierDefault, 2, 1), 1)
+
+	// task statistics may be served by the replica
+	count, err := store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestGetAndCompleteTransferTasks(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("task 1"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 2, Data: []byte("task 2"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 3, Data: []byte("task 3"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 4, Data: []byte("task 4"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           3,
+	}
+
+	resp, complete, err := store.GetAndCompleteTransferTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 3)
+
+	// task 4 is not part of the page and must not be completed
+	err = complete(ctx, []int64{1, 4})
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+
+	require.NoError(t, complete(ctx, []int64{1, 3}))
+
+	resp, _, err = store.GetAndCompleteTransferTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 2)
+	require.Equal(t, int64(2), resp.Tasks[0].Key.TaskID)
+	require.Equal(t, int64(4), resp.Tasks[1].Key.TaskID)
+
+	_, _, err = store.GetAndCompleteTransferTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryVisibility,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           3,
+	})
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+}
+
+func TestGetTransferTasksMultiShard(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	otherShardID := shardID + 1
+	emptyShardID := shardID + 2
+
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("transfer 1"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 2, Data: []byte("transfer 2"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 3, Data: []byte("transfer 3"), DataEncoding: "test"},
+		{ShardID: otherShardID, TaskID: 2, Data: []byte("transfer 2"), DataEncoding: "test"},
+		// outside of the requested range
+		{ShardID: otherShardID, TaskID: 10, Data: []byte("transfer 10"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	respByShard, err := store.GetTransferTasksMultiShard(ctx, nil, 1, 10, 2)
+	require.NoError(t, err)
+	require.Empty(t, respByShard)
+
+	respByShard, err = store.GetTransferTasksMultiShard(ctx, []int32{shardID, otherShardID, emptyShardID}, 1, 10, 2)
+	require.NoError(t, err)
+	require.Len(t, respByShard, 2)
+	require.Len(t, respByShard[shardID].Tasks, 2)
+	require.Equal(t, int64(1), respByShard[shardID].Tasks[0].Key.TaskID)
+	require.Equal(t, int64(2), respByShard[shardID].Tasks[1].Key.TaskID)
+	require.NotEmpty(t, respByShard[shardID].NextPageToken)
+	require.Len(t, respByShard[otherShardID].Tasks, 1)
+	require.Equal(t, []byte("transfer 2"), respByShard[otherShardID].Tasks[0].Blob.Data)
+	require.Empty(t, respByShard[otherShardID].NextPageToken)
+
+	// the page token continues the shard's reads
+	resp, err := store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           2,
+		NextPageToken:       respByShard[shardID].NextPageToken,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(3), resp.Tasks[0].Key.TaskID)
+}
+
+func TestGetHistoryTasks_CorruptPageToken(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("task 1"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 2, Data: []byte("task 2"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now, TaskID: 1, Data: []byte("timer 1"), DataEncoding: "test"},
+		{ShardID: shardID, VisibilityTimestamp: now.Add(time.Second), TaskID: 2, Data: []byte("timer 2"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	garbageToken := []byte("not a page token")
+	transferRequest := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           10,
+		NextPageToken:       garbageToken,
+	}
+	timerRequest := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(now, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+		BatchSize:           10,
+		NextPageToken:       garbageToken,
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		_, err := store.GetHistoryTasks(ctx, transferRequest)
+		require.Error(t, err)
+		_, err = store.GetHistoryTasks(ctx, timerRequest)
+		require.Error(t, err)
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		metricsHandler := metricstest.NewCaptureHandler()
+		capture := metricsHandler.StartCapture()
+		defer metricsHandler.StopCapture(capture)
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{LenientPageTokens: true}, log.NewTestLogger(), metricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, transferRequest)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 2)
+		require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+
+		resp, err = store.GetHistoryTasks(ctx, timerRequest)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 2)
+		require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+
+		require.Len(t, capture.Snapshot()[metrics.PersistenceCorruptPageTokens.Name()], 2)
+	})
+}
+
+func TestGetHistoryTasks_BacklogPressure(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	defer metricsHandler.StopCapture(capture)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{BacklogPressureFullPageThreshold: 2}, log.NewTestLogger(), metricsHandler)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 10; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+
+	read := func(shardID int32, batchSize int) {
+		_, err := store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        tasks.CategoryReplication,
+			InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+			ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+			BatchSize:           batchSize,
+		})
+		require.NoError(t, err)
+	}
+	backlogPressureReports := func() int {
+		return len(capture.Snapshot()[metrics.PersistenceTaskBacklogPressure.Name()])
+	}
+
+	// full pages up to the threshold are not reported
+	read(shardID, 5)
+	read(shardID, 5)
+	require.Zero(t, backlogPressureReports())
+	// a shard without tasks never returns full pages
+	read(shardID+1, 5)
+	read(shardID+1, 5)
+	read(shardID+1, 5)
+	require.Zero(t, backlogPressureReports())
+
+	read(shardID, 5)
+	require.Equal(t, 1, backlogPressureReports())
+	read(shardID, 5)
+	require.Equal(t, 2, backlogPressureReports())
+
+	// a page that isn't full resets the count
+	read(shardID, 20)
+	read(shardID, 5)
+	read(shardID, 5)
+	require.Equal(t, 2, backlogPressureReports())
+	read(shardID, 5)
+	require.Equal(t, 3, backlogPressureReports())
+
+	// reads without a batch size and internal full scans of the shard don't count
+	read(shardID, 20)
+	read(shardID, 0)
+	read(shardID, 0)
+	read(shardID, 0)
+	_, err := store.GetShardTaskCountsByNamespace(ctx, shardID, 5)
+	require.NoError(t, err)
+	read(shardID, 5)
+	read(shardID, 5)
+	require.Equal(t, 3, backlogPressureReports())
+}
+
+type countingTransferTasksDB struct {
+	sqlplugin.DB
+	selects int
+}
+
+func (db *countingTransferTasksDB) RangeSelectFromTransferTasks(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksRangeFilter,
+) ([]sqlplugin.TransferTasksRow, error) {
+	db.selects++
+	return db.DB.RangeSelectFromTransferTasks(ctx, filter)
+}
+
+func TestGetHistoryTasks_TransferTaskReadAhead(t *testing.T) {
+	ctx := context.Background()
+	db := &countingTransferTasksDB{DB: newTestDB(t)}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{TransferTaskReadAheadSize: 4}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, db, shardID, rangeID)
+	for taskID := int64(1); taskID <= 10; taskID++ {
+		_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+			{ShardID: shardID, TaskID: taskID, Data: []byte("transfer"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+	}
+
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           3,
+	}
+	read := func(expectedTaskIDs []int64, expectedSelects int) {
+		t.Helper()
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		var taskIDs []int64
+		for _, task := range resp.Tasks {
+			taskIDs = append(taskIDs, task.Key.TaskID)
+		}
+		require.Equal(t, expectedTaskIDs, taskIDs)
+		require.Equal(t, expectedSelects, db.selects)
+		request.NextPageToken = resp.NextPageToken
+	}
+
+	// the first read selects tasks 1 to 7, and the next page is served from memory
+	read([]int64{1, 2, 3}, 1)
+	read([]int64{4, 5, 6}, 1)
+	// task 7 alone doesn't fill a page, and tasks after it weren't read
+	read([]int64{7, 8, 9}, 2)
+	// the last read reached the end of the range, so task 10 is all that's left
+	read([]int64{10}, 2)
+	require.Empty(t, request.NextPageToken)
+
+	// a read that doesn't continue the last one isn't served from memory
+	read([]int64{1, 2, 3}, 3)
+	request.NextPageToken = nil
+	read([]int64{1, 2, 3}, 4)
+
+	// adding tasks to the shard drops the tasks read ahead
+	require.NoError(t, store.AddHistoryTasks(ctx, &p.InternalAddHistoryTasksRequest{
+		ShardID: shardID,
+		RangeID: rangeID,
+		Tasks: map[tasks.Category][]p.InternalHistoryTask{
+			tasks.CategoryTransfer: {{Key: tasks.NewImmediateKey(11), Blob: p.NewDataBlob([]byte("task"), "test")}},
+		},
+	}))
+	read([]int64{4, 5, 6}, 5)
+
+	// and so does deleting them
+	_, err := store.TruncateShardTasksAbove(ctx, shardID, rangeID, 8, time.Now())
+	require.NoError(t, err)
+	read([]int64{7, 8}, 6)
+}
+
+func TestGetHistoryTasks_TaskCategoryValidation(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+
+	transferBlob, err := serialization.TransferTaskInfoToBlob(&persistencespb.TransferTaskInfo{
+		TaskType: enumsspb.TASK_TYPE_TRANSFER_WORKFLOW_TASK,
+		TaskId:   1,
+	})
+	require.NoError(t, err)
+	// a timer task written to the transfer table by mistake
+	timerBlob, err := serialization.TimerTaskInfoToBlob(&persistencespb.TimerTaskInfo{
+		TaskType: enumsspb.TASK_TYPE_USER_TIMER,
+		TaskId:   2,
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: transferBlob.Data, DataEncoding: transferBlob.EncodingType.String()},
+		{ShardID: shardID, TaskID: 2, Data: timerBlob.Data, DataEncoding: timerBlob.EncodingType.String()},
+	})
+	require.NoError(t, err)
+
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           10,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 2)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskCategoryValidation: "error"}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		_, err := store.GetHistoryTasks(ctx, request)
+		require.ErrorAs(t, err, new(*serviceerror.Internal))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		metricsHandler := metricstest.NewCaptureHandler()
+		capture := metricsHandler.StartCapture()
+		defer metricsHandler.StopCapture(capture)
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskCategoryValidation: "skip"}, log.NewTestLogger(), metricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 1)
+		require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+		require.Len(t, capture.Snapshot()[metrics.PersistenceTaskCategoryMismatches.Name()], 1)
+	})
+}
+
+func TestGetHistoryTasks_SkippedPageKeepsPageToken(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	defer metricsHandler.StopCapture(capture)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskCategoryValidation: "skip"}, log.NewTestLogger(), metricsHandler)
+	shardID := rand.Int31()
+
+	// the first page holds only timer tasks written to the transfer table by mistake
+	timerBlob, err := serialization.TimerTaskInfoToBlob(&persistencespb.TimerTaskInfo{
+		TaskType: enumsspb.TASK_TYPE_USER_TIMER,
+	})
+	require.NoError(t, err)
+	transferBlob, err := serialization.TransferTaskInfoToBlob(&persistencespb.TransferTaskInfo{
+		TaskType: enumsspb.TASK_TYPE_TRANSFER_WORKFLOW_TASK,
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: timerBlob.Data, DataEncoding: timerBlob.EncodingType.String()},
+		{ShardID: shardID, TaskID: 2, Data: timerBlob.Data, DataEncoding: timerBlob.EncodingType.String()},
+		{ShardID: shardID, TaskID: 3, Data: transferBlob.Data, DataEncoding: transferBlob.EncodingType.String()},
+	})
+	require.NoError(t, err)
+
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           2,
+	}
+	resp, err := store.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Empty(t, resp.Tasks)
+	require.NotEmpty(t, resp.NextPageToken)
+	require.Len(t, capture.Snapshot()[metrics.PersistenceTaskCategoryMismatches.Name()], 2)
+
+	request.NextPageToken = resp.NextPageToken
+	resp, err = store.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(3), resp.Tasks[0].Key.TaskID)
+	require.Empty(t, resp.NextPageToken)
+}
+
+// failingTimerInsertDB fails every insert into timer_tasks made in a transaction
+type failingTimerInsertDB struct {
+	sqlplugin.DB
+}
+
+type failingTimerInsertTx struct {
+	sqlplugin.Tx
+}
+
+func (db *failingTimerInsertDB) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
+	tx, err := db.DB.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &failingTimerInsertTx{Tx: tx}, nil
+}
+
+func (tx *failingTimerInsertTx) InsertIntoTimerTasks(context.Context, []sqlplugin.TimerTasksRow) (gosql.Result, error) {
+	return nil, errors.New("injected insert failure")
+}
+
+func TestCompleteAndRescheduleTimerTask(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	rangeID := int64(5)
+	insertShard(t, db, shardID, rangeID)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	completedKey := tasks.NewKey(now, 1)
+	nextTimerTask := p.InternalHistoryTask{
+		Key:  tasks.NewKey(now.Add(time.Minute), 2),
+		Blob: p.NewDataBlob([]byte("next timer"), "test"),
+	}
+	_, err := db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{{
+		ShardID:             shardID,
+		VisibilityTimestamp: completedKey.FireTime,
+		TaskID:              completedKey.TaskID,
+		Data:                []byte("timer"),
+		DataEncoding:        "test",
+	}})
+	require.NoError(t, err)
+
+	getTimerTasks := func(store p.ExecutionStore) []tasks.Key {
+		resp, err := store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        tasks.CategoryTimer,
+			InclusiveMinTaskKey: tasks.NewKey(now, 0),
+			ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Hour), 0),
+			BatchSize:           10,
+		})
+		require.NoError(t, err)
+		var keys []tasks.Key
+		for _, task := range resp.Tasks {
+			keys = append(keys, task.Key)
+		}
+		return keys
+	}
+
+	request := &p.InternalCompleteAndRescheduleTimerTaskRequest{
+		ShardID:          shardID,
+		RangeID:          rangeID,
+		CompletedTaskKey: completedKey,
+		NextTask:         nextTimerTask,
+	}
+
+	// a failed insert of the next timer rolls back the completion
+	failingStore := sql.NewTestSQLExecutionStore(&failingTimerInsertDB{DB: db}, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	err = failingStore.CompleteAndRescheduleTimerTask(ctx, request)
+	require.Error(t, err)
+	require.Equal(t, []tasks.Key{completedKey}, getTimerTasks(failingStore))
+
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	require.NoError(t, store.CompleteAndRescheduleTimerTask(ctx, request))
+	require.Equal(t, []tasks.Key{nextTimerTask.Key}, getTimerTasks(store))
+}
+
+func TestGetTimerTasksByType(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	fireTime := time.Now().UTC().Truncate(time.Millisecond)
+	taskTypes := []enumsspb.TaskType{
+		enumsspb.TASK_TYPE_USER_TIMER,
+		enumsspb.TASK_TYPE_WORKFLOW_RUN_TIMEOUT,
+		enumsspb.TASK_TYPE_ACTIVITY_TIMEOUT,
+		enumsspb.TASK_TYPE_USER_TIMER,
+		enumsspb.TASK_TYPE_WORKFLOW_RUN_TIMEOUT,
+		enumsspb.TASK_TYPE_USER_TIMER,
+		enumsspb.TASK_TYPE_USER_TIMER,
+		enumsspb.TASK_TYPE_WORKFLOW_RUN_TIMEOUT,
+	}
+	for i, taskType := range taskTypes {
+		taskID := int64(i + 1)
+		blob, err := serialization.TimerTaskInfoToBlob(&persistencespb.TimerTaskInfo{
+			TaskType: taskType,
+			TaskId:   taskID,
+		})
+		require.NoError(t, err)
+		_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{{
+			ShardID:             shardID,
+			VisibilityTimestamp: fireTime.Add(time.Duration(i) * time.Second),
+			TaskID:              taskID,
+			Data:                blob.Data,
+			DataEncoding:        blob.EncodingType.String(),
+		}})
+		require.NoError(t, err)
+	}
+
+	readAll := func(taskTypes []enumsspb.TaskType) []int64 {
+		request := &p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        tasks.CategoryTimer,
+			InclusiveMinTaskKey: tasks.NewKey(fireTime, 0),
+			ExclusiveMaxTaskKey: tasks.NewKey(fireTime.Add(time.Hour), 0),
+			BatchSize:           2,
+		}
+		var taskIDs []int64
+		for {
+			resp, err := store.GetTimerTasksByType(ctx, request, taskTypes)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(resp.Tasks), 2)
+			for _, task := range resp.Tasks {
+				taskIDs = append(taskIDs, task.Key.TaskID)
+			}
+			if len(resp.NextPageToken) == 0 {
+				return taskIDs
+			}
+			request.NextPageToken = resp.NextPageToken
+		}
+	}
+
+	require.Equal(t, []int64{2, 5, 8}, readAll([]enumsspb.TaskType{enumsspb.TASK_TYPE_WORKFLOW_RUN_TIMEOUT}))
+	require.Equal(t, []int64{2, 3, 5, 8}, readAll([]enumsspb.TaskType{
+		enumsspb.TASK_TYPE_WORKFLOW_RUN_TIMEOUT,
+		enumsspb.TASK_TYPE_ACTIVITY_TIMEOUT,
+	}))
+	require.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8}, readAll(nil))
+}
+
+func TestGetHistoryTasks_DefaultTaskDataEncoding(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+
+	info := &persistencespb.TransferTaskInfo{
+		TaskType: enumsspb.TASK_TYPE_TRANSFER_WORKFLOW_TASK,
+		TaskId:   1,
+	}
+	blob, err := serialization.TransferTaskInfoToBlob(info)
+	require.NoError(t, err)
+	// legacy row written without a data encoding
+	_, err = db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: blob.Data, DataEncoding: ""},
+	})
+	require.NoError(t, err)
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           10,
+	}
+
+	t.Run("no default", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 1)
+		require.Equal(t, enumspb.ENCODING_TYPE_UNSPECIFIED, resp.Tasks[0].Blob.EncodingType)
+		_, err = serialization.TransferTaskInfoFromBlob(resp.Tasks[0].Blob.Data, resp.Tasks[0].Blob.EncodingType.String())
+		require.Error(t, err)
+	})
+
+	t.Run("default", func(t *testing.T) {
+		metricsHandler := metricstest.NewCaptureHandler()
+		capture := metricsHandler.StartCapture()
+		defer metricsHandler.StopCapture(capture)
+		store := sql.NewTestSQLExecutionStore(
+			db,
+			&config.SQL{DefaultTaskDataEncoding: enumspb.ENCODING_TYPE_PROTO3.String()},
+			log.NewTestLogger(),
+			metricsHandler,
+		)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 1)
+		require.Equal(t, enumspb.ENCODING_TYPE_PROTO3, resp.Tasks[0].Blob.EncodingType)
+		decoded, err := serialization.TransferTaskInfoFromBlob(resp.Tasks[0].Blob.Data, resp.Tasks[0].Blob.EncodingType.String())
+		require.NoError(t, err)
+		require.Equal(t, info.TaskType, decoded.TaskType)
+		require.Len(t, capture.Snapshot()[metrics.PersistenceTaskEncodingFallbacks.Name()], 1)
+	})
+}
+
+// slowReplicationTasksDB advances timeSource by delay on every replication task read
+type slowReplicationTasksDB struct {
+	sqlplugin.DB
+	timeSource *clock.EventTimeSource
+	delay      time.Duration
+}
+
+func (db *slowReplicationTasksDB) RangeSelectFromReplicationTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationTasksRangeFilter,
+) ([]sqlplugin.ReplicationTasksRow, error) {
+	db.timeSource.Advance(db.delay)
+	return db.DB.RangeSelectFromReplicationTasks(ctx, filter)
+}
+
+type slowTransferTasksDB struct {
+	sqlplugin.DB
+	delay time.Duration
+}
+
+func (db *slowTransferTasksDB) RangeSelectFromTransferTasks(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksRangeFilter,
+) ([]sqlplugin.TransferTasksRow, error) {
+	select {
+	case <-time.After(db.delay):
+		return db.DB.RangeSelectFromTransferTasks(ctx, filter)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type reversedReplicationTasksDB struct {
+	sqlplugin.DB
+}
+
+func (db *reversedReplicationTasksDB) RangeSelectFromReplicationTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationTasksRangeFilter,
+) ([]sqlplugin.ReplicationTasksRow, error) {
+	rows, err := db.DB.RangeSelectFromReplicationTasks(ctx, filter)
+	slices.Reverse(rows)
+	return rows, err
+}
+
+func TestGetHistoryTasks_StrictTaskOrder(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 5; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryReplication,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           10,
+	}
+	taskIDs := func(resp *p.InternalGetHistoryTasksResponse) []int64 {
+		var taskIDs []int64
+		for _, task := range resp.Tasks {
+			taskIDs = append(taskIDs, task.Key.TaskID)
+		}
+		return taskIDs
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(&reversedReplicationTasksDB{DB: db}, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Equal(t, []int64{5, 4, 3, 2, 1}, taskIDs(resp))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		metricsHandler := metricstest.NewCaptureHandler()
+		capture := metricsHandler.StartCapture()
+		defer metricsHandler.StopCapture(capture)
+		store := sql.NewTestSQLExecutionStore(&reversedReplicationTasksDB{DB: db}, &config.SQL{StrictTaskOrder: true}, log.NewTestLogger(), metricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, taskIDs(resp))
+		require.Len(t, capture.Snapshot()[metrics.PersistenceTaskOrderViolations.Name()], 1)
+	})
+
+	t.Run("enabled in order", func(t *testing.T) {
+		metricsHandler := metricstest.NewCaptureHandler()
+		capture := metricsHandler.StartCapture()
+		defer metricsHandler.StopCapture(capture)
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{StrictTaskOrder: true}, log.NewTestLogger(), metricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, taskIDs(resp))
+		require.Empty(t, capture.Snapshot()[metrics.PersistenceTaskOrderViolations.Name()])
+	})
+}
+
+func TestGetReplicationTasksWithDeadline(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 10; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryReplication,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           8,
+	}
+
+	t.Run("batch size bounds the read", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		resp, err := store.GetReplicationTasksWithDeadline(ctx, request, 3, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 8)
+		require.NotNil(t, resp.NextPageToken)
+
+		nextRequest := *request
+		nextRequest.NextPageToken = resp.NextPageToken
+		resp, err = store.GetReplicationTasksWithDeadline(ctx, &nextRequest, 3, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 2)
+		require.Equal(t, int64(9), resp.Tasks[0].Key.TaskID)
+		require.Nil(t, resp.NextPageToken)
+	})
+
+	t.Run("deadline bounds the read", func(t *testing.T) {
+		timeSource := clock.NewEventTimeSource()
+		store := sql.NewTestSQLExecutionStoreWithTimeSource(
+			&slowReplicationTasksDB{DB: db, timeSource: timeSource, delay: time.Second},
+			&config.SQL{},
+			timeSource,
+			log.NewTestLogger(),
+			metrics.NoopMetricsHandler,
+		)
+
+		// the first sub-page is always returned
+		resp, err := store.GetReplicationTasksWithDeadline(ctx, request, 3, time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 3)
+		require.NotNil(t, resp.NextPageToken)
+
+		// the deadline passes while reading the second sub-page, which is still returned
+		nextRequest := *request
+		nextRequest.NextPageToken = resp.NextPageToken
+		resp, err = store.GetReplicationTasksWithDeadline(ctx, &nextRequest, 3, 1500*time.Millisecond)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 6)
+		require.Equal(t, int64(4), resp.Tasks[0].Key.TaskID)
+		require.NotNil(t, resp.NextPageToken)
+	})
+}
+
+func TestGetHistoryTasks_PartialResultsOnDeadline(t *testing.T) {
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 10; taskID++ {
+		_, err := db.InsertIntoTransferTasks(context.Background(), []sqlplugin.TransferTasksRow{
+			{ShardID: shardID, TaskID: taskID, Data: []byte("task"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+	}
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           8,
+	}
+	delay := 50 * time.Millisecond
+	slowDB := &slowTransferTasksDB{DB: db, delay: delay}
+
+	t.Run("disabled", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(slowDB, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		ctx, cancel := context.WithTimeout(context.Background(), delay/2)
+		defer cancel()
+
+		_, err := store.GetHistoryTasks(ctx, request)
+		require.Error(t, err)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(slowDB, &config.SQL{PartialResultsPageSize: 3}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		// enough time for the first page only
+		ctx, cancel := context.WithTimeout(context.Background(), delay*3/2)
+		defer cancel()
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 3)
+		require.NotNil(t, resp.NextPageToken)
+
+		nextRequest := *request
+		nextRequest.NextPageToken = resp.NextPageToken
+		resp, err = store.GetHistoryTasks(context.Background(), &nextRequest)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 7)
+		require.Equal(t, int64(4), resp.Tasks[0].Key.TaskID)
+		require.Nil(t, resp.NextPageToken)
+	})
+
+	t.Run("no page read before deadline", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(slowDB, &config.SQL{PartialResultsPageSize: 3}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		ctx, cancel := context.WithTimeout(context.Background(), delay/2)
+		defer cancel()
+
+		_, err := store.GetHistoryTasks(ctx, request)
+		require.Error(t, err)
+	})
+}
+
+func TestGetReplicationTaskIDs(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	for _, taskID := range []int64{1, 2, 4, 7} {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+	// the task data isn't read, so data that can't be decoded doesn't matter
+	_, err := db.InsertIntoReplicationTasks(ctx, []sqlplugin.ReplicationTasksRow{
+		{ShardID: shardID, TaskID: 9, Data: []byte("not a task"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	// task IDs of other shards are ignored
+	insertReplicationTask(t, db, shardID+1, 3)
+
+	taskIDs, err := store.GetReplicationTaskIDs(ctx, shardID, 0, 100, 3)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 4}, taskIDs)
+
+	taskIDs, err = store.GetReplicationTaskIDs(ctx, shardID, 5, 100, 3)
+	require.NoError(t, err)
+	require.Equal(t, []int64{7, 9}, taskIDs)
+
+	taskIDs, err = store.GetReplicationTaskIDs(ctx, shardID, 2, 9, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 4, 7}, taskIDs)
+
+	taskIDs, err = store.GetReplicationTaskIDs(ctx, shardID, 10, 100, 10)
+	require.NoError(t, err)
+	require.Empty(t, taskIDs)
+
+	_, err = store.GetReplicationTaskIDs(ctx, shardID, 0, 100, 0)
+	require.ErrorAs(t, err, new(*serviceerror.InvalidArgument))
+}
+
+func TestCompleteHistoryTask_ProcessingLatency(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	defer metricsHandler.StopCapture(capture)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metricsHandler)
+	shardID := rand.Int31()
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte{0}, DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 2, Data: []byte{0}, DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	err = store.CompleteHistoryTask(ctx, &p.CompleteHistoryTaskRequest{
+		ShardID:      shardID,
+		TaskCategory: tasks.CategoryTransfer,
+		TaskKey:      tasks.NewImmediateKey(1),
+	})
+	require.NoError(t, err)
+	require.Empty(t, capture.Snapshot()[metrics.PersistenceTaskProcessingLatency.Name()])
+
+	err = store.CompleteHistoryTask(ctx, &p.CompleteHistoryTaskRequest{
+		ShardID:          shardID,
+		TaskCategory:     tasks.CategoryTransfer,
+		TaskKey:          tasks.NewImmediateKey(2),
+		TaskCreationTime: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	recordings := capture.Snapshot()[metrics.PersistenceTaskProcessingLatency.Name()]
+	require.Len(t, recordings, 1)
+	require.GreaterOrEqual(t, recordings[0].Value, time.Minute)
+	require.Equal(t, tasks.CategoryTransfer.Name(), recordings[0].Tags[metrics.TaskCategoryTagName])
+}
+
+func TestCompleteHistoryTaskWithCount(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte{0}, DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now, TaskID: 1, Data: []byte{0}, DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	for _, request := range []*p.CompleteHistoryTaskRequest{
+		{ShardID: shardID, TaskCategory: tasks.CategoryTransfer, TaskKey: tasks.NewImmediateKey(1)},
+		{ShardID: shardID, TaskCategory: tasks.CategoryTimer, TaskKey: tasks.NewKey(now, 1)},
+	} {
+		rowsDeleted, err := store.CompleteHistoryTaskWithCount(ctx, request)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), rowsDeleted, request.TaskCategory.Name())
+
+		rowsDeleted, err = store.CompleteHistoryTaskWithCount(ctx, request)
+		require.NoError(t, err)
+		require.Zero(t, rowsDeleted, request.TaskCategory.Name())
+	}
+}
+
+func TestGetHistoryTasks_TasksReadMetric(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	defer metricsHandler.StopCapture(capture)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte{0}, DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 2, Data: []byte{0}, DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 3, Data: []byte{0}, DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now, TaskID: 1, Data: []byte{0}, DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	_, err = store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           10,
+	})
+	require.NoError(t, err)
+	_, err = store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(now, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+		BatchSize:           10,
+	})
+	require.NoError(t, err)
+	_, err = store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryVisibility,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           10,
+	})
+	require.NoError(t, err)
+
+	recordings := capture.Snapshot()[metrics.PersistenceHistoryTasksRead.Name()]
+	require.Len(t, recordings, 3)
+	counts := make(map[string]any, len(recordings))
+	for _, recording := range recordings {
+		counts[recording.Tags[metrics.TaskCategoryTagName]] = recording.Value
+	}
+	require.Equal(t, map[string]any{
+		tasks.CategoryTransfer.Name():   int64(3),
+		tasks.CategoryTimer.Name():      int64(1),
+		tasks.CategoryVisibility.Name(): int64(0),
+	}, counts)
+}
+
+func TestGetHistoryTasks_ReplicationMaxTaskIDIsStrict(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 10; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+
+	resp, err := store.GetHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryReplication,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(5),
+		BatchSize:           100,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 4)
+	require.Equal(t, int64(4), resp.Tasks[len(resp.Tasks)-1].Key.TaskID)
+	require.Empty(t, resp.NextPageToken)
+}
+
+func TestGetHistoryTasks_VisibilityPaging(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 5; taskID++ {
+		_, err := db.InsertIntoVisibilityTasks(ctx, []sqlplugin.VisibilityTasksRow{
+			{ShardID: shardID, TaskID: taskID, Data: []byte("visibility"), DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+	}
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryVisibility,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(100),
+		BatchSize:           2,
+	}
+
+	var pages [][]int64
+	for {
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		var taskIDs []int64
+		for _, task := range resp.Tasks {
+			taskIDs = append(taskIDs, task.Key.TaskID)
+		}
+		pages = append(pages, taskIDs)
+		if len(resp.NextPageToken) == 0 {
+			break
+		}
+		request.NextPageToken = resp.NextPageToken
+	}
+	require.Equal(t, [][]int64{{1, 2}, {3, 4}, {5}}, pages)
+
+	// without a batch size, the whole range is read at once
+	request.NextPageToken = nil
+	request.BatchSize = 0
+	resp, err := store.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 5)
+	require.Empty(t, resp.NextPageToken)
+}
+
+func transferQueueShardRow(t *testing.T, shardID int32, exclusiveReaderHighWatermark int64, scopeStarts ...int64) *sqlplugin.ShardsRow {
+	readerState := &persistencespb.QueueReaderState{}
+	for _, start := range scopeStarts {
+		readerState.Scopes = append(readerState.Scopes, &persistencespb.QueueSliceScope{
+			Range: &persistencespb.QueueSliceRange{
+				InclusiveMin: &persistencespb.TaskKey{TaskId: start},
+				ExclusiveMax: &persistencespb.TaskKey{TaskId: exclusiveReaderHighWatermark},
+			},
+		})
+	}
+	blob, err := serialization.NewSerializer().ShardInfoToBlob(&persistencespb.ShardInfo{
+		ShardId: shardID,
+		RangeId: 1,
+		QueueStates: map[int32]*persistencespb.QueueState{
+			int32(tasks.CategoryIDTransfer): {
+				ReaderStates:                 map[int64]*persistencespb.QueueReaderState{0: readerState},
+				ExclusiveReaderHighWatermark: &persistencespb.TaskKey{TaskId: exclusiveReaderHighWatermark},
+			},
+		},
+	}, enumspb.ENCODING_TYPE_PROTO3)
+	require.NoError(t, err)
+	return &sqlplugin.ShardsRow{
+		ShardID:      shardID,
+		RangeID:      1,
+		Data:         blob.Data,
+		DataEncoding: blob.EncodingType.String(),
+	}
+}
+
+type recordingTimerDeleteDB struct {
+	sqlplugin.DB
+	pageSizes     []int
+	onRangeDelete func()
+}
+
+func (db *recordingTimerDeleteDB) RangeDeleteFromTimerTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerTasksRangeFilter,
+) (gosql.Result, error) {
+	db.pageSizes = append(db.pageSizes, filter.PageSize)
+	result, err := db.DB.RangeDeleteFromTimerTasks(ctx, filter)
+	db.onRangeDelete()
+	return result, err
+}
+
+func TestRangeCompleteHistoryTasks_TimerDeleteBatchSize(t *testing.T) {
+	ctx := context.Background()
+	batchSize := 2
+	db := &recordingTimerDeleteDB{
+		DB: newTestDB(t),
+		// operators change the batch size while the range is being deleted
+		onRangeDelete: func() { batchSize = 3 },
+	}
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	defer metricsHandler.StopCapture(capture)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{
+		TimerTaskRangeDeleteBatchSize: func() int { return batchSize },
+	}, log.NewTestLogger(), metricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i := 0; i < 10; i++ {
+		_, err := db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+			{ShardID: shardID, VisibilityTimestamp: now.Add(time.Duration(i) * time.Second), TaskID: int64(i), Data: []byte{0}, DataEncoding: "test"},
+		})
+		require.NoError(t, err)
+	}
+	// outside of the completed range
+	_, err := db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now.Add(time.Hour), TaskID: 10, Data: []byte{0}, DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	err = store.RangeCompleteHistoryTasks(ctx, &p.RangeCompleteHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(now, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 3, 3, 3}, db.pageSizes)
+	var deleted []any
+	for _, recording := range capture.Snapshot()[metrics.PersistenceTimerTasksDeletedPerBatch.Name()] {
+		deleted = append(deleted, recording.Value)
+	}
+	require.Equal(t, []any{int64(2), int64(3), int64(3), int64(2)}, deleted)
+
+	rows, err := db.RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: now,
+		ExclusiveMaxVisibilityTimestamp: now.Add(2 * time.Hour),
+		PageSize:                        100,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, int64(10), rows[0].TaskID)
+
+	// without a batch size the range is deleted with one statement
+	batchSize = 0
+	db.pageSizes = nil
+	db.onRangeDelete = func() {}
+	err = store.RangeCompleteHistoryTasks(ctx, &p.RangeCompleteHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(now, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(now.Add(2*time.Hour), 0),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, db.pageSizes)
+}
+
+func TestRangeCompleteHistoryTasks_ShardLocked(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{ShardLockedRangeCompletes: true}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(5)
+	insertShard(t, db, shardID, rangeID)
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("transfer"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 2, Data: []byte("transfer"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	request := &p.RangeCompleteHistoryTasksRequest{
+		ShardID:             shardID,
+		RangeID:             rangeID - 1,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+	}
+
+	// a stale owner deletes nothing
+	require.ErrorAs(t, store.RangeCompleteHistoryTasks(ctx, request), new(*p.ShardOwnershipLostError))
+	count, err := store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	request.RangeID = 0
+	require.ErrorAs(t, store.RangeCompleteHistoryTasks(ctx, request), new(*serviceerror.InvalidArgument))
+
+	request.RangeID = rangeID
+	require.NoError(t, store.RangeCompleteHistoryTasks(ctx, request))
+	count, err = store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestRangeCompleteHistoryTasks_ReplicationLowerBound(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 6; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+
+	err := store.RangeCompleteHistoryTasks(ctx, &p.RangeCompleteHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryReplication,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(3),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(5),
+	})
+	require.NoError(t, err)
+	var taskIDs []int64
+	for _, row := range selectReplicationTasks(t, db, shardID) {
+		taskIDs = append(taskIDs, row.TaskID)
+	}
+	require.Equal(t, []int64{1, 2, 5, 6}, taskIDs)
+
+	// an unset lower bound starts at task ID 0
+	err = store.RangeCompleteHistoryTasks(ctx, &p.RangeCompleteHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryReplication,
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(6),
+	})
+	require.NoError(t, err)
+	rows := selectReplicationTasks(t, db, shardID)
+	require.Len(t, rows, 1)
+	require.Equal(t, int64(6), rows[0].TaskID)
+}
+
+type countingTxDB struct {
+	sqlplugin.DB
+	beginTxCount int
+}
+
+func (db *countingTxDB) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
+	db.beginTxCount++
+	return db.DB.BeginTx(ctx)
+}
+
+func insertShard(t *testing.T, db sqlplugin.DB, shardID int32, rangeID int64) {
+	_, err := db.InsertIntoShards(context.Background(), &sqlplugin.ShardsRow{
+		ShardID:      shardID,
+		RangeID:      rangeID,
+		Data:         []byte("shard"),
+		DataEncoding: "test",
+	})
+	require.NoError(t, err)
+}
+
+type shardLockRecordingDB struct {
+	sqlplugin.DB
+	events []string
+	// afterCommit is called after every commit
+	afterCommit func()
+}
+
+type shardLockRecordingTx struct {
+	sqlplugin.Tx
+	db *shardLockRecordingDB
+}
+
+func (db *shardLockRecordingDB) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
+	tx, err := db.DB.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &shardLockRecordingTx{Tx: tx, db: db}, nil
+}
+
+func (tx *shardLockRecordingTx) ReadLockShards(ctx context.Context, filter sqlplugin.ShardsFilter) (int64, error) {
+	tx.db.events = append(tx.db.events, "lock")
+	return tx.Tx.ReadLockShards(ctx, filter)
+}
+
+func (tx *shardLockRecordingTx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	tx.db.events = append(tx.db.events, "commit")
+	if tx.db.afterCommit != nil {
+		tx.db.afterCommit()
+	}
+	return nil
+}
+
+func TestAddHistoryTasks_SplitByCategory(t *testing.T) {
+	ctx := context.Background()
+	rangeID := int64(1)
+	newRequest := func(shardID int32) *p.InternalAddHistoryTasksRequest {
+		historyTasks := map[tasks.Category][]p.InternalHistoryTask{}
+		for taskID := int64(1); taskID <= 2; taskID++ {
+			blob := p.NewDataBlob([]byte("task"), "test")
+			historyTasks[tasks.CategoryTransfer] = append(historyTasks[tasks.CategoryTransfer], p.InternalHistoryTask{
+				Key: tasks.NewImmediateKey(taskID), Blob: blob,
+			})
+			historyTasks[tasks.CategoryReplication] = append(historyTasks[tasks.CategoryReplication], p.InternalHistoryTask{
+				Key: tasks.NewImmediateKey(taskID), Blob: blob,
+			})
+			historyTasks[tasks.CategoryVisibility] = append(historyTasks[tasks.CategoryVisibility], p.InternalHistoryTask{
+				Key: tasks.NewImmediateKey(taskID), Blob: blob,
+			})
+		}
+		return &p.InternalAddHistoryTasksRequest{ShardID: shardID, RangeID: rangeID, Tasks: historyTasks}
+	}
+	transferTaskCount := func(db sqlplugin.DB, shardID int32) int64 {
+		count, err := db.RangeCountFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+			ShardID:            shardID,
+			InclusiveMinTaskID: 0,
+			ExclusiveMaxTaskID: math.MaxInt64,
+		})
+		require.NoError(t, err)
+		return count
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		db := &shardLockRecordingDB{DB: newTestDB(t)}
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		shardID := rand.Int31()
+		insertShard(t, db, shardID, rangeID)
+
+		require.NoError(t, store.AddHistoryTasks(ctx, newRequest(shardID)))
+		require.Equal(t, []string{"lock", "commit"}, db.events)
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		db := &shardLockRecordingDB{DB: newTestDB(t)}
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{AddHistoryTasksSplitThreshold: 6}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		shardID := rand.Int31()
+		insertShard(t, db, shardID, rangeID)
+
+		require.NoError(t, store.AddHistoryTasks(ctx, newRequest(shardID)))
+		require.Equal(t, []string{"lock", "commit"}, db.events)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		db := &shardLockRecordingDB{DB: newTestDB(t)}
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{AddHistoryTasksSplitThreshold: 5}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		shardID := rand.Int31()
+		insertShard(t, db, shardID, rangeID)
+
+		require.NoError(t, store.AddHistoryTasks(ctx, newRequest(shardID)))
+		require.Equal(t, []string{"lock", "commit", "lock", "commit", "lock", "commit"}, db.events)
+		require.Equal(t, int64(2), transferTaskCount(db, shardID))
+		require.Len(t, selectReplicationTasks(t, db, shardID), 2)
+
+		// retried requests skip the tasks added before
+		require.NoError(t, store.AddHistoryTasks(ctx, newRequest(shardID)))
+		require.Equal(t, int64(2), transferTaskCount(db, shardID))
+	})
+
+	t.Run("range ID change aborts the rest", func(t *testing.T) {
+		db := &shardLockRecordingDB{DB: newTestDB(t)}
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{AddHistoryTasksSplitThreshold: 5}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+		shardID := rand.Int31()
+		insertShard(t, db, shardID, rangeID)
+		db.afterCommit = func() {
+			// another host acquires the shard after the first category is added
+			db.afterCommit = nil
+			_, err := db.DB.UpdateShards(ctx, &sqlplugin.ShardsRow{
+				ShardID:      shardID,
+				RangeID:      rangeID + 1,
+				Data:         []byte("shard"),
+				DataEncoding: "test",
+			})
+			require.NoError(t, err)
+		}
+
+		err := store.AddHistoryTasks(ctx, newRequest(shardID))
+		require.ErrorAs(t, err, new(*p.ShardOwnershipLostError))
+		require.ErrorContains(t, err, "after adding the tasks of categories [transfer]")
+		require.Equal(t, []string{"lock", "commit", "lock"}, db.events)
+		// categories are added in category ID order: transfer, then replication, then visibility
+		require.Equal(t, int64(2), transferTaskCount(db, shardID))
+		require.Empty(t, selectReplicationTasks(t, db, shardID))
+	})
+}
+
+type insertOrderRecordingDB struct {
+	sqlplugin.DB
+	tables []string
+}
+
+type insertOrderRecordingTx struct {
+	sqlplugin.Tx
+	db *insertOrderRecordingDB
+}
+
+func (db *insertOrderRecordingDB) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
+	tx, err := db.DB.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &insertOrderRecordingTx{Tx: tx, db: db}, nil
+}
+
+func (tx *insertOrderRecordingTx) InsertIntoTransferTasks(ctx context.Context, rows []sqlplugin.TransferTasksRow) (gosql.Result, error) {
+	tx.db.tables = append(tx.db.tables, "transfer_tasks")
+	return tx.Tx.InsertIntoTransferTasks(ctx, rows)
+}
+
+func (tx *insertOrderRecordingTx) InsertIntoTimerTasks(ctx context.Context, rows []sqlplugin.TimerTasksRow) (gosql.Result, error) {
+	tx.db.tables = append(tx.db.tables, "timer_tasks")
+	return tx.Tx.InsertIntoTimerTasks(ctx, rows)
+}
+
+func (tx *insertOrderRecordingTx) InsertIntoReplicationTasks(ctx context.Context, rows []sqlplugin.ReplicationTasksRow) (gosql.Result, error) {
+	tx.db.tables = append(tx.db.tables, "replication_tasks")
+	return tx.Tx.InsertIntoReplicationTasks(ctx, rows)
+}
+
+func (tx *insertOrderRecordingTx) InsertIntoVisibilityTasks(ctx context.Context, rows []sqlplugin.VisibilityTasksRow) (gosql.Result, error) {
+	tx.db.tables = append(tx.db.tables, "visibility_tasks")
+	return tx.Tx.InsertIntoVisibilityTasks(ctx, rows)
+}
+
+func TestAddHistoryTasks_CanonicalTableOrder(t *testing.T) {
+	ctx := context.Background()
+	db := &insertOrderRecordingDB{DB: newTestDB(t)}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, db, shardID, rangeID)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// map iteration order is random, so the order is checked over many requests
+	for taskID := int64(1); taskID <= 20; taskID++ {
+		db.tables = nil
+		blob := p.NewDataBlob([]byte("task"), "test")
+		require.NoError(t, store.AddHistoryTasks(ctx, &p.InternalAddHistoryTasksRequest{
+			ShardID: shardID,
+			RangeID: rangeID,
+			Tasks: map[tasks.Category][]p.InternalHistoryTask{
+				tasks.CategoryVisibility:  {{Key: tasks.NewImmediateKey(taskID), Blob: blob}},
+				tasks.CategoryReplication: {{Key: tasks.NewImmediateKey(taskID), Blob: blob}},
+				tasks.CategoryTimer:       {{Key: tasks.NewKey(now, taskID), Blob: blob}},
+				tasks.CategoryTransfer:    {{Key: tasks.NewImmediateKey(taskID), Blob: blob}},
+			},
+		}))
+		require.Equal(t, []string{"transfer_tasks", "timer_tasks", "replication_tasks", "visibility_tasks"}, db.tables)
+	}
+}
+
+func TestAddHistoryTasks_ShardClosing(t *testing.T) {
+	ctx := context.Background()
+	db := &countingTxDB{DB: newTestDB(t)}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, db, shardID, rangeID)
+
+	newRequest := func(taskID int64) *p.InternalAddHistoryTasksRequest {
+		return &p.InternalAddHistoryTasksRequest{
+			ShardID: shardID,
+			RangeID: rangeID,
+			Tasks: map[tasks.Category][]p.InternalHistoryTask{
+				tasks.CategoryTransfer: {{
+					Key:  tasks.NewImmediateKey(taskID),
+					Blob: p.NewDataBlob([]byte("task"), "test"),
+				}},
+			},
+		}
+	}
+
+	// a closing mark of a previous owner of the shard does not affect the current owner
+	store.MarkShardClosing(shardID, rangeID-1)
+	require.NoError(t, store.AddHistoryTasks(ctx, newRequest(1)))
+	beginTxCount := db.beginTxCount
+
+	store.MarkShardClosing(shardID, rangeID)
+	err := store.AddHistoryTasks(ctx, newRequest(2))
+	require.ErrorAs(t, err, new(*p.ShardOwnershipLostError))
+	require.Equal(t, beginTxCount, db.beginTxCount)
+
+	// other shards are not affected
+	otherShardID := shardID + 1
+	insertShard(t, db, otherShardID, rangeID)
+	otherRequest := newRequest(1)
+	otherRequest.ShardID = otherShardID
+	require.NoError(t, store.AddHistoryTasks(ctx, otherRequest))
+
+	store.MarkShardOpen(shardID)
+	require.NoError(t, store.AddHistoryTasks(ctx, newRequest(3)))
+}
+
+var (
+	errInjectedConflict  = errors.New("injected conflict")
+	errInjectedDataError = errors.New("injected data error")
+)
+
+// failingTransferInsertDB fails every insert into transfer_tasks made in a transaction with err, and classifies
+// errInjectedConflict as a conflict and errInjectedDataError as a data error
+type failingTransferInsertDB struct {
+	sqlplugin.DB
+	err error
+}
+
+type failingTransferInsertTx struct {
+	sqlplugin.Tx
+	err error
+}
+
+func (db *failingTransferInsertDB) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
+	tx, err := db.DB.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &failingTransferInsertTx{Tx: tx, err: db.err}, nil
+}
+
+func (db *failingTransferInsertDB) IsConflictError(err error) bool {
+	return errors.Is(err, errInjectedConflict)
+}
+
+func (db *failingTransferInsertDB) IsDataError(err error) bool {
+	return errors.Is(err, errInjectedDataError)
+}
+
+func (tx *failingTransferInsertTx) InsertIntoTransferTasks(context.Context, []sqlplugin.TransferTasksRow) (gosql.Result, error) {
+	return nil, tx.err
+}
+
+func TestAddHistoryTasks_ErrorClassification(t *testing.T) {
+	ctx := context.Background()
+	baseDB := newTestDB(t)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, baseDB, shardID, rangeID)
+	request := &p.InternalAddHistoryTasksRequest{
+		ShardID: shardID,
+		RangeID: rangeID,
+		Tasks: map[tasks.Category][]p.InternalHistoryTask{
+			tasks.CategoryTransfer: {
+				{Key: tasks.NewImmediateKey(3), Blob: p.NewDataBlob([]byte("task"), "test")},
+				{Key: tasks.NewImmediateKey(1), Blob: p.NewDataBlob([]byte("task"), "test")},
+				{Key: tasks.NewImmediateKey(2), Blob: p.NewDataBlob([]byte("task"), "test")},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		err         error
+		expectedErr any
+	}{
+		{name: "conflict", err: errInjectedConflict, expectedErr: new(*serviceerror.Unavailable)},
+		{name: "connection", err: fmt.Errorf("insert: %w", driver.ErrBadConn), expectedErr: new(*serviceerror.Unavailable)},
+		{name: "data", err: fmt.Errorf("insert: %w", errInjectedDataError), expectedErr: new(*serviceerror.Internal)},
+		// errors that aren't known to be permanent, e.g. a connection lost mid-query, are retried
+		{name: "unknown", err: errors.New("injected failure"), expectedErr: new(*serviceerror.Unavailable)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &failingTransferInsertDB{DB: baseDB, err: tc.err}
+			store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+			err := store.AddHistoryTasks(ctx, request)
+			require.ErrorAs(t, err, tc.expectedErr)
+			require.ErrorContains(t, err, "createTransferTasks failed for transfer tasks with task IDs [1, 3]")
+		})
+	}
+
+	// a stale range ID is still reported as lost shard ownership
+	db := &failingTransferInsertDB{DB: baseDB, err: errInjectedConflict}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	request.RangeID = rangeID + 1
+	err := store.AddHistoryTasks(ctx, request)
+	require.ErrorAs(t, err, new(*p.ShardOwnershipLostError))
+}
+
+func TestAddHistoryTasks_DuplicateTasks(t *testing.T) {
+	ctx := context.Background()
+	db := &shardLockRecordingDB{DB: newTestDB(t)}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, db, shardID, rangeID)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	newRequest := func(taskIDs ...int64) *p.InternalAddHistoryTasksRequest {
+		request := &p.InternalAddHistoryTasksRequest{
+			ShardID: shardID,
+			RangeID: rangeID,
+			Tasks:   map[tasks.Category][]p.InternalHistoryTask{},
+		}
+		for _, taskID := range taskIDs {
+			request.Tasks[tasks.CategoryTransfer] = append(request.Tasks[tasks.CategoryTransfer], p.InternalHistoryTask{
+				Key:  tasks.NewImmediateKey(taskID),
+				Blob: p.NewDataBlob([]byte("task"), "test"),
+			})
+			request.Tasks[tasks.CategoryTimer] = append(request.Tasks[tasks.CategoryTimer], p.InternalHistoryTask{
+				Key:  tasks.NewKey(now, taskID),
+				Blob: p.NewDataBlob([]byte("task"), "test"),
+			})
+		}
+		return request
+	}
+
+	require.NoError(t, store.AddHistoryTasks(ctx, newRequest(1)))
+	// a retried request
+	db.events = nil
+	require.NoError(t, store.AddHistoryTasks(ctx, newRequest(1)))
+	// duplicates are detected within the transaction of the request
+	require.Equal(t, []string{"lock", "commit"}, db.events)
+	// the tasks that don't exist yet are still added
+	require.NoError(t, store.AddHistoryTasks(ctx, newRequest(1, 2)))
+
+	// a task whose key exists with different data fails the whole request
+	request := newRequest(1, 3)
+	request.Tasks[tasks.CategoryTimer][0].Blob = p.NewDataBlob([]byte("other task"), "test")
+	var internalErr *serviceerror.Internal
+	require.ErrorAs(t, store.AddHistoryTasks(ctx, request), &internalErr)
+	request = newRequest(3)
+	request.Tasks[tasks.CategoryTransfer] = append(request.Tasks[tasks.CategoryTransfer], p.InternalHistoryTask{
+		Key:  tasks.NewImmediateKey(1),
+		Blob: p.NewDataBlob([]byte("task"), enumspb.ENCODING_TYPE_PROTO3.String()),
+	})
+	require.ErrorAs(t, store.AddHistoryTasks(ctx, request), &internalErr)
+
+	count, err := store.GetTransferTaskCount(ctx, shardID, 0, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+	rows, err := db.RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: now,
+		ExclusiveMaxVisibilityTimestamp: now.Add(time.Second),
+		PageSize:                        10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	// other errors are still returned
+	request = newRequest(1)
+	request.RangeID = rangeID + 1
+	require.ErrorAs(t, store.AddHistoryTasks(ctx, request), new(*p.ShardOwnershipLostError))
+}
+
+func TestTaskDataChecksums(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	rangeID := int64(1)
+	insertShard(t, db, shardID, rangeID)
+
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskDataChecksums: "error"}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	require.NoError(t, store.AddHistoryTasks(ctx, &p.InternalAddHistoryTasksRequest{
+		ShardID: shardID,
+		RangeID: rangeID,
+		Tasks: map[tasks.Category][]p.InternalHistoryTask{
+			tasks.CategoryTransfer: {
+				{Key: tasks.NewImmediateKey(1), Blob: p.NewDataBlob([]byte("task 1"), "test")},
+				{Key: tasks.NewImmediateKey(2), Blob: p.NewDataBlob([]byte("task 2"), "test")},
+			},
+		},
+	}))
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTransfer,
+		InclusiveMinTaskKey: tasks.NewImmediateKey(1),
+		ExclusiveMaxTaskKey: tasks.NewImmediateKey(10),
+		BatchSize:           10,
+	}
+
+	resp, err := store.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 2)
+	require.Equal(t, []byte("task 2"), resp.Tasks[1].Blob.Data)
+
+	// flip a byte of the stored data of task 2, keeping its checksum
+	rows, err := db.RangeSelectFromTransferTasks(ctx, sqlplugin.TransferTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: 2,
+		ExclusiveMaxTaskID: 3,
+		PageSize:           1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.NotNil(t, rows[0].DataChecksum)
+	_, err = db.DeleteFromTransferTasks(ctx, sqlplugin.TransferTasksFilter{ShardID: shardID, TaskID: 2})
+	require.NoError(t, err)
+	rows[0].Data[0] ^= 0xff
+	rows[0].ShardID = shardID
+	_, err = db.InsertIntoTransferTasks(ctx, rows)
+	require.NoError(t, err)
+
+	t.Run("error", func(t *testing.T) {
+		_, err := store.GetHistoryTasks(ctx, request)
+		require.ErrorAs(t, err, new(*serviceerror.DataLoss))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		metricsHandler := metricstest.NewCaptureHandler()
+		capture := metricsHandler.StartCapture()
+		defer metricsHandler.StopCapture(capture)
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{TaskDataChecksums: "skip"}, log.NewTestLogger(), metricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 1)
+		require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+		require.Len(t, capture.Snapshot()[metrics.PersistenceTaskDataChecksumMismatches.Name()], 1)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+		resp, err := store.GetHistoryTasks(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, resp.Tasks, 2)
+	})
+}
+
+func TestGetHistoryTasks_URLSafeTimerPageToken(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	_, err := db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now, TaskID: 1, Data: []byte("timer 1"), DataEncoding: "test"},
+		{ShardID: shardID, VisibilityTimestamp: now.Add(time.Second), TaskID: 2, Data: []byte("timer 2"), DataEncoding: "test"},
+		{ShardID: shardID, VisibilityTimestamp: now.Add(2 * time.Second), TaskID: 3, Data: []byte("timer 3"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	request := &p.GetHistoryTasksRequest{
+		ShardID:             shardID,
+		TaskCategory:        tasks.CategoryTimer,
+		InclusiveMinTaskKey: tasks.NewKey(now, 0),
+		ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+		BatchSize:           1,
+	}
+	urlSafeStore := sql.NewTestSQLExecutionStore(db, &config.SQL{URLSafePageTokens: true}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	legacyStore := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+	resp, err := urlSafeStore.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(1), resp.Tasks[0].Key.TaskID)
+	require.Regexp(t, "^[A-Za-z0-9_-]+$", string(resp.NextPageToken))
+
+	// a URL-safe token is accepted regardless of the configured encoding
+	request.NextPageToken = resp.NextPageToken
+	resp, err = legacyStore.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(2), resp.Tasks[0].Key.TaskID)
+	require.Equal(t, byte('{'), resp.NextPageToken[0])
+
+	// and so is a legacy raw JSON token
+	request.NextPageToken = resp.NextPageToken
+	resp, err = urlSafeStore.GetHistoryTasks(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(3), resp.Tasks[0].Key.TaskID)
+}
+
+func TestGetHistoryTasks_TimerPageBoundaryOnSharedTimestamp(t *testing.T) {
+	for name, cfg := range map[string]*config.SQL{
+		"json":     {},
+		"url safe": {URLSafePageTokens: true},
+		"binary":   {BinaryPageTokens: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t)
+			store := sql.NewTestSQLExecutionStore(db, cfg, log.NewTestLogger(), metrics.NoopMetricsHandler)
+			shardID := rand.Int31()
+			now := time.Now().UTC().Truncate(time.Millisecond)
+
+			// the first page ends in the middle of the tasks sharing now, and the last task fires later with a
+			// smaller task ID
+			_, err := db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+				{ShardID: shardID, VisibilityTimestamp: now, TaskID: 10, Data: []byte("timer 10"), DataEncoding: "test"},
+				{ShardID: shardID, VisibilityTimestamp: now, TaskID: 20, Data: []byte("timer 20"), DataEncoding: "test"},
+				{ShardID: shardID, VisibilityTimestamp: now, TaskID: 30, Data: []byte("timer 30"), DataEncoding: "test"},
+				{ShardID: shardID, VisibilityTimestamp: now.Add(time.Millisecond), TaskID: 5, Data: []byte("timer 5"), DataEncoding: "test"},
+				{ShardID: shardID, VisibilityTimestamp: now.Add(time.Second), TaskID: math.MaxInt64, Data: []byte("timer max"), DataEncoding: "test"},
+				{ShardID: shardID, VisibilityTimestamp: now.Add(time.Second), TaskID: math.MaxInt64 - 1, Data: []byte("timer max-1"), DataEncoding: "test"},
+				{ShardID: shardID, VisibilityTimestamp: now.Add(2 * time.Second), TaskID: 1, Data: []byte("timer 1"), DataEncoding: "test"},
+			})
+			require.NoError(t, err)
+
+			request := &p.GetHistoryTasksRequest{
+				ShardID:             shardID,
+				TaskCategory:        tasks.CategoryTimer,
+				InclusiveMinTaskKey: tasks.NewKey(now, 0),
+				ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+				BatchSize:           2,
+			}
+			var keys []tasks.Key
+			for page := 0; ; page++ {
+				require.Less(t, page, 10, "paging doesn't terminate")
+				resp, err := store.GetHistoryTasks(ctx, request)
+				require.NoError(t, err)
+				for _, task := range resp.Tasks {
+					keys = append(keys, tasks.NewKey(task.Key.FireTime.UTC(), task.Key.TaskID))
+				}
+				if len(resp.NextPageToken) == 0 {
+					break
+				}
+				request.NextPageToken = resp.NextPageToken
+			}
+			require.Equal(t, []tasks.Key{
+				tasks.NewKey(now, 10),
+				tasks.NewKey(now, 20),
+				tasks.NewKey(now, 30),
+				tasks.NewKey(now.Add(time.Millisecond), 5),
+				tasks.NewKey(now.Add(time.Second), math.MaxInt64-1),
+				tasks.NewKey(now.Add(time.Second), math.MaxInt64),
+				tasks.NewKey(now.Add(2*time.Second), 1),
+			}, keys)
+		})
+	}
+}
+
+func TestGetHistoryTasks_BinaryTimerPageToken(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	_, err := db.InsertIntoTimerTasks(ctx, []sqlplugin.TimerTasksRow{
+		{ShardID: shardID, VisibilityTimestamp: now, TaskID: 1, Data: []byte("timer 1"), DataEncoding: "test"},
+		{ShardID: shardID, VisibilityTimestamp: now.Add(time.Second), TaskID: 2, Data: []byte("timer 2"), DataEncoding: "test"},
+		{ShardID: shardID, VisibilityTimestamp: now.Add(2 * time.Second), TaskID: 3, Data: []byte("timer 3"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	newRequest := func(pageToken []byte) *p.GetHistoryTasksRequest {
+		return &p.GetHistoryTasksRequest{
+			ShardID:             shardID,
+			TaskCategory:        tasks.CategoryTimer,
+			InclusiveMinTaskKey: tasks.NewKey(now, 0),
+			ExclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Minute), 0),
+			BatchSize:           1,
+			NextPageToken:       pageToken,
+		}
+	}
+	binaryStore := sql.NewTestSQLExecutionStore(db, &config.SQL{BinaryPageTokens: true}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	urlSafeBinaryStore := sql.NewTestSQLExecutionStore(db, &config.SQL{BinaryPageTokens: true, URLSafePageTokens: true}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	legacyStore := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+
+	binaryResp, err := binaryStore.GetHistoryTasks(ctx, newRequest(nil))
+	require.NoError(t, err)
+	legacyResp, err := legacyStore.GetHistoryTasks(ctx, newRequest(nil))
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), binaryResp.NextPageToken[0])
+	require.Less(t, len(binaryResp.NextPageToken)*2, len(legacyResp.NextPageToken))
+
+	// a binary token is accepted regardless of the configured encoding
+	resp, err := legacyStore.GetHistoryTasks(ctx, newRequest(binaryResp.NextPageToken))
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(2), resp.Tasks[0].Key.TaskID)
+	require.True(t, now.Add(time.Second).Equal(resp.Tasks[0].Key.FireTime))
+
+	// and so is a legacy JSON token
+	resp, err = urlSafeBinaryStore.GetHistoryTasks(ctx, newRequest(legacyResp.NextPageToken))
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(2), resp.Tasks[0].Key.TaskID)
+	require.Regexp(t, "^[A-Za-z0-9_-]+$", string(resp.NextPageToken))
+
+	// as well as a URL-safe binary token
+	resp, err = legacyStore.GetHistoryTasks(ctx, newRequest(resp.NextPageToken))
+	require.NoError(t, err)
+	require.Len(t, resp.Tasks, 1)
+	require.Equal(t, int64(3), resp.Tasks[0].Key.TaskID)
+}
+
+type slowBeginTxDB struct {
+	sqlplugin.DB
+}
+
+func (db *slowBeginTxDB) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
+	time.Sleep(10 * time.Millisecond)
+	return db.DB.BeginTx(ctx)
+}
@@ -26,9 +26,13 @@ package sql
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 
 	commonpb "go.temporal.io/api/common/v1"
 	enumspb "go.temporal.io/api/enums/v1"
@@ -42,7 +46,38 @@ import (
 	"go.temporal.io/server/service/history/tasks"
 )
 
-func applyWorkflowMutationTx(
+// errDuplicateTaskKey is wrapped by the error applyTasks returns when a task key already exists
+var errDuplicateTaskKey = errors.New("task key already exists")
+
+// errInvalidTaskData is wrapped by the error applyTasks returns when the database rejects the data of a task, e.g.
+// for being too large. txExecuteShardLocked returns it as Internal, as retrying would fail again.
+var errInvalidTaskData = errors.New("invalid task data")
+
+// taskInsertError is the failure of a task insert statement. It keeps the database error, so that applyTasks
+// can check it for duplicate keys. applyTasks adds the category and task ID range of the inserted tasks, so
+// that the error tells which of the tasks failed.
+type taskInsertError struct {
+	operation string
+	err       error
+
+	category  tasks.Category
+	minTaskID int64
+	maxTaskID int64
+}
+
+func (e *taskInsertError) Error() string {
+	if e.category == (tasks.Category{}) {
+		return fmt.Sprintf("%v failed. Error: %v", e.operation, e.err)
+	}
+	return fmt.Sprintf("%v failed for %v tasks with task IDs [%v, %v]. Error: %v",
+		e.operation, e.category.Name(), e.minTaskID, e.maxTaskID, e.err)
+}
+
+func (e *taskInsertError) Unwrap() error {
+	return e.err
+}
+
+func (m *sqlExecutionStore) applyWorkflowMutationTx(
 	ctx context.Context,
 	tx sqlplugin.Tx,
 	shardID int32,
@@ -95,7 +130,7 @@ func applyWorkflowMutationTx(
 		return serviceerror.NewUnavailable(fmt.Sprintf("applyWorkflowMutationTx failed. Failed to update executions row. Erorr: %v", err))
 	}
 
-	if err := applyTasks(ctx,
+	if err := m.applyTasks(ctx,
 		tx,
 		shardID,
 		workflowMutation.Tasks,
@@ -212,7 +247,7 @@ func applyWorkflowMutationTx(
 	return nil
 }
 
-func applyWorkflowSnapshotTxAsReset(
+func (m *sqlExecutionStore) applyWorkflowSnapshotTxAsReset(
 	ctx context.Context,
 	tx sqlplugin.Tx,
 	shardID int32,
@@ -264,7 +299,7 @@ func applyWorkflowSnapshotTxAsReset(
 		return serviceerror.NewUnavailable(fmt.Sprintf("applyWorkflowSnapshotTxAsReset failed. Failed to update executions row. Erorr: %v", err))
 	}
 
-	if err := applyTasks(ctx,
+	if err := m.applyTasks(ctx,
 		tx,
 		shardID,
 		workflowSnapshot.Tasks,
@@ -472,7 +507,7 @@ func (m *sqlExecutionStore) applyWorkflowSnapshotTxAsNew(
 		return err
 	}
 
-	if err := applyTasks(ctx,
+	if err := m.applyTasks(ctx,
 		tx,
 		shardID,
 		workflowSnapshot.Tasks,
@@ -567,7 +602,13 @@ func (m *sqlExecutionStore) applyWorkflowSnapshotTxAsNew(
 	return nil
 }
 
-func applyTasks(
+// applyTasks inserts the given tasks of a shard. If a task key already exists, an error wrapping
+// errDuplicateTaskKey is returned; the statement that failed may have aborted tx, e.g. on PostgreSQL.
+// Inserts that fail on conflicts or throttling are returned as Unavailable, so that they are retried, and inserts
+// that fail on the data of the tasks wrap errInvalidTaskData.
+// Categories are inserted in ascending category ID order, see sortedTaskCategories, so that transactions adding
+// tasks of overlapping categories lock the task tables in the same order and can't deadlock on each other.
+func (m *sqlExecutionStore) applyTasks(
 	ctx context.Context,
 	tx sqlplugin.Tx,
 	shardID int32,
@@ -575,16 +616,33 @@ func applyTasks(
 ) error {
 
 	var err error
-	for category, tasksByCategory := range insertTasks {
+	for _, category := range sortedTaskCategories(insertTasks) {
+		tasksByCategory := insertTasks[category]
+		// invalidated before the tasks are committed, but reads of a shard's owner don't read beyond the task IDs
+		// of tasks that aren't committed yet, so that no read can keep tasks that miss them
+		m.readAhead.invalidate(shardID, category)
 		switch category.Type() {
 		case tasks.CategoryTypeImmediate:
-			err = createImmediateTasks(ctx, tx, shardID, category.ID(), tasksByCategory)
+			err = createImmediateTasks(ctx, tx, shardID, category.ID(), tasksByCategory, m.writeTaskDataChecksums())
 		case tasks.CategoryTypeScheduled:
-			err = createScheduledTasks(ctx, tx, shardID, category.ID(), tasksByCategory)
+			err = createScheduledTasks(ctx, tx, shardID, category.ID(), tasksByCategory, m.writeTaskDataChecksums())
 		default:
 			err = serviceerror.NewInternal(fmt.Sprintf("Unknown task category type: %v", category))
 		}
 
+		var insertErr *taskInsertError
+		if errors.As(err, &insertErr) {
+			insertErr.category = category
+			insertErr.minTaskID, insertErr.maxTaskID = taskIDRange(tasksByCategory)
+			switch {
+			case m.Db.IsDupEntryError(insertErr.err):
+				return fmt.Errorf("%w: %v", errDuplicateTaskKey, err)
+			case m.Db.IsConflictError(insertErr.err), m.Db.IsThrottlingError(insertErr.err):
+				return serviceerror.NewUnavailable(err.Error())
+			case m.Db.IsDataError(insertErr.err):
+				return fmt.Errorf("%w: %v", errInvalidTaskData, err)
+			}
+		}
 		if err != nil {
 			return err
 		}
@@ -593,6 +651,25 @@ func applyTasks(
 	return nil
 }
 
+// sortedTaskCategories returns the categories of tasksByCategory in ascending category ID order. This is the
+// canonical order tasks are written in: transfer_tasks, timer_tasks, replication_tasks and visibility_tasks, and
+// then history_immediate_tasks and history_scheduled_tasks by the IDs of the other categories.
+func sortedTaskCategories[T any](tasksByCategory map[tasks.Category]T) []tasks.Category {
+	return slices.SortedFunc(maps.Keys(tasksByCategory), func(a, b tasks.Category) int {
+		return cmp.Compare(a.ID(), b.ID())
+	})
+}
+
+// taskIDRange returns the smallest and the largest task ID of historyTasks, which must not be empty.
+func taskIDRange(historyTasks []p.InternalHistoryTask) (minTaskID int64, maxTaskID int64) {
+	minTaskID, maxTaskID = historyTasks[0].Key.TaskID, historyTasks[0].Key.TaskID
+	for _, task := range historyTasks[1:] {
+		minTaskID = min(minTaskID, task.Key.TaskID)
+		maxTaskID = max(maxTaskID, task.Key.TaskID)
+	}
+	return minTaskID, maxTaskID
+}
+
 // lockCurrentExecutionIfExists returns current execution or nil if none is found for the workflowID
 // locking it in the DB
 func lockCurrentExecutionIfExists(
@@ -719,17 +796,18 @@ func createImmediateTasks(
 	shardID int32,
 	categoryID int,
 	immedidateTasks []p.InternalHistoryTask,
+	dataChecksums bool,
 ) error {
 	// This is for backward compatiblity.
 	// These task categories exist before the general history_immediate_tasks table is created,
 	// so they have their own tables.
 	switch categoryID {
 	case tasks.CategoryIDTransfer:
-		return createTransferTasks(ctx, tx, shardID, immedidateTasks)
+		return createTransferTasks(ctx, tx, shardID, immedidateTasks, dataChecksums)
 	case tasks.CategoryIDVisibility:
-		return createVisibilityTasks(ctx, tx, shardID, immedidateTasks)
+		return createVisibilityTasks(ctx, tx, shardID, immedidateTasks, dataChecksums)
 	case tasks.CategoryIDReplication:
-		return createReplicationTasks(ctx, tx, shardID, immedidateTasks)
+		return createReplicationTasks(ctx, tx, shardID, immedidateTasks, dataChecksums)
 	}
 
 	if len(immedidateTasks) == 0 {
@@ -744,12 +822,13 @@ func createImmediateTasks(
 			TaskID:       task.Key.TaskID,
 			Data:         task.Blob.Data,
 			DataEncoding: task.Blob.EncodingType.String(),
+			DataChecksum: taskDataChecksum(dataChecksums, task.Blob.Data),
 		})
 	}
 
 	result, err := tx.InsertIntoHistoryImmediateTasks(ctx, immediateTasksRows)
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createImmediateTasks failed. Error: %v", err))
+		return &taskInsertError{operation: "createImmediateTasks", err: err}
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -769,12 +848,13 @@ func createScheduledTasks(
 	shardID int32,
 	categoryID int,
 	scheduledTasks []p.InternalHistoryTask,
+	dataChecksums bool,
 ) error {
 	// This is for backward compatiblity.
 	// These task categories exists before the general history_scheduled_tasks table is created,
 	// so they have their own tables.
 	if categoryID == tasks.CategoryIDTimer {
-		return createTimerTasks(ctx, tx, shardID, scheduledTasks)
+		return createTimerTasks(ctx, tx, shardID, scheduledTasks, dataChecksums)
 	}
 
 	if len(scheduledTasks) == 0 {
@@ -790,12 +870,13 @@ func createScheduledTasks(
 			TaskID:              task.Key.TaskID,
 			Data:                task.Blob.Data,
 			DataEncoding:        task.Blob.EncodingType.String(),
+			DataChecksum:        taskDataChecksum(dataChecksums, task.Blob.Data),
 		})
 	}
 
 	result, err := tx.InsertIntoHistoryScheduledTasks(ctx, scheduledTasksRows)
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createScheduledTasks failed. Error: %v", err))
+		return &taskInsertError{operation: "createScheduledTasks", err: err}
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -813,6 +894,7 @@ func createTransferTasks(
 	tx sqlplugin.Tx,
 	shardID int32,
 	transferTasks []p.InternalHistoryTask,
+	dataChecksums bool,
 ) error {
 
 	if len(transferTasks) == 0 {
@@ -826,12 +908,13 @@ func createTransferTasks(
 			TaskID:       task.Key.TaskID,
 			Data:         task.Blob.Data,
 			DataEncoding: task.Blob.EncodingType.String(),
+			DataChecksum: taskDataChecksum(dataChecksums, task.Blob.Data),
 		})
 	}
 
 	result, err := tx.InsertIntoTransferTasks(ctx, transferTasksRows)
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createTransferTasks failed. Error: %v", err))
+		return &taskInsertError{operation: "createTransferTasks", err: err}
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -850,6 +933,7 @@ func createTimerTasks(
 	tx sqlplugin.Tx,
 	shardID int32,
 	timerTasks []p.InternalHistoryTask,
+	dataChecksums bool,
 ) error {
 
 	if len(timerTasks) == 0 {
@@ -864,12 +948,13 @@ func createTimerTasks(
 			TaskID:              task.Key.TaskID,
 			Data:                task.Blob.Data,
 			DataEncoding:        task.Blob.EncodingType.String(),
+			DataChecksum:        taskDataChecksum(dataChecksums, task.Blob.Data),
 		})
 	}
 
 	result, err := tx.InsertIntoTimerTasks(ctx, timerTasksRows)
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createTimerTasks failed. Error: %v", err))
+		return &taskInsertError{operation: "createTimerTasks", err: err}
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -887,6 +972,7 @@ func createReplicationTasks(
 	tx sqlplugin.Tx,
 	shardID int32,
 	replicationTasks []p.InternalHistoryTask,
+	dataChecksums bool,
 ) error {
 
 	if len(replicationTasks) == 0 {
@@ -900,12 +986,13 @@ func createReplicationTasks(
 			TaskID:       task.Key.TaskID,
 			Data:         task.Blob.Data,
 			DataEncoding: task.Blob.EncodingType.String(),
+			DataChecksum: taskDataChecksum(dataChecksums, task.Blob.Data),
 		})
 	}
 
 	result, err := tx.InsertIntoReplicationTasks(ctx, replicationTasksRows)
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createReplicationTasks failed. Error: %v", err))
+		return &taskInsertError{operation: "createReplicationTasks", err: err}
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -914,7 +1001,7 @@ func createReplicationTasks(
 	}
 
 	if int(rowsAffected) != len(replicationTasks) {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createReplicationTasks failed. Inserted %v instead of %v rows into transfer_tasks. Error: %v", rowsAffected, len(replicationTasks), err))
+		return serviceerror.NewUnavailable(fmt.Sprintf("createReplicationTasks failed. Inserted %v instead of %v rows into replication_tasks. Error: %v", rowsAffected, len(replicationTasks), err))
 	}
 	return nil
 }
@@ -924,6 +1011,7 @@ func createVisibilityTasks(
 	tx sqlplugin.Tx,
 	shardID int32,
 	visibilityTasks []p.InternalHistoryTask,
+	dataChecksums bool,
 ) error {
 
 	if len(visibilityTasks) == 0 {
@@ -937,21 +1025,22 @@ func createVisibilityTasks(
 			TaskID:       task.Key.TaskID,
 			Data:         task.Blob.Data,
 			DataEncoding: task.Blob.EncodingType.String(),
+			DataChecksum: taskDataChecksum(dataChecksums, task.Blob.Data),
 		})
 	}
 
 	result, err := tx.InsertIntoVisibilityTasks(ctx, visibilityTasksRows)
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createTransferTasks failed. Error: %v", err))
+		return &taskInsertError{operation: "createVisibilityTasks", err: err}
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createTransferTasks failed. Could not verify number of rows inserted. Error: %v", err))
+		return serviceerror.NewUnavailable(fmt.Sprintf("createVisibilityTasks failed. Could not verify number of rows inserted. Error: %v", err))
 	}
 
 	if int(rowsAffected) != len(visibilityTasksRows) {
-		return serviceerror.NewUnavailable(fmt.Sprintf("createTransferTasks failed. Inserted %v instead of %v rows into transfer_tasks. Error: %v", rowsAffected, len(visibilityTasksRows), err))
+		return serviceerror.NewUnavailable(fmt.Sprintf("createVisibilityTasks failed. Inserted %v instead of %v rows into visibility_tasks. Error: %v", rowsAffected, len(visibilityTasksRows), err))
 	}
 	return nil
 }
@@ -0,0 +1,66 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"go.temporal.io/server/common/clock"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+)
+
+func NewTestSQLExecutionStore(
+	db sqlplugin.DB,
+	cfg *config.SQL,
+	logger log.Logger,
+	metricsHandler metrics.Handler,
+) *sqlExecutionStore {
+	return newSQLExecutionStore(db, cfg, logger, metricsHandler)
+}
+
+func NewTestSQLExecutionStoreWithReplicationDLQ(
+	db sqlplugin.DB,
+	cfg *config.SQL,
+	replicationDLQ ReplicationDLQStore,
+	logger log.Logger,
+	metricsHandler metrics.Handler,
+) *sqlExecutionStore {
+	store := newSQLExecutionStore(db, cfg, logger, metricsHandler)
+	store.replicationDLQ = replicationDLQ
+	return store
+}
+
+func NewTestSQLExecutionStoreWithTimeSource(
+	db sqlplugin.DB,
+	cfg *config.SQL,
+	timeSource clock.TimeSource,
+	logger log.Logger,
+	metricsHandler metrics.Handler,
+) *sqlExecutionStore {
+	store := newSQLExecutionStore(db, cfg, logger, metricsHandler)
+	store.timeSource = timeSource
+	return store
+}
@@ -39,10 +39,11 @@ import (
 type (
 	// Factory vends store objects backed by MySQL
 	Factory struct {
-		cfg         config.SQL
-		mainDBConn  DbConn
-		clusterName string
-		logger      log.Logger
+		cfg            config.SQL
+		mainDBConn     DbConn
+		clusterName    string
+		logger         log.Logger
+		metricsHandler metrics.Handler
 	}
 
 	// DbConn represents a logical mysql connection - its a
@@ -72,10 +73,11 @@ func NewFactory(
 	metricsHandler metrics.Handler,
 ) *Factory {
 	return &Factory{
-		cfg:         cfg,
-		clusterName: clusterName,
-		logger:      logger,
-		mainDBConn:  NewRefCountedDBConn(sqlplugin.DbKindMain, &cfg, r, logger, metricsHandler),
+		cfg:            cfg,
+		clusterName:    clusterName,
+		logger:         logger,
+		metricsHandler: metricsHandler,
+		mainDBConn:     NewRefCountedDBConn(sqlplugin.DbKindMain, &cfg, r, logger, metricsHandler),
 	}
 }
 
@@ -130,7 +132,7 @@ func (f *Factory) NewExecutionStore() (p.ExecutionStore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewSQLExecutionStore(conn, f.logger)
+	return NewSQLExecutionStore(conn, &f.cfg, f.logger, f.metricsHandler)
 }
 
 // NewQueue returns a new queue backed by sql
@@ -0,0 +1,204 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+// readAheadMaxAge is how long read-ahead tasks are served for. Tasks of a shard are only written through the store
+// of its owner, which invalidates its read-ahead tasks, but another host may write them while owning the shard, so
+// read-ahead tasks of a shard that moved away and back must not be served.
+const readAheadMaxAge = 10 * time.Second
+
+type (
+	// taskReadAheadCache reads more tasks than requested by history task reads of every shard and task category,
+	// and serves the next read of the shard and category from the extra tasks if it continues where the last one
+	// left off. Writes of a shard's tasks invalidate its read-ahead tasks.
+	taskReadAheadCache struct {
+		size int
+
+		sync.Mutex
+		entries map[taskReadAheadKey]*taskReadAheadEntry
+	}
+
+	taskReadAheadKey struct {
+		shardID  int32
+		category tasks.Category
+	}
+
+	// taskReadAheadEntry holds all tasks of a shard and category with task IDs in
+	// [inclusiveMinTaskID, exclusiveMaxTaskID) at the time they were read.
+	taskReadAheadEntry struct {
+		// generation is incremented by every invalidation, so that reads that started before an invalidation
+		// don't store their tasks
+		generation         uint64
+		readTime           time.Time
+		inclusiveMinTaskID int64
+		exclusiveMaxTaskID int64
+		transferTasks      []sqlplugin.TransferTasksRow
+	}
+)
+
+// newTaskReadAheadCache returns nil if size isn't positive, which disables reading ahead.
+func newTaskReadAheadCache(size int) *taskReadAheadCache {
+	if size <= 0 {
+		return nil
+	}
+	return &taskReadAheadCache{
+		size:    size,
+		entries: make(map[taskReadAheadKey]*taskReadAheadEntry),
+	}
+}
+
+// selectTransferTasks selects the transfer tasks of filter through db. If the read continues the last read of
+// the shard, it is served from the tasks read ahead by it as long as there are enough of them, otherwise size
+// more tasks than requested are selected and kept for the next read.
+func (c *taskReadAheadCache) selectTransferTasks(
+	ctx context.Context,
+	db sqlplugin.HistoryTransferTask,
+	filter sqlplugin.TransferTasksRangeFilter,
+) ([]sqlplugin.TransferTasksRow, error) {
+	if c == nil || filter.PageSize <= 0 {
+		return db.RangeSelectFromTransferTasks(ctx, filter)
+	}
+
+	key := taskReadAheadKey{shardID: filter.ShardID, category: tasks.CategoryTransfer}
+	c.Lock()
+	entry := c.entry(key)
+	if rows, ok := entry.readTransferTasks(filter); ok {
+		c.Unlock()
+		return rows, nil
+	}
+	generation := entry.generation
+	c.Unlock()
+
+	readAheadFilter := filter
+	readAheadFilter.PageSize += c.size
+	rows, err := db.RangeSelectFromTransferTasks(ctx, readAheadFilter)
+	if err != nil || len(rows) <= filter.PageSize {
+		return rows, err
+	}
+
+	// the read returned all tasks up to its last one, or up to the end of the range if it wasn't full
+	exclusiveMaxTaskID := filter.ExclusiveMaxTaskID
+	if len(rows) == readAheadFilter.PageSize {
+		exclusiveMaxTaskID = rows[len(rows)-1].TaskID + 1
+	}
+	c.Lock()
+	if entry := c.entry(key); entry.generation == generation {
+		entry.readTime = time.Now()
+		entry.inclusiveMinTaskID = rows[filter.PageSize-1].TaskID + 1
+		entry.exclusiveMaxTaskID = exclusiveMaxTaskID
+		entry.transferTasks = rows[filter.PageSize:]
+	}
+	c.Unlock()
+	return rows[:filter.PageSize], nil
+}
+
+// invalidate drops the read-ahead tasks of the shard and category. It must be called whenever tasks are added to
+// or moved into the shard and category.
+func (c *taskReadAheadCache) invalidate(shardID int32, category tasks.Category) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.entry(taskReadAheadKey{shardID: shardID, category: category}).invalidate()
+}
+
+// invalidateShard drops the read-ahead tasks of all task categories of the shard.
+func (c *taskReadAheadCache) invalidateShard(shardID int32) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	for key, entry := range c.entries {
+		if key.shardID == shardID {
+			entry.invalidate()
+		}
+	}
+}
+
+// entry returns the entry of key, creating it if needed. Entries are never removed, so that their generation
+// survives invalidations. c must be locked.
+func (c *taskReadAheadCache) entry(key taskReadAheadKey) *taskReadAheadEntry {
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &taskReadAheadEntry{}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// readTransferTasks returns the transfer tasks of filter if the entry has all of them, and drops them from the
+// entry. The entry is invalidated if the read doesn't continue where the last read left off.
+func (e *taskReadAheadEntry) readTransferTasks(
+	filter sqlplugin.TransferTasksRangeFilter,
+) ([]sqlplugin.TransferTasksRow, bool) {
+	if len(e.transferTasks) == 0 ||
+		e.inclusiveMinTaskID != filter.InclusiveMinTaskID ||
+		time.Since(e.readTime) > readAheadMaxAge {
+		e.invalidate()
+		return nil, false
+	}
+
+	rows := e.transferTasks
+	if len(rows) > filter.PageSize {
+		rows = rows[:filter.PageSize]
+	}
+	for i, row := range rows {
+		if row.TaskID >= filter.ExclusiveMaxTaskID {
+			rows = rows[:i]
+			break
+		}
+	}
+	switch {
+	case len(rows) == filter.PageSize:
+		e.inclusiveMinTaskID = rows[len(rows)-1].TaskID + 1
+		e.transferTasks = e.transferTasks[len(rows):]
+	case e.exclusiveMaxTaskID >= filter.ExclusiveMaxTaskID:
+		// the entry has all tasks of the range, even though they don't fill a page
+		e.invalidate()
+	default:
+		e.invalidate()
+		return nil, false
+	}
+	return rows, true
+}
+
+// invalidate drops the tasks of the entry and stops reads that started before from storing theirs.
+func (e *taskReadAheadEntry) invalidate() {
+	e.generation++
+	e.transferTasks = nil
+}
@@ -0,0 +1,133 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+)
+
+type (
+	// ReplicationDLQStore stores the replication DLQ tasks of an execution store. By default they are stored in
+	// the replication_tasks_dlq table; an alternate implementation, e.g. one backed by a blob store for very large
+	// DLQs, can be passed to NewSQLExecutionStoreWithReplicationDLQ.
+	// Operations that change the DLQ and other tables in one transaction, i.e. MoveReplicationTasksToDLQ and
+	// ArchiveReplicationDLQ, and the aggregate queries GetReplicationDLQTaskCount, GetReplicationDLQReasonCounts
+	// and FindOrphanedDLQSourceClusters need the replication_tasks_dlq table. They return Unimplemented errors if
+	// an alternate implementation is used.
+	ReplicationDLQStore interface {
+		// PutTasks adds tasks to the DLQ. Tasks are immutable, so tasks that are already in the DLQ are left as
+		// is and are not an error.
+		PutTasks(ctx context.Context, rows []sqlplugin.ReplicationDLQTasksRow) error
+		// GetTasks returns, in task ID order, at most filter.PageSize tasks of a shard and source cluster with a
+		// task ID in [filter.InclusiveMinTaskID, filter.ExclusiveMaxTaskID).
+		GetTasks(ctx context.Context, filter sqlplugin.ReplicationDLQTasksRangeFilter) ([]sqlplugin.ReplicationDLQTasksRow, error)
+		// DeleteTask deletes a task. Deleting a task that doesn't exist is not an error.
+		DeleteTask(ctx context.Context, filter sqlplugin.ReplicationDLQTasksFilter) error
+		// RangeDeleteTasks deletes the tasks of a shard and source cluster with a task ID in
+		// [filter.InclusiveMinTaskID, filter.ExclusiveMaxTaskID). filter.PageSize is ignored.
+		RangeDeleteTasks(ctx context.Context, filter sqlplugin.ReplicationDLQTasksRangeFilter) error
+	}
+
+	// sqlReplicationDLQStore is the default ReplicationDLQStore, which stores the tasks in the
+	// replication_tasks_dlq table
+	sqlReplicationDLQStore struct {
+		db sqlplugin.DB
+	}
+)
+
+var _ ReplicationDLQStore = (*sqlReplicationDLQStore)(nil)
+
+func newSQLReplicationDLQStore(db sqlplugin.DB) *sqlReplicationDLQStore {
+	return &sqlReplicationDLQStore{db: db}
+}
+
+// requireSQLReplicationDLQ returns an Unimplemented error for operations that need the replication_tasks_dlq
+// table if the replication DLQ is stored by an alternate ReplicationDLQStore, see ReplicationDLQStore.
+func (m *sqlExecutionStore) requireSQLReplicationDLQ(operation string) error {
+	if _, ok := m.replicationDLQ.(*sqlReplicationDLQStore); ok {
+		return nil
+	}
+	return serviceerror.NewUnimplemented(fmt.Sprintf(
+		"%v is not supported with an alternate replication DLQ store", operation,
+	))
+}
+
+// PutTasks inserts the tasks with a single statement. If the statement fails because some tasks are already in
+// the DLQ, the tasks are inserted one by one, skipping the duplicates.
+func (s *sqlReplicationDLQStore) PutTasks(
+	ctx context.Context,
+	rows []sqlplugin.ReplicationDLQTasksRow,
+) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	_, err := s.db.InsertIntoReplicationDLQTasks(ctx, rows)
+	if err == nil || !s.db.IsDupEntryError(err) {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := s.db.InsertIntoReplicationDLQTasks(ctx, []sqlplugin.ReplicationDLQTasksRow{row}); err != nil && !s.db.IsDupEntryError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlReplicationDLQStore) GetTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationDLQTasksRangeFilter,
+) ([]sqlplugin.ReplicationDLQTasksRow, error) {
+	rows, err := s.db.RangeSelectFromReplicationDLQTasks(ctx, filter)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	// the source cluster is not selected, as rows are filtered by it
+	for i := range rows {
+		rows[i].SourceClusterName = filter.SourceClusterName
+		rows[i].ShardID = filter.ShardID
+	}
+	return rows, nil
+}
+
+func (s *sqlReplicationDLQStore) DeleteTask(
+	ctx context.Context,
+	filter sqlplugin.ReplicationDLQTasksFilter,
+) error {
+	_, err := s.db.DeleteFromReplicationDLQTasks(ctx, filter)
+	return err
+}
+
+func (s *sqlReplicationDLQStore) RangeDeleteTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationDLQTasksRangeFilter,
+) error {
+	_, err := s.db.RangeDeleteFromReplicationDLQTasks(ctx, filter)
+	return err
+}
@@ -0,0 +1,263 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+	persistencespb "go.temporal.io/server/api/persistence/v1"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	p "go.temporal.io/server/common/persistence"
+	persistencesql "go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+type replicationDLQKey struct {
+	shardID           int32
+	sourceClusterName string
+	taskID            int64
+}
+
+// memoryReplicationDLQStore is an in-memory ReplicationDLQStore, standing in for an alternate backing store
+type memoryReplicationDLQStore struct {
+	mu    sync.Mutex
+	tasks map[replicationDLQKey]sqlplugin.ReplicationDLQTasksRow
+}
+
+var _ persistencesql.ReplicationDLQStore = (*memoryReplicationDLQStore)(nil)
+
+func newMemoryReplicationDLQStore() *memoryReplicationDLQStore {
+	return &memoryReplicationDLQStore{tasks: make(map[replicationDLQKey]sqlplugin.ReplicationDLQTasksRow)}
+}
+
+func (s *memoryReplicationDLQStore) PutTasks(_ context.Context, rows []sqlplugin.ReplicationDLQTasksRow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range rows {
+		key := replicationDLQKey{row.ShardID, row.SourceClusterName, row.TaskID}
+		if _, ok := s.tasks[key]; !ok {
+			s.tasks[key] = row
+		}
+	}
+	return nil
+}
+
+func (s *memoryReplicationDLQStore) GetTasks(
+	_ context.Context,
+	filter sqlplugin.ReplicationDLQTasksRangeFilter,
+) ([]sqlplugin.ReplicationDLQTasksRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rows []sqlplugin.ReplicationDLQTasksRow
+	for key, row := range s.tasks {
+		if s.inRange(key, filter) {
+			rows = append(rows, row)
+		}
+	}
+	slices.SortFunc(rows, func(a, b sqlplugin.ReplicationDLQTasksRow) int {
+		return cmp.Compare(a.TaskID, b.TaskID)
+	})
+	return rows[:min(len(rows), filter.PageSize)], nil
+}
+
+func (s *memoryReplicationDLQStore) DeleteTask(_ context.Context, filter sqlplugin.ReplicationDLQTasksFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, replicationDLQKey{filter.ShardID, filter.SourceClusterName, filter.TaskID})
+	return nil
+}
+
+func (s *memoryReplicationDLQStore) RangeDeleteTasks(_ context.Context, filter sqlplugin.ReplicationDLQTasksRangeFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.tasks {
+		if s.inRange(key, filter) {
+			delete(s.tasks, key)
+		}
+	}
+	return nil
+}
+
+func (s *memoryReplicationDLQStore) inRange(key replicationDLQKey, filter sqlplugin.ReplicationDLQTasksRangeFilter) bool {
+	return key.shardID == filter.ShardID &&
+		key.sourceClusterName == filter.SourceClusterName &&
+		key.taskID >= filter.InclusiveMinTaskID &&
+		key.taskID < filter.ExclusiveMaxTaskID
+}
+
+func TestReplicationDLQStore(t *testing.T) {
+	for name, newDLQStore := range map[string]func() persistencesql.ReplicationDLQStore{
+		"sql":    func() persistencesql.ReplicationDLQStore { return nil },
+		"memory": func() persistencesql.ReplicationDLQStore { return newMemoryReplicationDLQStore() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t)
+			store := persistencesql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+			if dlqStore := newDLQStore(); dlqStore != nil {
+				store = persistencesql.NewTestSQLExecutionStoreWithReplicationDLQ(db, &config.SQL{}, dlqStore, log.NewTestLogger(), metrics.NoopMetricsHandler)
+			}
+
+			ctx := context.Background()
+			shardID := rand.Int31()
+			sourceCluster := "source-cluster"
+			newTaskInfo := func(taskID int64) *persistencespb.ReplicationTaskInfo {
+				return &persistencespb.ReplicationTaskInfo{NamespaceId: uuid.New(), TaskId: taskID}
+			}
+			getTaskIDs := func(sourceClusterName string) []int64 {
+				resp, err := store.GetReplicationTasksFromDLQ(ctx, &p.GetReplicationTasksFromDLQRequest{
+					SourceClusterName: sourceClusterName,
+					GetHistoryTasksRequest: p.GetHistoryTasksRequest{
+						ShardID:             shardID,
+						TaskCategory:        tasks.CategoryReplication,
+						InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+						ExclusiveMaxTaskKey: tasks.NewImmediateKey(math.MaxInt64),
+						BatchSize:           100,
+					},
+				})
+				require.NoError(t, err)
+				var taskIDs []int64
+				for _, task := range resp.Tasks {
+					taskIDs = append(taskIDs, task.Key.TaskID)
+				}
+				return taskIDs
+			}
+			isEmpty := func() bool {
+				empty, err := store.IsReplicationDLQEmpty(ctx, &p.GetReplicationTasksFromDLQRequest{
+					SourceClusterName: sourceCluster,
+					GetHistoryTasksRequest: p.GetHistoryTasksRequest{
+						ShardID:             shardID,
+						TaskCategory:        tasks.CategoryReplication,
+						InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+					},
+				})
+				require.NoError(t, err)
+				return empty
+			}
+
+			require.True(t, isEmpty())
+			require.NoError(t, store.PutReplicationTaskToDLQ(ctx, &p.PutReplicationTaskToDLQRequest{
+				ShardID:           shardID,
+				SourceClusterName: sourceCluster,
+				TaskInfo:          newTaskInfo(3),
+			}))
+			// tasks already in the DLQ are not an error
+			require.NoError(t, store.PutReplicationTasksToDLQ(ctx, shardID, sourceCluster, []*persistencespb.ReplicationTaskInfo{
+				newTaskInfo(1), newTaskInfo(2), newTaskInfo(3), newTaskInfo(4), newTaskInfo(5),
+			}))
+			require.NoError(t, store.PutReplicationTasksToDLQ(ctx, shardID, "other-cluster", []*persistencespb.ReplicationTaskInfo{
+				newTaskInfo(1),
+			}))
+			require.False(t, isEmpty())
+			require.Equal(t, []int64{1, 2, 3, 4, 5}, getTaskIDs(sourceCluster))
+			require.Equal(t, []int64{1}, getTaskIDs("other-cluster"))
+
+			require.NoError(t, store.DeleteReplicationTaskFromDLQ(ctx, &p.DeleteReplicationTaskFromDLQRequest{
+				SourceClusterName: sourceCluster,
+				CompleteHistoryTaskRequest: p.CompleteHistoryTaskRequest{
+					ShardID:      shardID,
+					TaskCategory: tasks.CategoryReplication,
+					TaskKey:      tasks.NewImmediateKey(2),
+				},
+			}))
+			require.Equal(t, []int64{1, 3, 4, 5}, getTaskIDs(sourceCluster))
+
+			require.NoError(t, store.RangeDeleteReplicationTaskFromDLQ(ctx, &p.RangeDeleteReplicationTaskFromDLQRequest{
+				SourceClusterName: sourceCluster,
+				RangeCompleteHistoryTasksRequest: p.RangeCompleteHistoryTasksRequest{
+					ShardID:             shardID,
+					TaskCategory:        tasks.CategoryReplication,
+					InclusiveMinTaskKey: tasks.NewImmediateKey(0),
+					ExclusiveMaxTaskKey: tasks.NewImmediateKey(5),
+				},
+			}))
+			require.Equal(t, []int64{5}, getTaskIDs(sourceCluster))
+			require.Equal(t, []int64{1}, getTaskIDs("other-cluster"))
+
+			rows, err := db.RangeSelectFromReplicationDLQTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+				ShardID:            shardID,
+				SourceClusterName:  sourceCluster,
+				InclusiveMinTaskID: 0,
+				ExclusiveMaxTaskID: math.MaxInt64,
+				PageSize:           100,
+			})
+			require.NoError(t, err)
+			if name == "sql" {
+				require.Len(t, rows, 1)
+			} else {
+				require.Empty(t, rows)
+			}
+		})
+	}
+}
+
+func TestReplicationDLQStore_SQLOnlyOperations(t *testing.T) {
+	db := newTestDB(t)
+	dlqStore := newMemoryReplicationDLQStore()
+	store := persistencesql.NewTestSQLExecutionStoreWithReplicationDLQ(db, &config.SQL{}, dlqStore, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	ctx := context.Background()
+	shardID := rand.Int31()
+	sourceCluster := "source-cluster"
+	require.NoError(t, store.PutReplicationTasksToDLQ(ctx, shardID, sourceCluster, []*persistencespb.ReplicationTaskInfo{
+		{NamespaceId: uuid.New(), TaskId: 1},
+	}))
+	insertReplicationTask(t, db, shardID, 2)
+
+	var unimplementedErr *serviceerror.Unimplemented
+	require.ErrorAs(t, store.MoveReplicationTasksToDLQ(ctx, shardID, []int64{2}, sourceCluster), &unimplementedErr)
+	_, err := store.GetReplicationDLQTaskCount(ctx, shardID, sourceCluster, nil)
+	require.ErrorAs(t, err, &unimplementedErr)
+	_, err = store.GetReplicationDLQReasonCounts(ctx, shardID, sourceCluster)
+	require.ErrorAs(t, err, &unimplementedErr)
+	_, err = store.FindOrphanedDLQSourceClusters(ctx, shardID, nil)
+	require.ErrorAs(t, err, &unimplementedErr)
+	_, err = store.ArchiveReplicationDLQ(ctx, shardID, "archive")
+	require.ErrorAs(t, err, &unimplementedErr)
+
+	// neither the DLQ nor the replication tasks were changed
+	rows, err := dlqStore.GetTasks(ctx, sqlplugin.ReplicationDLQTasksRangeFilter{
+		ShardID:            shardID,
+		SourceClusterName:  sourceCluster,
+		InclusiveMinTaskID: 0,
+		ExclusiveMaxTaskID: math.MaxInt64,
+		PageSize:           10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	replicationTasks := selectReplicationTasks(t, db, shardID)
+	require.Len(t, replicationTasks, 1)
+	require.Equal(t, int64(2), replicationTasks[0].TaskID)
+}
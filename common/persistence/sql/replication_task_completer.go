@@ -0,0 +1,166 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.temporal.io/server/common/log/tag"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+type (
+	// ReplicationTaskCompleter completes replication tasks of a shard one at a time, but coalesces completions of
+	// consecutive task IDs into a single range delete. Buffered completions are flushed when a completed task ID
+	// doesn't extend the current run, when the flush interval elapses, and on Flush or Close.
+	// Until then, a completed task is still stored and may be read again.
+	ReplicationTaskCompleter struct {
+		store         *sqlExecutionStore
+		shardID       int32
+		rangeID       int64
+		flushInterval time.Duration
+
+		mu sync.Mutex
+		// the buffered run of completed task IDs is [runStart, runEnd)
+		runStart   int64
+		runEnd     int64
+		flushTimer *time.Timer
+		closed     bool
+	}
+)
+
+// NewReplicationTaskCompleter returns a ReplicationTaskCompleter for the replication tasks of a shard owned with
+// rangeID. Runs of completed tasks are deleted like RangeCompleteHistoryTasks, so with shard locked range completes
+// a stale owner fails to flush with ShardOwnershipLostError.
+// Buffered completions are flushed at the latest flushInterval after they are buffered; if flushInterval is 0
+// they are only flushed on gaps, Flush and Close.
+func (m *sqlExecutionStore) NewReplicationTaskCompleter(
+	shardID int32,
+	rangeID int64,
+	flushInterval time.Duration,
+) *ReplicationTaskCompleter {
+	return &ReplicationTaskCompleter{
+		store:         m,
+		shardID:       shardID,
+		rangeID:       rangeID,
+		flushInterval: flushInterval,
+	}
+}
+
+// Complete marks a replication task as completed. If taskID doesn't directly follow the buffered run of
+// completed task IDs, the buffered run is deleted first.
+func (c *ReplicationTaskCompleter) Complete(
+	ctx context.Context,
+	taskID int64,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.runEnd > c.runStart && taskID == c.runEnd {
+		c.runEnd++
+		return nil
+	}
+	if err := c.flushLocked(ctx); err != nil {
+		return err
+	}
+	c.runStart = taskID
+	c.runEnd = taskID + 1
+	if c.flushInterval > 0 && !c.closed {
+		c.flushTimer = time.AfterFunc(c.flushInterval, c.flushOnTimer)
+	}
+	return nil
+}
+
+// Flush deletes the buffered completed tasks.
+func (c *ReplicationTaskCompleter) Flush(
+	ctx context.Context,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.flushLocked(ctx)
+}
+
+// Close deletes the buffered completed tasks and stops the flush timer.
+func (c *ReplicationTaskCompleter) Close(
+	ctx context.Context,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	return c.flushLocked(ctx)
+}
+
+func (c *ReplicationTaskCompleter) flushOnTimer() {
+	if err := c.Flush(context.Background()); err != nil {
+		c.store.logger.Error("Failed to flush completed replication tasks",
+			tag.ShardID(c.shardID),
+			tag.Error(err),
+		)
+	}
+}
+
+func (c *ReplicationTaskCompleter) flushLocked(
+	ctx context.Context,
+) error {
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+
+	var err error
+	switch c.runEnd - c.runStart {
+	case 0:
+		return nil
+	case 1:
+		_, err = c.store.completeReplicationTask(ctx, &p.CompleteHistoryTaskRequest{
+			ShardID:      c.shardID,
+			TaskCategory: tasks.CategoryReplication,
+			TaskKey:      tasks.NewImmediateKey(c.runStart),
+		})
+	default:
+		request := &p.RangeCompleteHistoryTasksRequest{
+			ShardID:             c.shardID,
+			RangeID:             c.rangeID,
+			TaskCategory:        tasks.CategoryReplication,
+			InclusiveMinTaskKey: tasks.NewImmediateKey(c.runStart),
+			ExclusiveMaxTaskKey: tasks.NewImmediateKey(c.runEnd),
+		}
+		err = c.store.executeRangeComplete(ctx, "ReplicationTaskCompleter", request, func(db sqlplugin.TableCRUD) error {
+			return c.store.rangeCompleteReplicationTasks(ctx, db, request)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	c.runStart = 0
+	c.runEnd = 0
+	return nil
+}
@@ -0,0 +1,128 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	p "go.temporal.io/server/common/persistence"
+	persistencesql "go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+)
+
+type recordingReplicationDeletesDB struct {
+	sqlplugin.DB
+	pointDeletes []int64
+	rangeDeletes [][2]int64
+}
+
+func (db *recordingReplicationDeletesDB) DeleteFromReplicationTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationTasksFilter,
+) (sql.Result, error) {
+	db.pointDeletes = append(db.pointDeletes, filter.TaskID)
+	return db.DB.DeleteFromReplicationTasks(ctx, filter)
+}
+
+func (db *recordingReplicationDeletesDB) RangeDeleteFromReplicationTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationTasksRangeFilter,
+) (sql.Result, error) {
+	db.rangeDeletes = append(db.rangeDeletes, [2]int64{filter.InclusiveMinTaskID, filter.ExclusiveMaxTaskID})
+	return db.DB.RangeDeleteFromReplicationTasks(ctx, filter)
+}
+
+func TestReplicationTaskCompleter(t *testing.T) {
+	ctx := context.Background()
+	db := &recordingReplicationDeletesDB{DB: newTestDB(t)}
+	store := persistencesql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 10; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+
+	completer := store.NewReplicationTaskCompleter(shardID, 1, 0)
+	for _, taskID := range []int64{1, 2, 3, 4, 6, 8, 9} {
+		require.NoError(t, completer.Complete(ctx, taskID))
+	}
+	require.NoError(t, completer.Close(ctx))
+
+	require.Equal(t, [][2]int64{{1, 5}, {8, 10}}, db.rangeDeletes)
+	require.Equal(t, []int64{6}, db.pointDeletes)
+	require.Equal(t, []int64{5, 7, 10}, selectReplicationTaskIDs(t, db, shardID))
+}
+
+func TestReplicationTaskCompleter_FlushInterval(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := persistencesql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	for taskID := int64(1); taskID <= 3; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+
+	completer := store.NewReplicationTaskCompleter(shardID, 1, 10*time.Millisecond)
+	defer func() { require.NoError(t, completer.Close(ctx)) }()
+	require.NoError(t, completer.Complete(ctx, 1))
+	require.NoError(t, completer.Complete(ctx, 2))
+
+	require.Eventually(t, func() bool {
+		return slices.Equal([]int64{3}, selectReplicationTaskIDs(t, db, shardID))
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestReplicationTaskCompleter_ShardLocked(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := persistencesql.NewTestSQLExecutionStore(db, &config.SQL{ShardLockedRangeCompletes: true}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	rangeID := int64(5)
+	insertShard(t, db, shardID, rangeID)
+	for taskID := int64(1); taskID <= 4; taskID++ {
+		insertReplicationTask(t, db, shardID, taskID)
+	}
+
+	// a stale owner deletes nothing
+	completer := store.NewReplicationTaskCompleter(shardID, rangeID-1, 0)
+	require.NoError(t, completer.Complete(ctx, 1))
+	require.NoError(t, completer.Complete(ctx, 2))
+	require.ErrorAs(t, completer.Flush(ctx), new(*p.ShardOwnershipLostError))
+	require.Equal(t, []int64{1, 2, 3, 4}, selectReplicationTaskIDs(t, db, shardID))
+
+	completer = store.NewReplicationTaskCompleter(shardID, rangeID, 0)
+	require.NoError(t, completer.Complete(ctx, 1))
+	require.NoError(t, completer.Complete(ctx, 2))
+	require.NoError(t, completer.Close(ctx))
+	require.Equal(t, []int64{3, 4}, selectReplicationTaskIDs(t, db, shardID))
+}
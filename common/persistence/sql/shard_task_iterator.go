@@ -0,0 +1,150 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+
+	"go.temporal.io/server/common/collection"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+type (
+	// CategorizedHistoryTask is a history task together with the category it was read from
+	CategorizedHistoryTask struct {
+		Category tasks.Category
+		Task     p.InternalHistoryTask
+	}
+
+	// shardImmediateTaskIterator merges the tasks of several immediate task categories into a single stream
+	// ordered by task ID. It buffers at most one page of tasks per category.
+	shardImmediateTaskIterator struct {
+		categories []tasks.Category
+		iterators  []collection.Iterator[p.InternalHistoryTask]
+		heads      []*p.InternalHistoryTask
+		err        error
+	}
+)
+
+var _ collection.Iterator[CategorizedHistoryTask] = (*shardImmediateTaskIterator)(nil)
+
+// NewShardImmediateTaskIterator returns an iterator over the transfer, replication and visibility tasks of a shard
+// with task IDs in [inclusiveMinTaskID, exclusiveMaxTaskID), ordered by task ID across categories.
+// Each category is read in pages of pageSize tasks.
+func (m *sqlExecutionStore) NewShardImmediateTaskIterator(
+	ctx context.Context,
+	shardID int32,
+	inclusiveMinTaskID int64,
+	exclusiveMaxTaskID int64,
+	pageSize int,
+) collection.Iterator[CategorizedHistoryTask] {
+	categories := []tasks.Category{
+		tasks.CategoryTransfer,
+		tasks.CategoryReplication,
+		tasks.CategoryVisibility,
+	}
+	iter := &shardImmediateTaskIterator{
+		categories: categories,
+		iterators:  make([]collection.Iterator[p.InternalHistoryTask], len(categories)),
+		heads:      make([]*p.InternalHistoryTask, len(categories)),
+	}
+	for i, category := range categories {
+		iter.iterators[i] = collection.NewPagingIterator(func(paginationToken []byte) ([]p.InternalHistoryTask, []byte, error) {
+			resp, err := m.getHistoryTasks(ctx, &p.GetHistoryTasksRequest{
+				ShardID:             shardID,
+				TaskCategory:        category,
+				InclusiveMinTaskKey: tasks.NewImmediateKey(inclusiveMinTaskID),
+				ExclusiveMaxTaskKey: tasks.NewImmediateKey(exclusiveMaxTaskID),
+				BatchSize:           pageSize,
+				NextPageToken:       paginationToken,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			m.enforceTaskOrder("NewShardImmediateTaskIterator", shardID, resp.Tasks)
+			return resp.Tasks, resp.NextPageToken, nil
+		})
+	}
+	return iter
+}
+
+// HasNext returns whether there is a next task or error
+func (i *shardImmediateTaskIterator) HasNext() bool {
+	i.fillHeads()
+	if i.err != nil {
+		return true
+	}
+	for _, head := range i.heads {
+		if head != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the task with the lowest task ID across all categories, or an error
+func (i *shardImmediateTaskIterator) Next() (CategorizedHistoryTask, error) {
+	if !i.HasNext() {
+		panic("shardImmediateTaskIterator Next() called without checking HasNext()")
+	}
+
+	if i.err != nil {
+		err := i.err
+		i.err = nil
+		return CategorizedHistoryTask{}, err
+	}
+
+	next := -1
+	for idx, head := range i.heads {
+		if head != nil && (next == -1 || head.Key.TaskID < i.heads[next].Key.TaskID) {
+			next = idx
+		}
+	}
+	task := *i.heads[next]
+	i.heads[next] = nil
+	return CategorizedHistoryTask{
+		Category: i.categories[next],
+		Task:     task,
+	}, nil
+}
+
+// fillHeads reads the next task of every category that has no buffered head task
+func (i *shardImmediateTaskIterator) fillHeads() {
+	if i.err != nil {
+		return
+	}
+	for idx, iter := range i.iterators {
+		if i.heads[idx] != nil || !iter.HasNext() {
+			continue
+		}
+		task, err := iter.Next()
+		if err != nil {
+			i.err = err
+			return
+		}
+		i.heads[idx] = &task
+	}
+}
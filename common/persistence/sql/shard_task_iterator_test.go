@@ -0,0 +1,91 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+func TestShardImmediateTaskIterator(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+
+	_, err := db.InsertIntoTransferTasks(ctx, []sqlplugin.TransferTasksRow{
+		{ShardID: shardID, TaskID: 1, Data: []byte("transfer 1"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 4, Data: []byte("transfer 4"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 5, Data: []byte("transfer 5"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 9, Data: []byte("transfer 9"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoReplicationTasks(ctx, []sqlplugin.ReplicationTasksRow{
+		{ShardID: shardID, TaskID: 2, Data: []byte("replication 2"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 7, Data: []byte("replication 7"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+	_, err = db.InsertIntoVisibilityTasks(ctx, []sqlplugin.VisibilityTasksRow{
+		{ShardID: shardID, TaskID: 3, Data: []byte("visibility 3"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 6, Data: []byte("visibility 6"), DataEncoding: "test"},
+		{ShardID: shardID, TaskID: 8, Data: []byte("visibility 8"), DataEncoding: "test"},
+		// outside of the requested range
+		{ShardID: shardID, TaskID: 10, Data: []byte("visibility 10"), DataEncoding: "test"},
+	})
+	require.NoError(t, err)
+
+	// a page size smaller than the number of tasks per category forces every reader to page
+	iter := store.NewShardImmediateTaskIterator(ctx, shardID, 1, 10, 2)
+
+	var taskIDs []int64
+	var categories []tasks.Category
+	for iter.HasNext() {
+		task, err := iter.Next()
+		require.NoError(t, err)
+		taskIDs = append(taskIDs, task.Task.Key.TaskID)
+		categories = append(categories, task.Category)
+	}
+	require.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9}, taskIDs)
+	require.Equal(t, []tasks.Category{
+		tasks.CategoryTransfer,
+		tasks.CategoryReplication,
+		tasks.CategoryVisibility,
+		tasks.CategoryTransfer,
+		tasks.CategoryTransfer,
+		tasks.CategoryVisibility,
+		tasks.CategoryReplication,
+		tasks.CategoryVisibility,
+		tasks.CategoryTransfer,
+	}, categories)
+}
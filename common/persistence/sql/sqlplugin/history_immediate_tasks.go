@@ -37,6 +37,7 @@ type (
 		TaskID       int64
 		Data         []byte
 		DataEncoding string
+		DataChecksum *int64
 	}
 
 	// HistoryImmediateTasksFilter contains the column names within history_immediate_tasks table that
@@ -27,8 +27,15 @@ package sqlplugin
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"regexp"
 )
 
+// ReplicationDLQArchiveTablePrefix is the name prefix of the tables replication DLQ tasks are archived into
+const ReplicationDLQArchiveTablePrefix = "replication_tasks_dlq_archive_"
+
+var replicationDLQArchiveTableSuffixRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]{1,32}$`)
+
 type (
 	// ReplicationDLQTasksRow represents a row in replication_tasks_dlq table
 	ReplicationDLQTasksRow struct {
@@ -37,6 +44,9 @@ type (
 		TaskID            int64
 		Data              []byte
 		DataEncoding      string
+		DataChecksum      *int64
+		// Reason is the optional reason the task was put into the DLQ
+		Reason *string
 	}
 
 	// ReplicationDLQTasksFilter contains the column names within replication_tasks_dlq table that
@@ -56,6 +66,19 @@ type (
 		PageSize           int
 	}
 
+	// ReplicationDLQTasksMinTaskIDRow represents the minimum task ID of one source cluster in replication_tasks_dlq table
+	ReplicationDLQTasksMinTaskIDRow struct {
+		SourceClusterName string
+		MinTaskID         int64
+	}
+
+	// ReplicationDLQTasksReasonCountRow represents the number of rows with one reason in replication_tasks_dlq table.
+	// Reason is empty for rows without a reason.
+	ReplicationDLQTasksReasonCountRow struct {
+		Reason    string
+		TaskCount int64
+	}
+
 	// HistoryReplicationDLQTask is the SQL persistence interface for history replication tasks DLQ
 	HistoryReplicationDLQTask interface {
 		// InsertIntoReplicationDLQTasks puts the replication task into DLQ
@@ -67,5 +90,31 @@ type (
 		// RangeDeleteFromReplicationDLQTasks deletes one or more rows from replication_tasks_dlq table
 		//  ReplicationDLQTasksRangeFilter - {PageSize} will be ignored
 		RangeDeleteFromReplicationDLQTasks(ctx context.Context, filter ReplicationDLQTasksRangeFilter) (sql.Result, error)
+		// SelectMinTaskIDFromReplicationDLQTasks returns the minimum task ID of a shard's rows in replication_tasks_dlq table,
+		// grouped by source cluster
+		SelectMinTaskIDFromReplicationDLQTasks(ctx context.Context, shardID int32) ([]ReplicationDLQTasksMinTaskIDRow, error)
+		// SelectReasonCountsFromReplicationDLQTasks returns the number of rows of a shard and source cluster in
+		// replication_tasks_dlq table, grouped by reason
+		SelectReasonCountsFromReplicationDLQTasks(ctx context.Context, shardID int32, sourceClusterName string) ([]ReplicationDLQTasksReasonCountRow, error)
+		// SelectCountFromReplicationDLQTasks returns the number of rows in replication_tasks_dlq table within the range
+		//  ReplicationDLQTasksRangeFilter - {PageSize} will be ignored
+		SelectCountFromReplicationDLQTasks(ctx context.Context, filter ReplicationDLQTasksRangeFilter) (int64, error)
+		// CreateReplicationDLQArchiveTable creates the archive table with the given suffix, with the same columns as
+		// replication_tasks_dlq table, if it doesn't exist yet. On MySQL this implicitly commits the current transaction.
+		CreateReplicationDLQArchiveTable(ctx context.Context, archiveTableSuffix string) error
+		// CopyIntoReplicationDLQArchive copies all rows of a shard in replication_tasks_dlq table into the archive table
+		// with the given suffix
+		CopyIntoReplicationDLQArchive(ctx context.Context, shardID int32, archiveTableSuffix string) (sql.Result, error)
+		// DeleteShardFromReplicationDLQTasks deletes all rows of a shard from replication_tasks_dlq table
+		DeleteShardFromReplicationDLQTasks(ctx context.Context, shardID int32) (sql.Result, error)
 	}
 )
+
+// ReplicationDLQArchiveTableName returns the name of the replication DLQ archive table with the given suffix.
+// The suffix is part of the SQL statements, so it may only consist of up to 32 letters, digits and underscores.
+func ReplicationDLQArchiveTableName(archiveTableSuffix string) (string, error) {
+	if !replicationDLQArchiveTableSuffixRegexp.MatchString(archiveTableSuffix) {
+		return "", fmt.Errorf("invalid replication DLQ archive table suffix %q", archiveTableSuffix)
+	}
+	return ReplicationDLQArchiveTablePrefix + archiveTableSuffix, nil
+}
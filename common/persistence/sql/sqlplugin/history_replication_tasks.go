@@ -36,6 +36,7 @@ type (
 		TaskID       int64
 		Data         []byte
 		DataEncoding string
+		DataChecksum *int64
 	}
 
 	// ReplicationTasksFilter contains the column names within replication_tasks table that
@@ -54,16 +55,29 @@ type (
 		PageSize           int
 	}
 
+	// ReplicationTasksStatsRow represents aggregates over the rows of a shard in replication_tasks table
+	ReplicationTasksStatsRow struct {
+		MinTaskID int64
+		MaxTaskID int64
+		TaskCount int64
+	}
+
 	// HistoryReplicationTask is the SQL persistence interface for history replication tasks
 	HistoryReplicationTask interface {
 		// InsertIntoReplicationTasks inserts rows that into replication_tasks table.
 		InsertIntoReplicationTasks(ctx context.Context, rows []ReplicationTasksRow) (sql.Result, error)
 		// RangeSelectFromReplicationTasks returns one or more rows from replication_tasks table
 		RangeSelectFromReplicationTasks(ctx context.Context, filter ReplicationTasksRangeFilter) ([]ReplicationTasksRow, error)
+		// RangeSelectTaskIDsFromReplicationTasks returns the task_ids of the rows RangeSelectFromReplicationTasks
+		// would return for filter, without reading their data.
+		RangeSelectTaskIDsFromReplicationTasks(ctx context.Context, filter ReplicationTasksRangeFilter) ([]int64, error)
 		// DeleteFromReplicationTasks deletes a row from replication_tasks table
 		DeleteFromReplicationTasks(ctx context.Context, filter ReplicationTasksFilter) (sql.Result, error)
 		// DeleteFromReplicationTasks deletes multi rows from replication_tasks table
 		//  ReplicationTasksRangeFilter - {PageSize} will be ignored
 		RangeDeleteFromReplicationTasks(ctx context.Context, filter ReplicationTasksRangeFilter) (sql.Result, error)
+		// SelectStatsFromReplicationTasks returns the minimum and maximum task_id and the number of rows of a shard in
+		// replication_tasks table. The task IDs are 0 if the shard has no rows.
+		SelectStatsFromReplicationTasks(ctx context.Context, shardID int32) (ReplicationTasksStatsRow, error)
 	}
 )
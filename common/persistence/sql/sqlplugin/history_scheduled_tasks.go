@@ -39,6 +39,7 @@ type (
 		TaskID              int64
 		Data                []byte
 		DataEncoding        string
+		DataChecksum        *int64
 	}
 
 	// HistoryScheduledTasksFilter contains the column names within history_scheduled_tasks table that
@@ -38,6 +38,7 @@ type (
 		TaskID              int64
 		Data                []byte
 		DataEncoding        string
+		DataChecksum        *int64
 	}
 
 	// TimerTasksFilter contains the column names within timer_tasks table that
@@ -67,7 +68,11 @@ type (
 		// DeleteFromTimerTasks deletes one or more rows from timer_tasks table
 		DeleteFromTimerTasks(ctx context.Context, filter TimerTasksFilter) (sql.Result, error)
 		// RangeDeleteFromTimerTasks deletes one or more rows from timer_tasks table
-		//  TimerTasksRangeFilter - {TaskID, PageSize} will be ignored
+		//  TimerTasksRangeFilter - {TaskID} will be ignored, a positive PageSize limits the number of
+		//  rows deleted to the PageSize oldest rows of the range
 		RangeDeleteFromTimerTasks(ctx context.Context, filter TimerTasksRangeFilter) (sql.Result, error)
+		// SelectOldestFromTimerTasks returns the row of a shard in timer_tasks table with the smallest
+		// (visibility_timestamp, task_id), or sql.ErrNoRows if the shard has no timer tasks.
+		SelectOldestFromTimerTasks(ctx context.Context, shardID int32) (*TimerTasksRow, error)
 	}
 )
@@ -36,6 +36,7 @@ type (
 		TaskID       int64
 		Data         []byte
 		DataEncoding string
+		DataChecksum *int64
 	}
 
 	// TransferTasksFilter contains the column names within transfer_tasks table that
@@ -45,6 +46,12 @@ type (
 		TaskID  int64
 	}
 
+	// TransferTasksIDsFilter contains the shard and the task IDs of rows of transfer_tasks table
+	TransferTasksIDsFilter struct {
+		ShardID int32
+		TaskIDs []int64
+	}
+
 	// TransferTasksRangeFilter contains the column names within transfer_tasks table that
 	// can be used to filter results through a WHERE clause
 	TransferTasksRangeFilter struct {
@@ -54,6 +61,16 @@ type (
 		PageSize           int
 	}
 
+	// TransferTasksMultiShardRangeFilter contains the column names within transfer_tasks table that
+	// can be used to filter results of several shards through a WHERE clause
+	TransferTasksMultiShardRangeFilter struct {
+		ShardIDs           []int32
+		InclusiveMinTaskID int64
+		ExclusiveMaxTaskID int64
+		// PageSize is the maximum number of rows returned per shard
+		PageSize int
+	}
+
 	// HistoryTransferTask is the SQL persistence interface for history transfer tasks
 	HistoryTransferTask interface {
 		// InsertIntoTransferTasks inserts rows that into transfer_tasks table.
@@ -62,8 +79,17 @@ type (
 		RangeSelectFromTransferTasks(ctx context.Context, filter TransferTasksRangeFilter) ([]TransferTasksRow, error)
 		// DeleteFromTransferTasks deletes one rows from transfer_tasks table.
 		DeleteFromTransferTasks(ctx context.Context, filter TransferTasksFilter) (sql.Result, error)
+		// DeleteTaskIDsFromTransferTasks deletes the rows of a shard with the given task_ids from transfer_tasks table.
+		// Callers bound the number of task IDs, as they are sent as one parameter each.
+		DeleteTaskIDsFromTransferTasks(ctx context.Context, filter TransferTasksIDsFilter) (sql.Result, error)
 		// RangeDeleteFromTransferTasks deletes one or more rows from transfer_tasks table.
 		//  TransferTasksRangeFilter - {PageSize} will be ignored
 		RangeDeleteFromTransferTasks(ctx context.Context, filter TransferTasksRangeFilter) (sql.Result, error)
+		// RangeCountFromTransferTasks returns the number of rows in transfer_tasks table within the task ID range of filter.
+		//  TransferTasksRangeFilter - {PageSize} will be ignored
+		RangeCountFromTransferTasks(ctx context.Context, filter TransferTasksRangeFilter) (int64, error)
+		// RangeSelectFromTransferTasksMultiShard returns the rows of several shards in transfer_tasks table, ordered by
+		// shard ID and task ID and at most filter.PageSize of them per shard.
+		RangeSelectFromTransferTasksMultiShard(ctx context.Context, filter TransferTasksMultiShardRangeFilter) ([]TransferTasksRow, error)
 	}
 )
@@ -36,6 +36,7 @@ type (
 		TaskID       int64
 		Data         []byte
 		DataEncoding string
+		DataChecksum *int64
 	}
 
 	// VisibilityTasksFilter contains the column names within visibility_tasks table that
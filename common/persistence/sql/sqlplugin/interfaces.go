@@ -27,6 +27,7 @@ package sqlplugin
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"go.temporal.io/server/common/config"
@@ -93,6 +94,7 @@ type (
 		HistoryTimerTask
 		HistoryReplicationTask
 		HistoryReplicationDLQTask
+		HistoryTimerDLQTask
 		HistoryVisibilityTask
 	}
 
@@ -123,6 +125,22 @@ type (
 		GenericDB
 		BeginTx(ctx context.Context) (Tx, error)
 		IsDupEntryError(err error) bool
+		// IsConflictError returns true if err is a transient conflict with concurrent transactions, e.g. a deadlock
+		// or a serialization failure, so that the transaction can be retried
+		IsConflictError(err error) bool
+		// IsThrottlingError returns true if err is caused by the database running out of a resource, e.g.
+		// connections, so that the statement can be retried later
+		IsThrottlingError(err error) bool
+		// IsDataError returns true if err is a permanent failure caused by the data a statement writes, e.g. a
+		// value too long for its column, so that retrying the statement would fail again
+		IsDataError(err error) bool
+	}
+
+	// ReadOnlyDB is implemented by DBs that can route reads to a read replica
+	ReadOnlyDB interface {
+		// ReadOnly returns a DB for reads that may lag behind the primary, e.g. task scans. It returns the DB
+		// itself if no read replica is configured, or if the DB is a transaction.
+		ReadOnly() DB
 	}
 
 	// AdminDB defines the API for admin SQL operations for CLI and testing suites
@@ -137,6 +155,9 @@ type (
 		DbName() string
 		PluginName() string
 		Close() error
+		// SetSlowQueryThreshold makes the DB and its transactions log the queries that take longer than
+		// threshold, with the logger the DB was created with. Zero, the default, disables logging.
+		SetSlowQueryThreshold(threshold time.Duration)
 	}
 
 	// Conn defines the API for a single database connection
@@ -27,7 +27,9 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
@@ -48,6 +50,20 @@ const (
 	tooManyConnectionsCode = 1040
 	// Running in read-only mode
 	readOnlyModeCode = 1836
+	// Too many connections open by the user
+	tooManyUserConnectionsCode = 1203
+	// Lock wait timeout exceeded
+	lockWaitTimeoutCode = 1205
+	// Deadlock found when trying to get lock
+	deadlockCode = 1213
+	// Column cannot be null
+	badNullCode = 1048
+	// Out of range value for column
+	dataOutOfRangeCode = 1264
+	// Incorrect value for column
+	truncatedWrongValueCode = 1366
+	// Data too long for column
+	dataTooLongCode = 1406
 )
 
 // db represents a logical connection to mysql database
@@ -58,11 +74,15 @@ type db struct {
 	handle    *sqlplugin.DatabaseHandle
 	tx        *sqlx.Tx
 	converter DataConverter
+	// readReplica is nil if no read replica is configured
+	readReplica *db
+	slowQueries *sqlplugin.SlowQueryLogger
 }
 
 var _ sqlplugin.AdminDB = (*db)(nil)
 var _ sqlplugin.DB = (*db)(nil)
 var _ sqlplugin.Tx = (*db)(nil)
+var _ sqlplugin.ReadOnlyDB = (*db)(nil)
 
 func isConnNeedsRefreshError(err error) bool {
 	myErr, ok := err.(*mysql.MySQLError)
@@ -77,6 +97,29 @@ func (mdb *db) IsDupEntryError(err error) bool {
 	return ok && sqlErr.Number == ErrDupEntryCode
 }
 
+func (mdb *db) IsConflictError(err error) bool {
+	var sqlErr *mysql.MySQLError
+	return errors.As(err, &sqlErr) && (sqlErr.Number == deadlockCode || sqlErr.Number == lockWaitTimeoutCode)
+}
+
+func (mdb *db) IsThrottlingError(err error) bool {
+	var sqlErr *mysql.MySQLError
+	return errors.As(err, &sqlErr) && (sqlErr.Number == tooManyConnectionsCode || sqlErr.Number == tooManyUserConnectionsCode)
+}
+
+func (mdb *db) IsDataError(err error) bool {
+	var sqlErr *mysql.MySQLError
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.Number {
+	case badNullCode, dataOutOfRangeCode, truncatedWrongValueCode, dataTooLongCode:
+		return true
+	default:
+		return false
+	}
+}
+
 // newDB returns an instance of DB, which is a logical
 // connection to the underlying mysql database
 func newDB(
@@ -84,12 +127,14 @@ func newDB(
 	dbName string,
 	handle *sqlplugin.DatabaseHandle,
 	tx *sqlx.Tx,
+	slowQueries *sqlplugin.SlowQueryLogger,
 ) *db {
 	mdb := &db{
-		dbKind: dbKind,
-		dbName: dbName,
-		handle: handle,
-		tx:     tx,
+		dbKind:      dbKind,
+		dbName:      dbName,
+		handle:      handle,
+		tx:          tx,
+		slowQueries: slowQueries,
 	}
 	mdb.converter = &converter{}
 	return mdb
@@ -112,7 +157,7 @@ func (mdb *db) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
 	if err != nil {
 		return nil, mdb.handle.ConvertError(err)
 	}
-	return newDB(mdb.dbKind, mdb.dbName, mdb.handle, xtx), nil
+	return newDB(mdb.dbKind, mdb.dbName, mdb.handle, xtx, mdb.slowQueries), nil
 }
 
 // Commit commits a previously started transaction
@@ -125,9 +170,20 @@ func (mdb *db) Rollback() error {
 	return mdb.tx.Rollback()
 }
 
+// ReadOnly returns the read replica of the db, or the db itself if no read replica is configured
+func (mdb *db) ReadOnly() sqlplugin.DB {
+	if mdb.readReplica == nil || mdb.tx != nil {
+		return mdb
+	}
+	return mdb.readReplica
+}
+
 // Close closes the connection to the mysql db
 func (mdb *db) Close() error {
 	mdb.handle.Close()
+	if mdb.readReplica != nil {
+		mdb.readReplica.handle.Close()
+	}
 	return nil
 }
 
@@ -141,6 +197,11 @@ func (mdb *db) DbName() string {
 	return mdb.dbName
 }
 
+// SetSlowQueryThreshold sets the duration above which queries are logged
+func (mdb *db) SetSlowQueryThreshold(threshold time.Duration) {
+	mdb.slowQueries.SetThreshold(threshold)
+}
+
 // ExpectedVersion returns expected version.
 func (mdb *db) ExpectedVersion() string {
 	switch mdb.dbKind {
@@ -161,26 +222,31 @@ func (mdb *db) VerifyVersion() error {
 
 // Helper methods to hide common error handling
 func (mdb *db) ExecContext(ctx context.Context, stmt string, args ...any) (sql.Result, error) {
+	defer mdb.slowQueries.Observe(stmt, time.Now())
 	res, err := mdb.conn().ExecContext(ctx, stmt, args...)
 	return res, mdb.handle.ConvertError(err)
 }
 
 func (mdb *db) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	defer mdb.slowQueries.Observe(query, time.Now())
 	err := mdb.conn().GetContext(ctx, dest, query, args...)
 	return mdb.handle.ConvertError(err)
 }
 
 func (mdb *db) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	defer mdb.slowQueries.Observe(query, time.Now())
 	err := mdb.conn().SelectContext(ctx, dest, query, args...)
 	return mdb.handle.ConvertError(err)
 }
 
 func (mdb *db) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	defer mdb.slowQueries.Observe(query, time.Now())
 	res, err := mdb.conn().NamedExecContext(ctx, query, arg)
 	return res, mdb.handle.ConvertError(err)
 }
 
 func (mdb *db) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	defer mdb.slowQueries.Observe(query, time.Now())
 	stmt, err := mdb.conn().PrepareNamedContext(ctx, query)
 	return stmt, mdb.handle.ConvertError(err)
 }
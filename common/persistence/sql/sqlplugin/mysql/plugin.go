@@ -64,7 +64,17 @@ func (p *plugin) CreateDB(
 		return p.createDBConnection(dbKind, cfg, r)
 	}
 	handle := sqlplugin.NewDatabaseHandle(connect, isConnNeedsRefreshError, logger, metricsHandler, clock.NewRealTimeSource())
-	db := newDB(dbKind, cfg.DatabaseName, handle, nil)
+	slowQueries := sqlplugin.NewSlowQueryLogger(logger)
+	db := newDB(dbKind, cfg.DatabaseName, handle, nil, slowQueries)
+	if cfg.ReadReplicaConnectAddr != "" && cfg.Connect == nil {
+		replicaCfg := *cfg
+		replicaCfg.ConnectAddr = cfg.ReadReplicaConnectAddr
+		connectReplica := func() (*sqlx.DB, error) {
+			return p.createDBConnection(dbKind, &replicaCfg, r)
+		}
+		replicaHandle := sqlplugin.NewDatabaseHandle(connectReplica, isConnNeedsRefreshError, logger, metricsHandler, clock.NewRealTimeSource())
+		db.readReplica = newDB(dbKind, cfg.DatabaseName, replicaHandle, nil, slowQueries)
+	}
 	return db, nil
 }
 
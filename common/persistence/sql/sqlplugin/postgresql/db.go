@@ -28,6 +28,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"go.temporal.io/server/common/config"
@@ -42,6 +43,18 @@ func (pdb *db) IsDupEntryError(err error) bool {
 	return pdb.dbDriver.IsDupEntryError(err)
 }
 
+func (pdb *db) IsConflictError(err error) bool {
+	return pdb.dbDriver.IsConflictError(err)
+}
+
+func (pdb *db) IsThrottlingError(err error) bool {
+	return pdb.dbDriver.IsThrottlingError(err)
+}
+
+func (pdb *db) IsDataError(err error) bool {
+	return pdb.dbDriver.IsDataError(err)
+}
+
 func (pdb *db) IsDupDatabaseError(err error) bool {
 	return pdb.dbDriver.IsDupDatabaseError(err)
 }
@@ -59,9 +72,13 @@ type db struct {
 
 	handle *sqlplugin.DatabaseHandle
 	tx     *sqlx.Tx
+	// readReplica is nil if no read replica is configured
+	readReplica *db
+	slowQueries *sqlplugin.SlowQueryLogger
 }
 
 var _ sqlplugin.DB = (*db)(nil)
+var _ sqlplugin.ReadOnlyDB = (*db)(nil)
 
 // newDB returns an instance of DB, which is a logical
 // connection to the underlying postgresql database
@@ -71,13 +88,15 @@ func newDB(
 	dbDriver driver.Driver,
 	handle *sqlplugin.DatabaseHandle,
 	tx *sqlx.Tx,
+	slowQueries *sqlplugin.SlowQueryLogger,
 ) *db {
 	mdb := &db{
-		dbKind:   dbKind,
-		dbName:   dbName,
-		dbDriver: dbDriver,
-		handle:   handle,
-		tx:       tx,
+		dbKind:      dbKind,
+		dbName:      dbName,
+		dbDriver:    dbDriver,
+		handle:      handle,
+		tx:          tx,
+		slowQueries: slowQueries,
 	}
 	mdb.converter = &converter{}
 	return mdb
@@ -101,12 +120,23 @@ func (pdb *db) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
 	if err != nil {
 		return nil, pdb.handle.ConvertError(err)
 	}
-	return newDB(pdb.dbKind, pdb.dbName, pdb.dbDriver, pdb.handle, tx), nil
+	return newDB(pdb.dbKind, pdb.dbName, pdb.dbDriver, pdb.handle, tx, pdb.slowQueries), nil
+}
+
+// ReadOnly returns the read replica of the db, or the db itself if no read replica is configured
+func (pdb *db) ReadOnly() sqlplugin.DB {
+	if pdb.readReplica == nil || pdb.tx != nil {
+		return pdb
+	}
+	return pdb.readReplica
 }
 
 // Close closes the connection to the mysql db
 func (pdb *db) Close() error {
 	pdb.handle.Close()
+	if pdb.readReplica != nil {
+		pdb.readReplica.handle.Close()
+	}
 	return nil
 }
 
@@ -120,6 +150,11 @@ func (pdb *db) DbName() string {
 	return pdb.dbName
 }
 
+// SetSlowQueryThreshold sets the duration above which queries are logged
+func (pdb *db) SetSlowQueryThreshold(threshold time.Duration) {
+	pdb.slowQueries.SetThreshold(threshold)
+}
+
 // ExpectedVersion returns expected version.
 func (pdb *db) ExpectedVersion() string {
 	switch pdb.dbKind {
@@ -150,11 +185,13 @@ func (pdb *db) Rollback() error {
 
 // Helper methods to hide common error handling
 func (pdb *db) ExecContext(ctx context.Context, stmt string, args ...any) (sql.Result, error) {
+	defer pdb.slowQueries.Observe(stmt, time.Now())
 	res, err := pdb.conn().ExecContext(ctx, stmt, args...)
 	return res, pdb.handle.ConvertError(err)
 }
 
 func (pdb *db) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	defer pdb.slowQueries.Observe(query, time.Now())
 	err := pdb.conn().GetContext(ctx, dest, query, args...)
 	return pdb.handle.ConvertError(err)
 }
@@ -169,16 +206,19 @@ func (pdb *db) Select(dest any, query string, args ...any) error {
 }
 
 func (pdb *db) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	defer pdb.slowQueries.Observe(query, time.Now())
 	err := pdb.conn().SelectContext(ctx, dest, query, args...)
 	return pdb.handle.ConvertError(err)
 }
 
 func (pdb *db) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	defer pdb.slowQueries.Observe(query, time.Now())
 	res, err := pdb.conn().NamedExecContext(ctx, query, arg)
 	return res, pdb.handle.ConvertError(err)
 }
 
 func (pdb *db) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	defer pdb.slowQueries.Observe(query, time.Now())
 	stmt, err := pdb.conn().PrepareNamedContext(ctx, query)
 	return stmt, pdb.handle.ConvertError(err)
 }
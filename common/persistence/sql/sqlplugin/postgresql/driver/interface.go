@@ -25,16 +25,25 @@
 package driver
 
 import (
+	"strings"
+
 	"github.com/jmoiron/sqlx"
 )
 
 const (
 	// check http://www.postgresql.org/docs/9.3/static/errcodes-appendix.html
-	dupEntryCode            = "23505"
-	dupDatabaseCode         = "42P04"
-	readOnlyTransactionCode = "25006"
-	cannotConnectNowCode    = "57P03"
-	featureNotSupportedCode = "0A000"
+	dupEntryCode             = "23505"
+	dupDatabaseCode          = "42P04"
+	readOnlyTransactionCode  = "25006"
+	cannotConnectNowCode     = "57P03"
+	featureNotSupportedCode  = "0A000"
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+	lockNotAvailableCode     = "55P03"
+	tooManyConnectionsCode   = "53300"
+	notNullViolationCode     = "23502"
+	// class of data exceptions, e.g. 22001 string_data_right_truncation
+	dataExceptionClass = "22"
 
 	// Unsupported "feature" messages to look for
 	cannotSetReadWriteModeDuringRecoveryMsg = "cannot set transaction read-write mode during recovery"
@@ -45,6 +54,17 @@ type Driver interface {
 	IsDupEntryError(error) bool
 	IsDupDatabaseError(error) bool
 	IsConnNeedsRefreshError(error) bool
+	IsConflictError(error) bool
+	IsThrottlingError(error) bool
+	IsDataError(error) bool
+}
+
+func isConflictError(code string) bool {
+	return code == serializationFailureCode || code == deadlockDetectedCode || code == lockNotAvailableCode
+}
+
+func isDataError(code string) bool {
+	return strings.HasPrefix(code, dataExceptionClass) || code == notNullViolationCode
 }
 
 func isConnNeedsRefreshError(code, message string) bool {
@@ -25,6 +25,8 @@
 package driver
 
 import (
+	"errors"
+
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib" // register pgx driver for sqlx
 	"github.com/jmoiron/sqlx"
@@ -46,6 +48,21 @@ func (p *PGXDriver) IsDupDatabaseError(err error) bool {
 	return ok && pqErr.Code == dupDatabaseCode
 }
 
+func (p *PGXDriver) IsConflictError(err error) bool {
+	var pgxErr *pgconn.PgError
+	return errors.As(err, &pgxErr) && isConflictError(pgxErr.Code)
+}
+
+func (p *PGXDriver) IsThrottlingError(err error) bool {
+	var pgxErr *pgconn.PgError
+	return errors.As(err, &pgxErr) && pgxErr.Code == tooManyConnectionsCode
+}
+
+func (p *PGXDriver) IsDataError(err error) bool {
+	var pgxErr *pgconn.PgError
+	return errors.As(err, &pgxErr) && isDataError(pgxErr.Code)
+}
+
 func (p *PGXDriver) IsConnNeedsRefreshError(err error) bool {
 	pqErr, ok := err.(*pgconn.PgError)
 	if !ok {
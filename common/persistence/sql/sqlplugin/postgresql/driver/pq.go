@@ -25,6 +25,8 @@
 package driver
 
 import (
+	"errors"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
@@ -45,6 +47,21 @@ func (p *PQDriver) IsDupDatabaseError(err error) bool {
 	return ok && pqErr.Code == dupDatabaseCode
 }
 
+func (p *PQDriver) IsConflictError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && isConflictError(string(pqErr.Code))
+}
+
+func (p *PQDriver) IsThrottlingError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == tooManyConnectionsCode
+}
+
+func (p *PQDriver) IsDataError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && isDataError(string(pqErr.Code))
+}
+
 func (p *PQDriver) IsConnNeedsRefreshError(err error) bool {
 	pqErr, ok := err.(*pq.Error)
 	if !ok {
@@ -27,7 +27,9 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
+	"github.com/jmoiron/sqlx"
 	"go.temporal.io/server/common/persistence/sql/sqlplugin"
 )
 
@@ -86,19 +88,19 @@ namespace_id = :namespace_id AND
 workflow_id = :workflow_id
 `
 
-	createHistoryImmediateTasksQuery = `INSERT INTO history_immediate_tasks(shard_id, category_id, task_id, data, data_encoding) 
- VALUES(:shard_id, :category_id, :task_id, :data, :data_encoding)`
+	createHistoryImmediateTasksQuery = `INSERT INTO history_immediate_tasks(shard_id, category_id, task_id, data, data_encoding, data_checksum) 
+ VALUES(:shard_id, :category_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getHistoryImmediateTasksQuery = `SELECT task_id, data, data_encoding 
+	getHistoryImmediateTasksQuery = `SELECT task_id, data, data_encoding, data_checksum 
  FROM history_immediate_tasks WHERE shard_id = $1 AND category_id = $2 AND task_id >= $3 AND task_id < $4 ORDER BY task_id LIMIT $5`
 
 	deleteHistoryImmediateTaskQuery       = `DELETE FROM history_immediate_tasks WHERE shard_id = $1 AND category_id = $2 AND task_id = $3`
 	rangeDeleteHistoryImmediateTasksQuery = `DELETE FROM history_immediate_tasks WHERE shard_id = $1 AND category_id = $2 AND task_id >= $3 AND task_id < $4`
 
-	createHistoryScheduledTasksQuery = `INSERT INTO history_scheduled_tasks (shard_id, category_id, visibility_timestamp, task_id, data, data_encoding)
-  VALUES (:shard_id, :category_id, :visibility_timestamp, :task_id, :data, :data_encoding)`
+	createHistoryScheduledTasksQuery = `INSERT INTO history_scheduled_tasks (shard_id, category_id, visibility_timestamp, task_id, data, data_encoding, data_checksum)
+  VALUES (:shard_id, :category_id, :visibility_timestamp, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getHistoryScheduledTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding FROM history_scheduled_tasks 
+	getHistoryScheduledTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM history_scheduled_tasks 
   WHERE shard_id = $1 
   AND category_id = $2 
   AND ((visibility_timestamp >= $3 AND task_id >= $4) OR visibility_timestamp > $5) 
@@ -108,19 +110,28 @@ workflow_id = :workflow_id
 	deleteHistoryScheduledTaskQuery       = `DELETE FROM history_scheduled_tasks WHERE shard_id = $1 AND category_id = $2 AND visibility_timestamp = $3 AND task_id = $4`
 	rangeDeleteHistoryScheduledTasksQuery = `DELETE FROM history_scheduled_tasks WHERE shard_id = $1 AND category_id = $2 AND visibility_timestamp >= $3 AND visibility_timestamp < $4`
 
-	createTransferTasksQuery = `INSERT INTO transfer_tasks(shard_id, task_id, data, data_encoding) 
- VALUES(:shard_id, :task_id, :data, :data_encoding)`
+	createTransferTasksQuery = `INSERT INTO transfer_tasks(shard_id, task_id, data, data_encoding, data_checksum) 
+ VALUES(:shard_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getTransferTasksQuery = `SELECT task_id, data, data_encoding 
+	getTransferTasksQuery = `SELECT task_id, data, data_encoding, data_checksum 
  FROM transfer_tasks WHERE shard_id = $1 AND task_id >= $2 AND task_id < $3 ORDER BY task_id LIMIT $4`
 
 	deleteTransferTaskQuery      = `DELETE FROM transfer_tasks WHERE shard_id = $1 AND task_id = $2`
 	rangeDeleteTransferTaskQuery = `DELETE FROM transfer_tasks WHERE shard_id = $1 AND task_id >= $2 AND task_id < $3`
+	deleteTransferTaskIDsQuery   = `DELETE FROM transfer_tasks WHERE shard_id = ? AND task_id IN ( ? )`
 
-	createTimerTasksQuery = `INSERT INTO timer_tasks (shard_id, visibility_timestamp, task_id, data, data_encoding)
-  VALUES (:shard_id, :visibility_timestamp, :task_id, :data, :data_encoding)`
+	getTransferTasksCountQuery = `SELECT COUNT(*) FROM transfer_tasks WHERE shard_id = $1 AND task_id >= $2 AND task_id < $3`
 
-	getTimerTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding FROM timer_tasks 
+	// NOTE: sqlx only support ? when doing `sqlx.In` expanding query
+	getTransferTasksMultiShardQuery = `SELECT shard_id, task_id, data, data_encoding, data_checksum FROM (
+ SELECT shard_id, task_id, data, data_encoding, data_checksum, ROW_NUMBER() OVER (PARTITION BY shard_id ORDER BY task_id) AS row_num
+ FROM transfer_tasks WHERE shard_id IN ( ? ) AND task_id >= ? AND task_id < ?
+) AS shard_tasks WHERE row_num <= ? ORDER BY shard_id, task_id`
+
+	createTimerTasksQuery = `INSERT INTO timer_tasks (shard_id, visibility_timestamp, task_id, data, data_encoding, data_checksum)
+  VALUES (:shard_id, :visibility_timestamp, :task_id, :data, :data_encoding, :data_checksum)`
+
+	getTimerTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM timer_tasks 
   WHERE shard_id = $1 
   AND ((visibility_timestamp >= $2 AND task_id >= $3) OR visibility_timestamp > $4) 
   AND visibility_timestamp < $5
@@ -128,27 +139,41 @@ workflow_id = :workflow_id
 
 	deleteTimerTaskQuery      = `DELETE FROM timer_tasks WHERE shard_id = $1 AND visibility_timestamp = $2 AND task_id = $3`
 	rangeDeleteTimerTaskQuery = `DELETE FROM timer_tasks WHERE shard_id = $1 AND visibility_timestamp >= $2 AND visibility_timestamp < $3`
+	// rangeDeleteTimerTaskLimitQuery deletes at most the given number of the oldest timer tasks of the range,
+	// as PostgreSQL doesn't support DELETE ... LIMIT
+	rangeDeleteTimerTaskLimitQuery = `DELETE FROM timer_tasks WHERE shard_id = $1 AND (visibility_timestamp, task_id) IN (` +
+		`SELECT visibility_timestamp, task_id FROM timer_tasks WHERE shard_id = $1 AND visibility_timestamp >= $2 AND visibility_timestamp < $3 ` +
+		`ORDER BY visibility_timestamp, task_id LIMIT $4)`
+
+	getOldestTimerTaskQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM timer_tasks
+  WHERE shard_id = $1 ORDER BY visibility_timestamp, task_id LIMIT 1`
 
-	createReplicationTasksQuery = `INSERT INTO replication_tasks (shard_id, task_id, data, data_encoding) 
-  VALUES(:shard_id, :task_id, :data, :data_encoding)`
+	createReplicationTasksQuery = `INSERT INTO replication_tasks (shard_id, task_id, data, data_encoding, data_checksum) 
+  VALUES(:shard_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getReplicationTasksQuery = `SELECT task_id, data, data_encoding FROM replication_tasks WHERE 
+	getReplicationTasksQuery = `SELECT task_id, data, data_encoding, data_checksum FROM replication_tasks WHERE 
+shard_id = $1 AND task_id >= $2 AND task_id < $3 ORDER BY task_id LIMIT $4`
+
+	rangeGetReplicationTaskIDsQuery = `SELECT task_id FROM replication_tasks WHERE 
 shard_id = $1 AND task_id >= $2 AND task_id < $3 ORDER BY task_id LIMIT $4`
 
 	deleteReplicationTaskQuery      = `DELETE FROM replication_tasks WHERE shard_id = $1 AND task_id = $2`
 	rangeDeleteReplicationTaskQuery = `DELETE FROM replication_tasks WHERE shard_id = $1 AND task_id >= $2 AND task_id < $3`
 
-	getReplicationTasksDLQQuery = `SELECT task_id, data, data_encoding FROM replication_tasks_dlq WHERE 
+	getReplicationTasksStatsQuery = `SELECT COALESCE(MIN(task_id), 0) AS min_task_id, COALESCE(MAX(task_id), 0) AS max_task_id, COUNT(*) AS task_count
+ FROM replication_tasks WHERE shard_id = $1`
+
+	getReplicationTasksDLQQuery = `SELECT task_id, data, data_encoding, data_checksum, reason FROM replication_tasks_dlq WHERE 
 source_cluster_name = $1 AND
 shard_id = $2 AND
 task_id >= $3 AND
 task_id < $4
 ORDER BY task_id LIMIT $5`
 
-	createVisibilityTasksQuery = `INSERT INTO visibility_tasks(shard_id, task_id, data, data_encoding) 
- VALUES(:shard_id, :task_id, :data, :data_encoding)`
+	createVisibilityTasksQuery = `INSERT INTO visibility_tasks(shard_id, task_id, data, data_encoding, data_checksum) 
+ VALUES(:shard_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getVisibilityTasksQuery = `SELECT task_id, data, data_encoding 
+	getVisibilityTasksQuery = `SELECT task_id, data, data_encoding, data_checksum 
  FROM visibility_tasks WHERE shard_id = $1 AND task_id >= $2 AND task_id < $3 ORDER BY task_id LIMIT $4`
 
 	deleteVisibilityTaskQuery      = `DELETE FROM visibility_tasks WHERE shard_id = $1 AND task_id = $2`
@@ -167,12 +192,16 @@ INSERT INTO replication_tasks_dlq
              shard_id, 
              task_id, 
              data, 
-             data_encoding) 
+             data_encoding, 
+             reason, 
+             data_checksum) 
 VALUES     (:source_cluster_name, 
             :shard_id, 
             :task_id, 
             :data, 
-            :data_encoding)
+            :data_encoding, 
+            :reason, 
+            :data_checksum)
 `
 	deleteReplicationTaskFromDLQQuery = `
 	DELETE FROM replication_tasks_dlq 
@@ -186,6 +215,44 @@ VALUES     (:source_cluster_name,
 		AND shard_id = $2 
 		AND task_id >= $3
 		AND task_id < $4`
+
+	getReplicationTasksDLQMinTaskIDQuery = `SELECT source_cluster_name, MIN(task_id) AS min_task_id
+ FROM replication_tasks_dlq WHERE shard_id = $1 GROUP BY source_cluster_name`
+
+	getReplicationTasksDLQReasonCountsQuery = `SELECT COALESCE(reason, '') AS reason, COUNT(*) AS task_count
+ FROM replication_tasks_dlq WHERE shard_id = $1 AND source_cluster_name = $2 GROUP BY reason`
+
+	getReplicationTasksDLQCountQuery = `SELECT COUNT(*) FROM replication_tasks_dlq
+ WHERE source_cluster_name = $1 AND shard_id = $2 AND task_id >= $3 AND task_id < $4`
+
+	// %s is the archive table name
+	createReplicationDLQArchiveTableQuery = `CREATE TABLE IF NOT EXISTS %s (
+  source_cluster_name VARCHAR(255) NOT NULL,
+  shard_id INTEGER NOT NULL,
+  task_id BIGINT NOT NULL,
+  data BYTEA NOT NULL,
+  data_encoding VARCHAR(16) NOT NULL,
+  reason VARCHAR(255),
+  data_checksum BIGINT,
+  PRIMARY KEY (source_cluster_name, shard_id, task_id)
+)`
+
+	copyIntoReplicationDLQArchiveQuery = `INSERT INTO %s (source_cluster_name, shard_id, task_id, data, data_encoding, reason, data_checksum)
+ SELECT source_cluster_name, shard_id, task_id, data, data_encoding, reason, data_checksum FROM replication_tasks_dlq WHERE shard_id = $1`
+
+	deleteShardFromReplicationDLQQuery = `DELETE FROM replication_tasks_dlq WHERE shard_id = $1`
+
+	createTimerDLQTasksQuery = `INSERT INTO timer_tasks_dlq (shard_id, visibility_timestamp, task_id, data, data_encoding, data_checksum)
+  VALUES (:shard_id, :visibility_timestamp, :task_id, :data, :data_encoding, :data_checksum)`
+
+	getTimerDLQTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM timer_tasks_dlq 
+  WHERE shard_id = $1 
+  AND ((visibility_timestamp >= $2 AND task_id >= $3) OR visibility_timestamp > $4) 
+  AND visibility_timestamp < $5
+  ORDER BY visibility_timestamp,task_id LIMIT $6`
+
+	deleteTimerDLQTaskQuery      = `DELETE FROM timer_tasks_dlq WHERE shard_id = $1 AND visibility_timestamp = $2 AND task_id = $3`
+	rangeDeleteTimerDLQTaskQuery = `DELETE FROM timer_tasks_dlq WHERE shard_id = $1 AND visibility_timestamp >= $2 AND visibility_timestamp < $3`
 )
 
 // InsertIntoExecutions inserts a row into executions table
@@ -537,6 +604,21 @@ func (pdb *db) DeleteFromTransferTasks(
 	)
 }
 
+// DeleteTaskIDsFromTransferTasks deletes the rows of a shard with the given task_ids from transfer_tasks table
+func (pdb *db) DeleteTaskIDsFromTransferTasks(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksIDsFilter,
+) (sql.Result, error) {
+	query, args, err := sqlx.In(deleteTransferTaskIDsQuery, filter.ShardID, filter.TaskIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pdb.ExecContext(ctx,
+		pdb.Rebind(query),
+		args...,
+	)
+}
+
 // RangeDeleteFromTransferTasks deletes one or more rows from transfer_tasks table
 func (pdb *db) RangeDeleteFromTransferTasks(
 	ctx context.Context,
@@ -550,6 +632,46 @@ func (pdb *db) RangeDeleteFromTransferTasks(
 	)
 }
 
+// RangeCountFromTransferTasks returns the number of rows of a task ID range of a shard in transfer_tasks table
+func (pdb *db) RangeCountFromTransferTasks(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksRangeFilter,
+) (int64, error) {
+	var count int64
+	err := pdb.GetContext(ctx,
+		&count,
+		getTransferTasksCountQuery,
+		filter.ShardID,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+	)
+	return count, err
+}
+
+// RangeSelectFromTransferTasksMultiShard reads rows of several shards from transfer_tasks table
+func (pdb *db) RangeSelectFromTransferTasksMultiShard(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksMultiShardRangeFilter,
+) ([]sqlplugin.TransferTasksRow, error) {
+	query, args, err := sqlx.In(
+		getTransferTasksMultiShardQuery,
+		filter.ShardIDs,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+		filter.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var rows []sqlplugin.TransferTasksRow
+	err = pdb.SelectContext(ctx,
+		&rows,
+		pdb.Rebind(query),
+		args...,
+	)
+	return rows, err
+}
+
 // InsertIntoTimerTasks inserts one or more rows into timer_tasks table
 func (pdb *db) InsertIntoTimerTasks(
 	ctx context.Context,
@@ -612,6 +734,15 @@ func (pdb *db) RangeDeleteFromTimerTasks(
 ) (sql.Result, error) {
 	filter.InclusiveMinVisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.InclusiveMinVisibilityTimestamp)
 	filter.ExclusiveMaxVisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.ExclusiveMaxVisibilityTimestamp)
+	if filter.PageSize > 0 {
+		return pdb.ExecContext(ctx,
+			rangeDeleteTimerTaskLimitQuery,
+			filter.ShardID,
+			filter.InclusiveMinVisibilityTimestamp,
+			filter.ExclusiveMaxVisibilityTimestamp,
+			filter.PageSize,
+		)
+	}
 	return pdb.ExecContext(ctx,
 		rangeDeleteTimerTaskQuery,
 		filter.ShardID,
@@ -620,6 +751,24 @@ func (pdb *db) RangeDeleteFromTimerTasks(
 	)
 }
 
+// SelectOldestFromTimerTasks returns the oldest row of a shard in timer_tasks table
+func (pdb *db) SelectOldestFromTimerTasks(
+	ctx context.Context,
+	shardID int32,
+) (*sqlplugin.TimerTasksRow, error) {
+	var row sqlplugin.TimerTasksRow
+	if err := pdb.GetContext(ctx,
+		&row,
+		getOldestTimerTaskQuery,
+		shardID,
+	); err != nil {
+		return nil, err
+	}
+	row.ShardID = shardID
+	row.VisibilityTimestamp = pdb.converter.FromPostgreSQLDateTime(row.VisibilityTimestamp)
+	return &row, nil
+}
+
 // InsertIntoBufferedEvents inserts one or more rows into buffered_events table
 func (pdb *db) InsertIntoBufferedEvents(
 	ctx context.Context,
@@ -698,6 +847,23 @@ func (pdb *db) RangeSelectFromReplicationTasks(
 	return rows, err
 }
 
+// RangeSelectTaskIDsFromReplicationTasks reads the task_ids of one or more rows from replication_tasks table
+func (pdb *db) RangeSelectTaskIDsFromReplicationTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationTasksRangeFilter,
+) ([]int64, error) {
+	var taskIDs []int64
+	err := pdb.SelectContext(ctx,
+		&taskIDs,
+		rangeGetReplicationTaskIDsQuery,
+		filter.ShardID,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+		filter.PageSize,
+	)
+	return taskIDs, err
+}
+
 // DeleteFromReplicationTasks deletes one rows from replication_tasks table
 func (pdb *db) DeleteFromReplicationTasks(
 	ctx context.Context,
@@ -723,6 +889,20 @@ func (pdb *db) RangeDeleteFromReplicationTasks(
 	)
 }
 
+// SelectStatsFromReplicationTasks returns the task_id range and the number of rows of a shard in replication_tasks table
+func (pdb *db) SelectStatsFromReplicationTasks(
+	ctx context.Context,
+	shardID int32,
+) (sqlplugin.ReplicationTasksStatsRow, error) {
+	var row sqlplugin.ReplicationTasksStatsRow
+	err := pdb.GetContext(ctx,
+		&row,
+		getReplicationTasksStatsQuery,
+		shardID,
+	)
+	return row, err
+}
+
 // InsertIntoReplicationDLQTasks inserts one or more rows into replication_tasks_dlq table
 func (pdb *db) InsertIntoReplicationDLQTasks(
 	ctx context.Context,
@@ -780,6 +960,170 @@ func (pdb *db) RangeDeleteFromReplicationDLQTasks(
 	)
 }
 
+// SelectMinTaskIDFromReplicationDLQTasks returns the minimum task ID of a shard's rows in replication_tasks_dlq table,
+// grouped by source cluster
+func (pdb *db) SelectMinTaskIDFromReplicationDLQTasks(
+	ctx context.Context,
+	shardID int32,
+) ([]sqlplugin.ReplicationDLQTasksMinTaskIDRow, error) {
+	var rows []sqlplugin.ReplicationDLQTasksMinTaskIDRow
+	if err := pdb.SelectContext(ctx,
+		&rows,
+		getReplicationTasksDLQMinTaskIDQuery,
+		shardID,
+	); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SelectReasonCountsFromReplicationDLQTasks returns the number of rows of a shard and source cluster in
+// replication_tasks_dlq table, grouped by reason
+func (pdb *db) SelectReasonCountsFromReplicationDLQTasks(
+	ctx context.Context,
+	shardID int32,
+	sourceClusterName string,
+) ([]sqlplugin.ReplicationDLQTasksReasonCountRow, error) {
+	var rows []sqlplugin.ReplicationDLQTasksReasonCountRow
+	err := pdb.SelectContext(ctx,
+		&rows,
+		getReplicationTasksDLQReasonCountsQuery,
+		shardID,
+		sourceClusterName,
+	)
+	return rows, err
+}
+
+// SelectCountFromReplicationDLQTasks returns the number of rows in replication_tasks_dlq table within the range
+func (pdb *db) SelectCountFromReplicationDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationDLQTasksRangeFilter,
+) (int64, error) {
+	var count int64
+	err := pdb.GetContext(ctx,
+		&count,
+		getReplicationTasksDLQCountQuery,
+		filter.SourceClusterName,
+		filter.ShardID,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+	)
+	return count, err
+}
+
+// CreateReplicationDLQArchiveTable creates the replication DLQ archive table with the given suffix if it doesn't exist
+func (pdb *db) CreateReplicationDLQArchiveTable(
+	ctx context.Context,
+	archiveTableSuffix string,
+) error {
+	tableName, err := sqlplugin.ReplicationDLQArchiveTableName(archiveTableSuffix)
+	if err != nil {
+		return err
+	}
+	_, err = pdb.ExecContext(ctx,
+		fmt.Sprintf(createReplicationDLQArchiveTableQuery, tableName),
+	)
+	return err
+}
+
+// CopyIntoReplicationDLQArchive copies all rows of a shard in replication_tasks_dlq table into the archive table
+// with the given suffix
+func (pdb *db) CopyIntoReplicationDLQArchive(
+	ctx context.Context,
+	shardID int32,
+	archiveTableSuffix string,
+) (sql.Result, error) {
+	tableName, err := sqlplugin.ReplicationDLQArchiveTableName(archiveTableSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return pdb.ExecContext(ctx,
+		fmt.Sprintf(copyIntoReplicationDLQArchiveQuery, tableName),
+		shardID,
+	)
+}
+
+// DeleteShardFromReplicationDLQTasks deletes all rows of a shard from replication_tasks_dlq table
+func (pdb *db) DeleteShardFromReplicationDLQTasks(
+	ctx context.Context,
+	shardID int32,
+) (sql.Result, error) {
+	return pdb.ExecContext(ctx,
+		deleteShardFromReplicationDLQQuery,
+		shardID,
+	)
+}
+
+// InsertIntoTimerDLQTasks inserts one or more rows into timer_tasks_dlq table
+func (pdb *db) InsertIntoTimerDLQTasks(
+	ctx context.Context,
+	rows []sqlplugin.TimerDLQTasksRow,
+) (sql.Result, error) {
+	for i := range rows {
+		rows[i].VisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(rows[i].VisibilityTimestamp)
+	}
+	return pdb.NamedExecContext(ctx,
+		createTimerDLQTasksQuery,
+		rows,
+	)
+}
+
+// RangeSelectFromTimerDLQTasks reads one or more rows from timer_tasks_dlq table
+func (pdb *db) RangeSelectFromTimerDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerDLQTasksRangeFilter,
+) ([]sqlplugin.TimerDLQTasksRow, error) {
+	var rows []sqlplugin.TimerDLQTasksRow
+	filter.InclusiveMinVisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.InclusiveMinVisibilityTimestamp)
+	filter.ExclusiveMaxVisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.ExclusiveMaxVisibilityTimestamp)
+	err := pdb.SelectContext(ctx,
+		&rows,
+		getTimerDLQTasksQuery,
+		filter.ShardID,
+		filter.InclusiveMinVisibilityTimestamp,
+		filter.InclusiveMinTaskID,
+		filter.InclusiveMinVisibilityTimestamp,
+		filter.ExclusiveMaxVisibilityTimestamp,
+		filter.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].VisibilityTimestamp = pdb.converter.FromPostgreSQLDateTime(rows[i].VisibilityTimestamp)
+	}
+	return rows, nil
+}
+
+// DeleteFromTimerDLQTasks deletes one or more rows from timer_tasks_dlq table
+func (pdb *db) DeleteFromTimerDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerDLQTasksFilter,
+) (sql.Result, error) {
+	filter.VisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.VisibilityTimestamp)
+	return pdb.ExecContext(ctx,
+		deleteTimerDLQTaskQuery,
+		filter.ShardID,
+		filter.VisibilityTimestamp,
+		filter.TaskID,
+	)
+}
+
+// RangeDeleteFromTimerDLQTasks deletes one or more rows from timer_tasks_dlq table
+func (pdb *db) RangeDeleteFromTimerDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerDLQTasksRangeFilter,
+) (sql.Result, error) {
+	filter.InclusiveMinVisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.InclusiveMinVisibilityTimestamp)
+	filter.ExclusiveMaxVisibilityTimestamp = pdb.converter.ToPostgreSQLDateTime(filter.ExclusiveMaxVisibilityTimestamp)
+	return pdb.ExecContext(ctx,
+		rangeDeleteTimerDLQTaskQuery,
+		filter.ShardID,
+		filter.InclusiveMinVisibilityTimestamp,
+		filter.ExclusiveMaxVisibilityTimestamp,
+	)
+}
+
 // InsertIntoVisibilityTasks inserts one or more rows into visibility_tasks table
 func (pdb *db) InsertIntoVisibilityTasks(
 	ctx context.Context,
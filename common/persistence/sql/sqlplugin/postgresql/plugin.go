@@ -81,7 +81,17 @@ func (d *plugin) CreateDB(
 	}
 	needsRefresh := d.d.IsConnNeedsRefreshError
 	handle := sqlplugin.NewDatabaseHandle(connect, needsRefresh, logger, metricsHandler, clock.NewRealTimeSource())
-	db := newDB(dbKind, cfg.DatabaseName, d.d, handle, nil)
+	slowQueries := sqlplugin.NewSlowQueryLogger(logger)
+	db := newDB(dbKind, cfg.DatabaseName, d.d, handle, nil, slowQueries)
+	if cfg.ReadReplicaConnectAddr != "" && cfg.Connect == nil {
+		replicaCfg := *cfg
+		replicaCfg.ConnectAddr = cfg.ReadReplicaConnectAddr
+		connectReplica := func() (*sqlx.DB, error) {
+			return d.createDBConnection(&replicaCfg, r)
+		}
+		replicaHandle := sqlplugin.NewDatabaseHandle(connectReplica, needsRefresh, logger, metricsHandler, clock.NewRealTimeSource())
+		db.readReplica = newDB(dbKind, cfg.DatabaseName, d.d, replicaHandle, nil, slowQueries)
+	}
 	return db, nil
 }
 
@@ -0,0 +1,120 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sqlplugin
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+)
+
+// slowQueryMaxLength is the maximum length of the queries logged by SlowQueryLogger, longer queries are truncated
+const slowQueryMaxLength = 1024
+
+// SlowQueryLogger logs the queries of a DB that take longer than a threshold. It is shared by the DB and its
+// transactions, so that setting the threshold applies to all of them. Logging is disabled until a threshold is
+// set. A nil SlowQueryLogger logs nothing.
+type SlowQueryLogger struct {
+	logger    log.Logger
+	threshold atomic.Int64
+}
+
+// slowQueryConn is a Conn whose queries are observed by a SlowQueryLogger
+type slowQueryConn struct {
+	Conn
+	slowQueries *SlowQueryLogger
+}
+
+func NewSlowQueryLogger(logger log.Logger) *SlowQueryLogger {
+	return &SlowQueryLogger{logger: logger}
+}
+
+// SetThreshold sets the duration above which queries are logged, zero disables logging
+func (l *SlowQueryLogger) SetThreshold(threshold time.Duration) {
+	if l == nil {
+		return
+	}
+	l.threshold.Store(int64(threshold))
+}
+
+// Observe logs query if it took longer than the threshold since startTime. It is meant to be deferred with
+// time.Now() as startTime.
+func (l *SlowQueryLogger) Observe(query string, startTime time.Time) {
+	if l == nil {
+		return
+	}
+	threshold := time.Duration(l.threshold.Load())
+	if threshold <= 0 {
+		return
+	}
+	elapsed := time.Since(startTime)
+	if elapsed < threshold {
+		return
+	}
+	if len(query) > slowQueryMaxLength {
+		query = query[:slowQueryMaxLength]
+	}
+	l.logger.Warn("Slow persistence query",
+		tag.NewStringTag("query", query),
+		tag.NewDurationTag("elapsed", elapsed),
+		tag.NewDurationTag("threshold", threshold),
+	)
+}
+
+// Conn returns conn with its queries observed by l
+func (l *SlowQueryLogger) Conn(conn Conn) Conn {
+	if l == nil {
+		return conn
+	}
+	return &slowQueryConn{Conn: conn, slowQueries: l}
+}
+
+func (c *slowQueryConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer c.slowQueries.Observe(query, time.Now())
+	return c.Conn.ExecContext(ctx, query, args...)
+}
+
+func (c *slowQueryConn) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	defer c.slowQueries.Observe(query, time.Now())
+	return c.Conn.NamedExecContext(ctx, query, arg)
+}
+
+func (c *slowQueryConn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer c.slowQueries.Observe(query, time.Now())
+	return c.Conn.GetContext(ctx, dest, query, args...)
+}
+
+func (c *slowQueryConn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer c.slowQueries.Observe(query, time.Now())
+	return c.Conn.SelectContext(ctx, dest, query, args...)
+}
+
+func (c *slowQueryConn) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	defer c.slowQueries.Observe(query, time.Now())
+	return c.Conn.PrepareNamedContext(ctx, query)
+}
@@ -0,0 +1,77 @@
+// The MIT License
+//
+// Copyright (c) 2024 Temporal Technologies Inc.  All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sqlplugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"go.uber.org/mock/gomock"
+)
+
+type sleepingConn struct {
+	Conn
+	delay time.Duration
+}
+
+func (c *sleepingConn) SelectContext(context.Context, any, string, ...any) error {
+	time.Sleep(c.delay)
+	return nil
+}
+
+func TestSlowQueryLogger(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	logger := log.NewMockLogger(ctrl)
+	slowQueries := NewSlowQueryLogger(logger)
+	conn := slowQueries.Conn(&sleepingConn{delay: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	// disabled by default
+	require.NoError(t, conn.SelectContext(ctx, nil, "SELECT 1"))
+
+	slowQueries.SetThreshold(time.Hour)
+	require.NoError(t, conn.SelectContext(ctx, nil, "SELECT 1"))
+
+	var loggedQueries []string
+	logger.EXPECT().Warn("Slow persistence query", gomock.Any()).Do(func(_ string, tags ...tag.Tag) {
+		require.Equal(t, "query", tags[0].Key())
+		loggedQueries = append(loggedQueries, tags[0].Value().(string))
+	}).Times(2)
+	slowQueries.SetThreshold(time.Millisecond)
+	require.NoError(t, conn.SelectContext(ctx, nil, "SELECT 1"))
+	// long queries are truncated
+	require.NoError(t, conn.SelectContext(ctx, nil, "SELECT "+strings.Repeat("1, ", slowQueryMaxLength)+"1"))
+	require.Len(t, loggedQueries, 2)
+	require.Equal(t, "SELECT 1", loggedQueries[0])
+	require.Len(t, loggedQueries[1], slowQueryMaxLength)
+
+	// nil loggers observe nothing
+	var nilSlowQueries *SlowQueryLogger
+	nilSlowQueries.SetThreshold(time.Millisecond)
+	require.NoError(t, nilSlowQueries.Conn(&sleepingConn{delay: 10 * time.Millisecond}).SelectContext(ctx, nil, "SELECT 1"))
+}
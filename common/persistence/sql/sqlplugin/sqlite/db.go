@@ -30,6 +30,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"go.temporal.io/server/common/persistence/sql/sqlplugin"
@@ -48,6 +49,8 @@ type db struct {
 	tx        *sqlx.Tx
 	conn      sqlplugin.Conn
 	converter DataConverter
+	// slowQueries observes the queries run through conn
+	slowQueries *sqlplugin.SlowQueryLogger
 }
 
 var _ sqlplugin.AdminDB = (*db)(nil)
@@ -61,17 +64,19 @@ func newDB(
 	dbName string,
 	xdb *sqlx.DB,
 	tx *sqlx.Tx,
+	slowQueries *sqlplugin.SlowQueryLogger,
 ) *db {
 	mdb := &db{
-		dbKind:  dbKind,
-		dbName:  dbName,
-		onClose: make([]func(), 0),
-		db:      xdb,
-		tx:      tx,
+		dbKind:      dbKind,
+		dbName:      dbName,
+		onClose:     make([]func(), 0),
+		db:          xdb,
+		tx:          tx,
+		slowQueries: slowQueries,
 	}
-	mdb.conn = xdb
+	mdb.conn = slowQueries.Conn(xdb)
 	if tx != nil {
-		mdb.conn = tx
+		mdb.conn = slowQueries.Conn(tx)
 	}
 	mdb.converter = &converter{}
 	return mdb
@@ -83,7 +88,7 @@ func (mdb *db) BeginTx(ctx context.Context) (sqlplugin.Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newDB(mdb.dbKind, mdb.dbName, mdb.db, xtx), nil
+	return newDB(mdb.dbKind, mdb.dbName, mdb.db, xtx, mdb.slowQueries), nil
 }
 
 // Commit commits a previously started transaction
@@ -126,6 +131,11 @@ func (mdb *db) DbName() string {
 	return mdb.dbName
 }
 
+// SetSlowQueryThreshold sets the duration above which queries are logged
+func (mdb *db) SetSlowQueryThreshold(threshold time.Duration) {
+	mdb.slowQueries.SetThreshold(threshold)
+}
+
 // ExpectedVersion returns expected version.
 func (mdb *db) ExpectedVersion() string {
 	switch mdb.dbKind {
@@ -38,6 +38,8 @@ const (
 	goSqlDriverName       = "sqlite"
 	sqlConstraintCodes    = sqlite3.SQLITE_CONSTRAINT | sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY | sqlite3.SQLITE_CONSTRAINT_UNIQUE
 	sqlTableExistsPattern = "SQL logic error: table .* already exists \\(1\\)"
+	// extended result codes keep the primary result code in their lowest byte
+	sqlPrimaryCodeMask = 0xff
 )
 
 var sqlTableExistsRegex = regexp.MustCompile(sqlTableExistsPattern)
@@ -51,6 +53,30 @@ func (*db) IsDupEntryError(err error) bool {
 	return false
 }
 
+// IsConflictError returns true for SQLITE_LOCKED, a conflict with another connection of the same process
+func (*db) IsConflictError(err error) bool {
+	var sqlErr *sqlite.Error
+	return errors.As(err, &sqlErr) && sqlErr.Code()&sqlPrimaryCodeMask == sqlite3.SQLITE_LOCKED
+}
+
+// IsThrottlingError returns true for SQLITE_BUSY, which is returned once the busy timeout expired while the
+// database file was locked by another connection
+func (*db) IsThrottlingError(err error) bool {
+	var sqlErr *sqlite.Error
+	return errors.As(err, &sqlErr) && sqlErr.Code()&sqlPrimaryCodeMask == sqlite3.SQLITE_BUSY
+}
+
+// IsDataError returns true for SQLITE_TOOBIG and SQLITE_MISMATCH, which are returned for values too large for
+// the database or of the wrong type
+func (*db) IsDataError(err error) bool {
+	var sqlErr *sqlite.Error
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	code := sqlErr.Code() & sqlPrimaryCodeMask
+	return code == sqlite3.SQLITE_TOOBIG || code == sqlite3.SQLITE_MISMATCH
+}
+
 func isTableExistsError(err error) bool {
 	var sqlErr *sqlite.Error
 	if errors.As(err, &sqlErr) {
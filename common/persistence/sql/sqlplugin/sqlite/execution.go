@@ -29,7 +29,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
+	"github.com/jmoiron/sqlx"
 	"go.temporal.io/server/common/persistence/sql/sqlplugin"
 )
 
@@ -88,19 +90,19 @@ namespace_id = :namespace_id AND
 workflow_id = :workflow_id
 `
 
-	createHistoryImmediateTasksQuery = `INSERT INTO history_immediate_tasks(shard_id, category_id, task_id, data, data_encoding) 
- VALUES(:shard_id, :category_id, :task_id, :data, :data_encoding)`
+	createHistoryImmediateTasksQuery = `INSERT INTO history_immediate_tasks(shard_id, category_id, task_id, data, data_encoding, data_checksum) 
+ VALUES(:shard_id, :category_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getHistoryImmediateTasksQuery = `SELECT task_id, data, data_encoding 
+	getHistoryImmediateTasksQuery = `SELECT task_id, data, data_encoding, data_checksum 
  FROM history_immediate_tasks WHERE shard_id = ? AND category_id = ? AND task_id >= ? AND task_id < ? ORDER BY task_id LIMIT ?`
 
 	deleteHistoryImmediateTaskQuery       = `DELETE FROM history_immediate_tasks WHERE shard_id = ? AND category_id = ? AND task_id = ?`
 	rangeDeleteHistoryImmediateTasksQuery = `DELETE FROM history_immediate_tasks WHERE shard_id = ? AND category_id = ? AND task_id >= ? AND task_id < ?`
 
-	createHistoryScheduledTasksQuery = `INSERT INTO history_scheduled_tasks (shard_id, category_id, visibility_timestamp, task_id, data, data_encoding)
-  VALUES (:shard_id, :category_id, :visibility_timestamp, :task_id, :data, :data_encoding)`
+	createHistoryScheduledTasksQuery = `INSERT INTO history_scheduled_tasks (shard_id, category_id, visibility_timestamp, task_id, data, data_encoding, data_checksum)
+  VALUES (:shard_id, :category_id, :visibility_timestamp, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getHistoryScheduledTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding FROM history_scheduled_tasks 
+	getHistoryScheduledTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM history_scheduled_tasks 
   WHERE shard_id = ? 
   AND category_id = ? 
   AND ((visibility_timestamp >= ? AND task_id >= ?) OR visibility_timestamp > ?) 
@@ -110,19 +112,27 @@ workflow_id = :workflow_id
 	deleteHistoryScheduledTaskQuery       = `DELETE FROM history_scheduled_tasks WHERE shard_id = ? AND category_id = ? AND visibility_timestamp = ? AND task_id = ?`
 	rangeDeleteHistoryScheduledTasksQuery = `DELETE FROM history_scheduled_tasks WHERE shard_id = ? AND category_id = ? AND visibility_timestamp >= ? AND visibility_timestamp < ?`
 
-	createTransferTasksQuery = `INSERT INTO transfer_tasks(shard_id, task_id, data, data_encoding) 
- VALUES(:shard_id, :task_id, :data, :data_encoding)`
+	createTransferTasksQuery = `INSERT INTO transfer_tasks(shard_id, task_id, data, data_encoding, data_checksum) 
+ VALUES(:shard_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getTransferTasksQuery = `SELECT task_id, data, data_encoding 
+	getTransferTasksQuery = `SELECT task_id, data, data_encoding, data_checksum 
  FROM transfer_tasks WHERE shard_id = ? AND task_id >= ? AND task_id < ? ORDER BY task_id LIMIT ?`
 
 	deleteTransferTaskQuery      = `DELETE FROM transfer_tasks WHERE shard_id = ? AND task_id = ?`
 	rangeDeleteTransferTaskQuery = `DELETE FROM transfer_tasks WHERE shard_id = ? AND task_id >= ? AND task_id < ?`
+	deleteTransferTaskIDsQuery   = `DELETE FROM transfer_tasks WHERE shard_id = ? AND task_id IN ( ? )`
 
-	createTimerTasksQuery = `INSERT INTO timer_tasks (shard_id, visibility_timestamp, task_id, data, data_encoding)
-  VALUES (:shard_id, :visibility_timestamp, :task_id, :data, :data_encoding)`
+	getTransferTasksCountQuery = `SELECT COUNT(*) FROM transfer_tasks WHERE shard_id = ? AND task_id >= ? AND task_id < ?`
 
-	getTimerTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding FROM timer_tasks 
+	getTransferTasksMultiShardQuery = `SELECT shard_id, task_id, data, data_encoding, data_checksum FROM (
+ SELECT shard_id, task_id, data, data_encoding, data_checksum, ROW_NUMBER() OVER (PARTITION BY shard_id ORDER BY task_id) AS row_num
+ FROM transfer_tasks WHERE shard_id IN ( ? ) AND task_id >= ? AND task_id < ?
+) AS shard_tasks WHERE row_num <= ? ORDER BY shard_id, task_id`
+
+	createTimerTasksQuery = `INSERT INTO timer_tasks (shard_id, visibility_timestamp, task_id, data, data_encoding, data_checksum)
+  VALUES (:shard_id, :visibility_timestamp, :task_id, :data, :data_encoding, :data_checksum)`
+
+	getTimerTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM timer_tasks 
   WHERE shard_id = ? 
   AND ((visibility_timestamp >= ? AND task_id >= ?) OR visibility_timestamp > ?) 
   AND visibility_timestamp < ?
@@ -130,27 +140,41 @@ workflow_id = :workflow_id
 
 	deleteTimerTaskQuery      = `DELETE FROM timer_tasks WHERE shard_id = ? AND visibility_timestamp = ? AND task_id = ?`
 	rangeDeleteTimerTaskQuery = `DELETE FROM timer_tasks WHERE shard_id = ? AND visibility_timestamp >= ? AND visibility_timestamp < ?`
+	// rangeDeleteTimerTaskLimitQuery deletes at most the given number of the oldest timer tasks of the range,
+	// as DELETE ... LIMIT is only available in SQLite builds with SQLITE_ENABLE_UPDATE_DELETE_LIMIT
+	rangeDeleteTimerTaskLimitQuery = `DELETE FROM timer_tasks WHERE shard_id = ? AND (visibility_timestamp, task_id) IN (` +
+		`SELECT visibility_timestamp, task_id FROM timer_tasks WHERE shard_id = ? AND visibility_timestamp >= ? AND visibility_timestamp < ? ` +
+		`ORDER BY visibility_timestamp, task_id LIMIT ?)`
+
+	getOldestTimerTaskQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM timer_tasks
+  WHERE shard_id = ? ORDER BY visibility_timestamp, task_id LIMIT 1`
 
-	createReplicationTasksQuery = `INSERT INTO replication_tasks (shard_id, task_id, data, data_encoding) 
-  VALUES(:shard_id, :task_id, :data, :data_encoding)`
+	createReplicationTasksQuery = `INSERT INTO replication_tasks (shard_id, task_id, data, data_encoding, data_checksum) 
+  VALUES(:shard_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getReplicationTasksQuery = `SELECT task_id, data, data_encoding FROM replication_tasks WHERE 
+	getReplicationTasksQuery = `SELECT task_id, data, data_encoding, data_checksum FROM replication_tasks WHERE 
+shard_id = ? AND task_id >= ? AND task_id < ? ORDER BY task_id LIMIT ?`
+
+	rangeGetReplicationTaskIDsQuery = `SELECT task_id FROM replication_tasks WHERE 
 shard_id = ? AND task_id >= ? AND task_id < ? ORDER BY task_id LIMIT ?`
 
 	deleteReplicationTaskQuery      = `DELETE FROM replication_tasks WHERE shard_id = ? AND task_id = ?`
 	rangeDeleteReplicationTaskQuery = `DELETE FROM replication_tasks WHERE shard_id = ? AND task_id >= ? AND task_id < ?`
 
-	getReplicationTasksDLQQuery = `SELECT task_id, data, data_encoding FROM replication_tasks_dlq WHERE 
+	getReplicationTasksStatsQuery = `SELECT COALESCE(MIN(task_id), 0) AS min_task_id, COALESCE(MAX(task_id), 0) AS max_task_id, COUNT(*) AS task_count
+ FROM replication_tasks WHERE shard_id = ?`
+
+	getReplicationTasksDLQQuery = `SELECT task_id, data, data_encoding, data_checksum, reason FROM replication_tasks_dlq WHERE 
 source_cluster_name = ? AND
 shard_id = ? AND
 task_id >= ? AND
 task_id < ?
 ORDER BY task_id LIMIT ?`
 
-	createVisibilityTasksQuery = `INSERT INTO visibility_tasks(shard_id, task_id, data, data_encoding) 
- VALUES(:shard_id, :task_id, :data, :data_encoding)`
+	createVisibilityTasksQuery = `INSERT INTO visibility_tasks(shard_id, task_id, data, data_encoding, data_checksum) 
+ VALUES(:shard_id, :task_id, :data, :data_encoding, :data_checksum)`
 
-	getVisibilityTasksQuery = `SELECT task_id, data, data_encoding 
+	getVisibilityTasksQuery = `SELECT task_id, data, data_encoding, data_checksum 
  FROM visibility_tasks WHERE shard_id = ? AND task_id >= ? AND task_id < ? ORDER BY task_id LIMIT ?`
 
 	deleteVisibilityTaskQuery      = `DELETE FROM visibility_tasks WHERE shard_id = ? AND task_id = ?`
@@ -170,12 +194,16 @@ INSERT INTO replication_tasks_dlq
              shard_id, 
              task_id, 
              data, 
-             data_encoding) 
+             data_encoding, 
+             reason, 
+             data_checksum) 
 VALUES     (:source_cluster_name, 
             :shard_id, 
             :task_id, 
             :data, 
-            :data_encoding)
+            :data_encoding, 
+            :reason, 
+            :data_checksum)
 `
 	deleteReplicationTaskFromDLQQuery = `
 	DELETE FROM replication_tasks_dlq 
@@ -189,6 +217,44 @@ VALUES     (:source_cluster_name,
 		AND shard_id = ? 
 		AND task_id >= ?
 		AND task_id < ?`
+
+	getReplicationTasksDLQMinTaskIDQuery = `SELECT source_cluster_name, MIN(task_id) AS min_task_id
+ FROM replication_tasks_dlq WHERE shard_id = ? GROUP BY source_cluster_name`
+
+	getReplicationTasksDLQReasonCountsQuery = `SELECT COALESCE(reason, '') AS reason, COUNT(*) AS task_count
+ FROM replication_tasks_dlq WHERE shard_id = ? AND source_cluster_name = ? GROUP BY reason`
+
+	getReplicationTasksDLQCountQuery = `SELECT COUNT(*) FROM replication_tasks_dlq
+ WHERE source_cluster_name = ? AND shard_id = ? AND task_id >= ? AND task_id < ?`
+
+	// %s is the archive table name
+	createReplicationDLQArchiveTableQuery = `CREATE TABLE IF NOT EXISTS %s (
+  source_cluster_name VARCHAR(255) NOT NULL,
+  shard_id INT NOT NULL,
+  task_id BIGINT NOT NULL,
+  data MEDIUMBLOB NOT NULL,
+  data_encoding VARCHAR(16) NOT NULL,
+  reason VARCHAR(255),
+  data_checksum BIGINT,
+  PRIMARY KEY (source_cluster_name, shard_id, task_id)
+)`
+
+	copyIntoReplicationDLQArchiveQuery = `INSERT INTO %s (source_cluster_name, shard_id, task_id, data, data_encoding, reason, data_checksum)
+ SELECT source_cluster_name, shard_id, task_id, data, data_encoding, reason, data_checksum FROM replication_tasks_dlq WHERE shard_id = ?`
+
+	deleteShardFromReplicationDLQQuery = `DELETE FROM replication_tasks_dlq WHERE shard_id = ?`
+
+	createTimerDLQTasksQuery = `INSERT INTO timer_tasks_dlq (shard_id, visibility_timestamp, task_id, data, data_encoding, data_checksum)
+  VALUES (:shard_id, :visibility_timestamp, :task_id, :data, :data_encoding, :data_checksum)`
+
+	getTimerDLQTasksQuery = `SELECT visibility_timestamp, task_id, data, data_encoding, data_checksum FROM timer_tasks_dlq 
+  WHERE shard_id = ? 
+  AND ((visibility_timestamp >= ? AND task_id >= ?) OR visibility_timestamp > ?) 
+  AND visibility_timestamp < ?
+  ORDER BY visibility_timestamp,task_id LIMIT ?`
+
+	deleteTimerDLQTaskQuery      = `DELETE FROM timer_tasks_dlq WHERE shard_id = ? AND visibility_timestamp = ? AND task_id = ?`
+	rangeDeleteTimerDLQTaskQuery = `DELETE FROM timer_tasks_dlq WHERE shard_id = ? AND visibility_timestamp >= ? AND visibility_timestamp < ?`
 )
 
 // InsertIntoExecutions inserts a row into executions table
@@ -538,6 +604,21 @@ func (mdb *db) DeleteFromTransferTasks(
 	)
 }
 
+// DeleteTaskIDsFromTransferTasks deletes the rows of a shard with the given task_ids from transfer_tasks table
+func (mdb *db) DeleteTaskIDsFromTransferTasks(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksIDsFilter,
+) (sql.Result, error) {
+	query, args, err := sqlx.In(deleteTransferTaskIDsQuery, filter.ShardID, filter.TaskIDs)
+	if err != nil {
+		return nil, err
+	}
+	return mdb.conn.ExecContext(ctx,
+		mdb.conn.Rebind(query),
+		args...,
+	)
+}
+
 // RangeDeleteFromTransferTasks deletes one or more rows from transfer_tasks table
 func (mdb *db) RangeDeleteFromTransferTasks(
 	ctx context.Context,
@@ -551,6 +632,46 @@ func (mdb *db) RangeDeleteFromTransferTasks(
 	)
 }
 
+// RangeCountFromTransferTasks returns the number of rows of a task ID range of a shard in transfer_tasks table
+func (mdb *db) RangeCountFromTransferTasks(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksRangeFilter,
+) (int64, error) {
+	var count int64
+	err := mdb.conn.GetContext(ctx,
+		&count,
+		getTransferTasksCountQuery,
+		filter.ShardID,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+	)
+	return count, err
+}
+
+// RangeSelectFromTransferTasksMultiShard reads rows of several shards from transfer_tasks table
+func (mdb *db) RangeSelectFromTransferTasksMultiShard(
+	ctx context.Context,
+	filter sqlplugin.TransferTasksMultiShardRangeFilter,
+) ([]sqlplugin.TransferTasksRow, error) {
+	query, args, err := sqlx.In(
+		getTransferTasksMultiShardQuery,
+		filter.ShardIDs,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+		filter.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var rows []sqlplugin.TransferTasksRow
+	err = mdb.conn.SelectContext(ctx,
+		&rows,
+		mdb.conn.Rebind(query),
+		args...,
+	)
+	return rows, err
+}
+
 // InsertIntoTimerTasks inserts one or more rows into timer_tasks table
 func (mdb *db) InsertIntoTimerTasks(
 	ctx context.Context,
@@ -613,6 +734,16 @@ func (mdb *db) RangeDeleteFromTimerTasks(
 ) (sql.Result, error) {
 	filter.InclusiveMinVisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.InclusiveMinVisibilityTimestamp)
 	filter.ExclusiveMaxVisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.ExclusiveMaxVisibilityTimestamp)
+	if filter.PageSize > 0 {
+		return mdb.conn.ExecContext(ctx,
+			rangeDeleteTimerTaskLimitQuery,
+			filter.ShardID,
+			filter.ShardID,
+			filter.InclusiveMinVisibilityTimestamp,
+			filter.ExclusiveMaxVisibilityTimestamp,
+			filter.PageSize,
+		)
+	}
 	return mdb.conn.ExecContext(ctx,
 		rangeDeleteTimerTaskQuery,
 		filter.ShardID,
@@ -621,6 +752,24 @@ func (mdb *db) RangeDeleteFromTimerTasks(
 	)
 }
 
+// SelectOldestFromTimerTasks returns the oldest row of a shard in timer_tasks table
+func (mdb *db) SelectOldestFromTimerTasks(
+	ctx context.Context,
+	shardID int32,
+) (*sqlplugin.TimerTasksRow, error) {
+	var row sqlplugin.TimerTasksRow
+	if err := mdb.conn.GetContext(ctx,
+		&row,
+		getOldestTimerTaskQuery,
+		shardID,
+	); err != nil {
+		return nil, err
+	}
+	row.ShardID = shardID
+	row.VisibilityTimestamp = mdb.converter.FromSQLiteDateTime(row.VisibilityTimestamp)
+	return &row, nil
+}
+
 // InsertIntoBufferedEvents inserts one or more rows into buffered_events table
 func (mdb *db) InsertIntoBufferedEvents(
 	ctx context.Context,
@@ -699,6 +848,23 @@ func (mdb *db) RangeSelectFromReplicationTasks(
 	return rows, err
 }
 
+// RangeSelectTaskIDsFromReplicationTasks reads the task_ids of one or more rows from replication_tasks table
+func (mdb *db) RangeSelectTaskIDsFromReplicationTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationTasksRangeFilter,
+) ([]int64, error) {
+	var taskIDs []int64
+	err := mdb.conn.SelectContext(ctx,
+		&taskIDs,
+		rangeGetReplicationTaskIDsQuery,
+		filter.ShardID,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+		filter.PageSize,
+	)
+	return taskIDs, err
+}
+
 // DeleteFromReplicationTasks deletes one row from replication_tasks table
 func (mdb *db) DeleteFromReplicationTasks(
 	ctx context.Context,
@@ -724,6 +890,20 @@ func (mdb *db) RangeDeleteFromReplicationTasks(
 	)
 }
 
+// SelectStatsFromReplicationTasks returns the task_id range and the number of rows of a shard in replication_tasks table
+func (mdb *db) SelectStatsFromReplicationTasks(
+	ctx context.Context,
+	shardID int32,
+) (sqlplugin.ReplicationTasksStatsRow, error) {
+	var row sqlplugin.ReplicationTasksStatsRow
+	err := mdb.conn.GetContext(ctx,
+		&row,
+		getReplicationTasksStatsQuery,
+		shardID,
+	)
+	return row, err
+}
+
 // InsertIntoReplicationDLQTasks inserts one or more rows into replication_tasks_dlq table
 func (mdb *db) InsertIntoReplicationDLQTasks(
 	ctx context.Context,
@@ -781,6 +961,170 @@ func (mdb *db) RangeDeleteFromReplicationDLQTasks(
 	)
 }
 
+// SelectMinTaskIDFromReplicationDLQTasks returns the minimum task ID of a shard's rows in replication_tasks_dlq table,
+// grouped by source cluster
+func (mdb *db) SelectMinTaskIDFromReplicationDLQTasks(
+	ctx context.Context,
+	shardID int32,
+) ([]sqlplugin.ReplicationDLQTasksMinTaskIDRow, error) {
+	var rows []sqlplugin.ReplicationDLQTasksMinTaskIDRow
+	if err := mdb.conn.SelectContext(ctx,
+		&rows,
+		getReplicationTasksDLQMinTaskIDQuery,
+		shardID,
+	); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// SelectReasonCountsFromReplicationDLQTasks returns the number of rows of a shard and source cluster in
+// replication_tasks_dlq table, grouped by reason
+func (mdb *db) SelectReasonCountsFromReplicationDLQTasks(
+	ctx context.Context,
+	shardID int32,
+	sourceClusterName string,
+) ([]sqlplugin.ReplicationDLQTasksReasonCountRow, error) {
+	var rows []sqlplugin.ReplicationDLQTasksReasonCountRow
+	err := mdb.conn.SelectContext(ctx,
+		&rows,
+		getReplicationTasksDLQReasonCountsQuery,
+		shardID,
+		sourceClusterName,
+	)
+	return rows, err
+}
+
+// SelectCountFromReplicationDLQTasks returns the number of rows in replication_tasks_dlq table within the range
+func (mdb *db) SelectCountFromReplicationDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.ReplicationDLQTasksRangeFilter,
+) (int64, error) {
+	var count int64
+	err := mdb.conn.GetContext(ctx,
+		&count,
+		getReplicationTasksDLQCountQuery,
+		filter.SourceClusterName,
+		filter.ShardID,
+		filter.InclusiveMinTaskID,
+		filter.ExclusiveMaxTaskID,
+	)
+	return count, err
+}
+
+// CreateReplicationDLQArchiveTable creates the replication DLQ archive table with the given suffix if it doesn't exist
+func (mdb *db) CreateReplicationDLQArchiveTable(
+	ctx context.Context,
+	archiveTableSuffix string,
+) error {
+	tableName, err := sqlplugin.ReplicationDLQArchiveTableName(archiveTableSuffix)
+	if err != nil {
+		return err
+	}
+	_, err = mdb.conn.ExecContext(ctx,
+		fmt.Sprintf(createReplicationDLQArchiveTableQuery, tableName),
+	)
+	return err
+}
+
+// CopyIntoReplicationDLQArchive copies all rows of a shard in replication_tasks_dlq table into the archive table
+// with the given suffix
+func (mdb *db) CopyIntoReplicationDLQArchive(
+	ctx context.Context,
+	shardID int32,
+	archiveTableSuffix string,
+) (sql.Result, error) {
+	tableName, err := sqlplugin.ReplicationDLQArchiveTableName(archiveTableSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return mdb.conn.ExecContext(ctx,
+		fmt.Sprintf(copyIntoReplicationDLQArchiveQuery, tableName),
+		shardID,
+	)
+}
+
+// DeleteShardFromReplicationDLQTasks deletes all rows of a shard from replication_tasks_dlq table
+func (mdb *db) DeleteShardFromReplicationDLQTasks(
+	ctx context.Context,
+	shardID int32,
+) (sql.Result, error) {
+	return mdb.conn.ExecContext(ctx,
+		deleteShardFromReplicationDLQQuery,
+		shardID,
+	)
+}
+
+// InsertIntoTimerDLQTasks inserts one or more rows into timer_tasks_dlq table
+func (mdb *db) InsertIntoTimerDLQTasks(
+	ctx context.Context,
+	rows []sqlplugin.TimerDLQTasksRow,
+) (sql.Result, error) {
+	for i := range rows {
+		rows[i].VisibilityTimestamp = mdb.converter.ToSQLiteDateTime(rows[i].VisibilityTimestamp)
+	}
+	return mdb.conn.NamedExecContext(
+		ctx,
+		createTimerDLQTasksQuery,
+		rows,
+	)
+}
+
+// RangeSelectFromTimerDLQTasks reads one or more rows from timer_tasks_dlq table
+func (mdb *db) RangeSelectFromTimerDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerDLQTasksRangeFilter,
+) ([]sqlplugin.TimerDLQTasksRow, error) {
+	var rows []sqlplugin.TimerDLQTasksRow
+	filter.InclusiveMinVisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.InclusiveMinVisibilityTimestamp)
+	filter.ExclusiveMaxVisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.ExclusiveMaxVisibilityTimestamp)
+	if err := mdb.conn.SelectContext(ctx,
+		&rows,
+		getTimerDLQTasksQuery,
+		filter.ShardID,
+		filter.InclusiveMinVisibilityTimestamp,
+		filter.InclusiveMinTaskID,
+		filter.InclusiveMinVisibilityTimestamp,
+		filter.ExclusiveMaxVisibilityTimestamp,
+		filter.PageSize,
+	); err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].VisibilityTimestamp = mdb.converter.FromSQLiteDateTime(rows[i].VisibilityTimestamp)
+	}
+	return rows, nil
+}
+
+// DeleteFromTimerDLQTasks deletes one or more rows from timer_tasks_dlq table
+func (mdb *db) DeleteFromTimerDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerDLQTasksFilter,
+) (sql.Result, error) {
+	filter.VisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.VisibilityTimestamp)
+	return mdb.conn.ExecContext(ctx,
+		deleteTimerDLQTaskQuery,
+		filter.ShardID,
+		filter.VisibilityTimestamp,
+		filter.TaskID,
+	)
+}
+
+// RangeDeleteFromTimerDLQTasks deletes one or more rows from timer_tasks_dlq table
+func (mdb *db) RangeDeleteFromTimerDLQTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerDLQTasksRangeFilter,
+) (sql.Result, error) {
+	filter.InclusiveMinVisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.InclusiveMinVisibilityTimestamp)
+	filter.ExclusiveMaxVisibilityTimestamp = mdb.converter.ToSQLiteDateTime(filter.ExclusiveMaxVisibilityTimestamp)
+	return mdb.conn.ExecContext(ctx,
+		rangeDeleteTimerDLQTaskQuery,
+		filter.ShardID,
+		filter.InclusiveMinVisibilityTimestamp,
+		filter.ExclusiveMaxVisibilityTimestamp,
+	)
+}
+
 // InsertIntoVisibilityTasks inserts one or more rows into visibility_tasks table
 func (mdb *db) InsertIntoVisibilityTasks(
 	ctx context.Context,
@@ -78,14 +78,14 @@ func (p *plugin) CreateDB(
 	dbKind sqlplugin.DbKind,
 	cfg *config.SQL,
 	r resolver.ServiceResolver,
-	_ log.Logger,
+	logger log.Logger,
 	_ metrics.Handler,
 ) (sqlplugin.GenericDB, error) {
 	conn, err := p.connPool.Allocate(cfg, r, p.createDBConnection)
 	if err != nil {
 		return nil, err
 	}
-	db := newDB(dbKind, cfg.DatabaseName, conn, nil)
+	db := newDB(dbKind, cfg.DatabaseName, conn, nil, sqlplugin.NewSlowQueryLogger(logger))
 	db.OnClose(func() { p.connPool.Close(cfg) }) // remove reference
 	return db, nil
 }
@@ -140,7 +140,7 @@ func (p *plugin) createDBConnection(
 }
 
 func (p *plugin) setupSQLiteDatabase(cfg *config.SQL, conn *sqlx.DB) error {
-	db := newDB(sqlplugin.DbKindUnknown, cfg.DatabaseName, conn, nil)
+	db := newDB(sqlplugin.DbKindUnknown, cfg.DatabaseName, conn, nil, nil)
 	defer func() { _ = db.Close() }()
 
 	err := db.CreateDatabase(cfg.DatabaseName)
@@ -0,0 +1,371 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tests
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/common/shuffle"
+)
+
+type (
+	historyHistoryTimerDLQTaskSuite struct {
+		suite.Suite
+		*require.Assertions
+
+		store sqlplugin.HistoryTimerDLQTask
+	}
+)
+
+const (
+	testHistoryTimerDLQTaskEncoding = "random encoding"
+)
+
+var (
+	testHistoryTimerDLQTaskData = []byte("random history timer DLQ task data")
+)
+
+func NewHistoryTimerDLQTaskSuite(
+	t *testing.T,
+	store sqlplugin.HistoryTimerDLQTask,
+) *historyHistoryTimerDLQTaskSuite {
+	return &historyHistoryTimerDLQTaskSuite{
+		Assertions: require.New(t),
+		store:      store,
+	}
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) SetupSuite() {
+
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TearDownSuite() {
+
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) SetupTest() {
+	s.Assertions = require.New(s.T())
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TearDownTest() {
+
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsert_Single_Success() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	task := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(1, int(rowsAffected))
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsert_Multiple_Success() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	task1 := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	timestamp = timestamp.Add(time.Millisecond)
+	taskID++
+	task2 := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task1, task2})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(2, int(rowsAffected))
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsert_Single_Fail_Duplicate() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	task := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(1, int(rowsAffected))
+
+	task = s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	_, err = s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task})
+	s.Error(err) // TODO persistence layer should do proper error translation
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsert_Multiple_Fail_Duplicate() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	task1 := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	timestamp = timestamp.Add(time.Millisecond)
+	taskID++
+	task2 := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task1, task2})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(2, int(rowsAffected))
+
+	task2 = s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	timestamp = timestamp.Add(time.Millisecond)
+	taskID++
+	task3 := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	_, err = s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task2, task3})
+	s.Error(err) // TODO persistence layer should do proper error translation
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsertSelect_Single() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	task := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(1, int(rowsAffected))
+
+	rangeFilter := sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinTaskID:              taskID,
+		InclusiveMinVisibilityTimestamp: timestamp,
+		ExclusiveMaxVisibilityTimestamp: timestamp.Add(persistence.ScheduledTaskMinPrecision),
+		PageSize:                        1,
+	}
+	rows, err := s.store.RangeSelectFromTimerDLQTasks(newExecutionContext(), rangeFilter)
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal([]sqlplugin.TimerDLQTasksRow{task}, rows)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsertSelect_Multiple() {
+	numTasks := 20
+
+	shardID := rand.Int31()
+	timestamp := s.now()
+	minTimestamp := timestamp
+	taskID := int64(1)
+	maxTimestamp := timestamp.Add(time.Duration(numTasks) * time.Millisecond)
+
+	var tasks []sqlplugin.TimerDLQTasksRow
+	for i := 0; i < numTasks; i++ {
+		task := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+		timestamp = timestamp.Add(time.Millisecond)
+		taskID++
+		tasks = append(tasks, task)
+	}
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), tasks)
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(numTasks, int(rowsAffected))
+
+	filter := sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: minTimestamp,
+		ExclusiveMaxVisibilityTimestamp: maxTimestamp,
+		PageSize:                        numTasks,
+	}
+	rows, err := s.store.RangeSelectFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal(tasks, rows)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestDeleteSelect_Single() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	filter := sqlplugin.TimerDLQTasksFilter{
+		ShardID:             shardID,
+		VisibilityTimestamp: timestamp,
+		TaskID:              taskID,
+	}
+	result, err := s.store.DeleteFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(0, int(rowsAffected))
+
+	rangeFilter := sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinTaskID:              taskID,
+		InclusiveMinVisibilityTimestamp: timestamp,
+		ExclusiveMaxVisibilityTimestamp: timestamp.Add(persistence.ScheduledTaskMinPrecision),
+		PageSize:                        1,
+	}
+	rows, err := s.store.RangeSelectFromTimerDLQTasks(newExecutionContext(), rangeFilter)
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal([]sqlplugin.TimerDLQTasksRow(nil), rows)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestDeleteSelect_Multiple() {
+	pageSize := 100
+
+	shardID := rand.Int31()
+	minTimestamp := s.now()
+	maxTimestamp := minTimestamp.Add(time.Minute)
+
+	filter := sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: minTimestamp,
+		ExclusiveMaxVisibilityTimestamp: maxTimestamp,
+		PageSize:                        0,
+	}
+	result, err := s.store.RangeDeleteFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(0, int(rowsAffected))
+
+	filter.PageSize = pageSize
+	rows, err := s.store.RangeSelectFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal([]sqlplugin.TimerDLQTasksRow(nil), rows)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsertDeleteSelect_Single() {
+	shardID := rand.Int31()
+	timestamp := s.now()
+	taskID := int64(1)
+
+	task := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), []sqlplugin.TimerDLQTasksRow{task})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(1, int(rowsAffected))
+
+	filter := sqlplugin.TimerDLQTasksFilter{
+		ShardID:             shardID,
+		VisibilityTimestamp: timestamp,
+		TaskID:              taskID,
+	}
+	result, err = s.store.DeleteFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	rowsAffected, err = result.RowsAffected()
+	s.NoError(err)
+	s.Equal(1, int(rowsAffected))
+
+	rangeFilter := sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinTaskID:              taskID,
+		InclusiveMinVisibilityTimestamp: timestamp,
+		ExclusiveMaxVisibilityTimestamp: timestamp.Add(persistence.ScheduledTaskMinPrecision),
+		PageSize:                        1,
+	}
+	rows, err := s.store.RangeSelectFromTimerDLQTasks(newExecutionContext(), rangeFilter)
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal([]sqlplugin.TimerDLQTasksRow(nil), rows)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) TestInsertDeleteSelect_Multiple() {
+	numTasks := 20
+	pageSize := numTasks
+
+	shardID := rand.Int31()
+	timestamp := s.now()
+	minTimestamp := timestamp
+	taskID := int64(1)
+	maxTimestamp := timestamp.Add(time.Duration(numTasks) * time.Millisecond)
+
+	var tasks []sqlplugin.TimerDLQTasksRow
+	for i := 0; i < numTasks; i++ {
+		task := s.newRandomTimerDLQTaskRow(shardID, timestamp, taskID)
+		timestamp = timestamp.Add(time.Millisecond)
+		taskID++
+		tasks = append(tasks, task)
+	}
+	result, err := s.store.InsertIntoTimerDLQTasks(newExecutionContext(), tasks)
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(numTasks, int(rowsAffected))
+
+	filter := sqlplugin.TimerDLQTasksRangeFilter{
+		ShardID:                         shardID,
+		InclusiveMinVisibilityTimestamp: minTimestamp,
+		ExclusiveMaxVisibilityTimestamp: maxTimestamp,
+		PageSize:                        0,
+	}
+	result, err = s.store.RangeDeleteFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	rowsAffected, err = result.RowsAffected()
+	s.NoError(err)
+	s.Equal(numTasks, int(rowsAffected))
+
+	filter.PageSize = pageSize
+	rows, err := s.store.RangeSelectFromTimerDLQTasks(newExecutionContext(), filter)
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal([]sqlplugin.TimerDLQTasksRow(nil), rows)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func (s *historyHistoryTimerDLQTaskSuite) newRandomTimerDLQTaskRow(
+	shardID int32,
+	timestamp time.Time,
+	taskID int64,
+) sqlplugin.TimerDLQTasksRow {
+	return sqlplugin.TimerDLQTasksRow{
+		ShardID:             shardID,
+		VisibilityTimestamp: timestamp,
+		TaskID:              taskID,
+		Data:                shuffle.Bytes(testHistoryTimerDLQTaskData),
+		DataEncoding:        testHistoryTimerDLQTaskEncoding,
+	}
+}
@@ -330,6 +330,39 @@ func (s *historyHistoryTransferTaskSuite) TestInsertDeleteSelect_Multiple() {
 	s.Equal([]sqlplugin.TransferTasksRow(nil), rows)
 }
 
+func (s *historyHistoryTransferTaskSuite) TestInsertDeleteTaskIDsSelect() {
+	numTasks := 5
+
+	shardID := rand.Int31()
+	var tasks []sqlplugin.TransferTasksRow
+	for taskID := int64(1); taskID <= int64(numTasks); taskID++ {
+		tasks = append(tasks, s.newRandomTransferTaskRow(shardID, taskID))
+	}
+	_, err := s.store.InsertIntoTransferTasks(newExecutionContext(), tasks)
+	s.NoError(err)
+
+	result, err := s.store.DeleteTaskIDsFromTransferTasks(newExecutionContext(), sqlplugin.TransferTasksIDsFilter{
+		ShardID: shardID,
+		TaskIDs: []int64{1, 3, 5, 7},
+	})
+	s.NoError(err)
+	rowsAffected, err := result.RowsAffected()
+	s.NoError(err)
+	s.Equal(3, int(rowsAffected))
+
+	rows, err := s.store.RangeSelectFromTransferTasks(newExecutionContext(), sqlplugin.TransferTasksRangeFilter{
+		ShardID:            shardID,
+		InclusiveMinTaskID: 1,
+		ExclusiveMaxTaskID: int64(numTasks) + 1,
+		PageSize:           numTasks,
+	})
+	s.NoError(err)
+	for index := range rows {
+		rows[index].ShardID = shardID
+	}
+	s.Equal([]sqlplugin.TransferTasksRow{tasks[1], tasks[3]}, rows)
+}
+
 func (s *historyHistoryTransferTaskSuite) newRandomTransferTaskRow(
 	shardID int32,
 	taskID int64,
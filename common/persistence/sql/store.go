@@ -90,6 +90,7 @@ func createDB[T any](
 	if err != nil {
 		return res, err
 	}
+	db.SetSlowQueryThreshold(cfg.SlowQueryThreshold)
 	//revive:disable-next-line:unchecked-type-assertion
 	res = db.(T)
 	return res, err
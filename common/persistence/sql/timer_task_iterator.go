@@ -0,0 +1,135 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	p "go.temporal.io/server/common/persistence"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+type (
+	// TimerTaskIterator iterates over the timer tasks of a shard in fire time order. Pages are read lazily, one
+	// at a time, and the position between pages is kept as a page token struct that is never serialized.
+	TimerTaskIterator struct {
+		store                *sqlExecutionStore
+		shardID              int32
+		exclusiveMaxFireTime time.Time
+		pageSize             int
+
+		buffer    []p.InternalHistoryTask
+		pageToken *scheduledTaskPageToken
+	}
+)
+
+// NewTimerTaskIterator returns an iterator over the timer tasks of a shard with fire times in
+// [inclusiveMinKey.FireTime, exclusiveMaxKey.FireTime), reading pages of pageSize tasks.
+func (m *sqlExecutionStore) NewTimerTaskIterator(
+	shardID int32,
+	inclusiveMinKey tasks.Key,
+	exclusiveMaxKey tasks.Key,
+	pageSize int,
+) *TimerTaskIterator {
+	return &TimerTaskIterator{
+		store:                m,
+		shardID:              shardID,
+		exclusiveMaxFireTime: exclusiveMaxKey.FireTime,
+		pageSize:             pageSize,
+		pageToken:            &scheduledTaskPageToken{TaskID: math.MinInt64, Timestamp: inclusiveMinKey.FireTime},
+	}
+}
+
+// HasNext returns whether there is a next task. Every page is read with one extra row to know whether another
+// page follows, so it is exact unless the remaining tasks are all skipped for failing checksum verification,
+// in which case Next returns io.EOF.
+func (i *TimerTaskIterator) HasNext() bool {
+	return len(i.buffer) > 0 || i.pageToken != nil
+}
+
+// Next returns the next timer task, reading the next page with ctx if no task is buffered.
+func (i *TimerTaskIterator) Next(ctx context.Context) (p.InternalHistoryTask, error) {
+	for len(i.buffer) == 0 {
+		if i.pageToken == nil {
+			return p.InternalHistoryTask{}, io.EOF
+		}
+		if err := ctx.Err(); err != nil {
+			return p.InternalHistoryTask{}, err
+		}
+		if err := i.readPage(ctx); err != nil {
+			return p.InternalHistoryTask{}, err
+		}
+	}
+	task := i.buffer[0]
+	i.buffer = i.buffer[1:]
+	return task, nil
+}
+
+func (i *TimerTaskIterator) readPage(ctx context.Context) error {
+	rows, err := i.store.Db.RangeSelectFromTimerTasks(ctx, sqlplugin.TimerTasksRangeFilter{
+		ShardID:                         i.shardID,
+		InclusiveMinVisibilityTimestamp: i.pageToken.Timestamp,
+		InclusiveMinTaskID:              i.pageToken.TaskID,
+		ExclusiveMaxVisibilityTimestamp: i.exclusiveMaxFireTime,
+		PageSize:                        i.pageSize + 1,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return serviceerror.NewUnavailable(fmt.Sprintf("TimerTaskIterator failed. Select failed. Error: %v", err))
+	}
+
+	i.pageToken = nil
+	if len(rows) > i.pageSize {
+		// the extra row starts the next page
+		i.pageToken = &scheduledTaskPageToken{
+			TaskID:    rows[i.pageSize].TaskID,
+			Timestamp: rows[i.pageSize].VisibilityTimestamp,
+		}
+		rows = rows[:i.pageSize]
+	}
+	for _, row := range rows {
+		ok, err := i.store.verifyTaskDataChecksum("TimerTaskIterator", i.shardID, row.TaskID, row.Data, row.DataChecksum)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		i.buffer = append(i.buffer, p.InternalHistoryTask{
+			Key:  tasks.NewKey(row.VisibilityTimestamp, row.TaskID),
+			Blob: i.store.newTaskDataBlob("TimerTaskIterator", row.Data, row.DataEncoding),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// The MIT License
+//
+// Copyright (c) 2020 Temporal Technologies Inc.  All rights reserved.
+//
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/persistence/sql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin"
+	"go.temporal.io/server/service/history/tasks"
+)
+
+type countingTimerReadsDB struct {
+	sqlplugin.DB
+	reads int
+}
+
+func (db *countingTimerReadsDB) RangeSelectFromTimerTasks(
+	ctx context.Context,
+	filter sqlplugin.TimerTasksRangeFilter,
+) ([]sqlplugin.TimerTasksRow, error) {
+	db.reads++
+	return db.DB.RangeSelectFromTimerTasks(ctx, filter)
+}
+
+func TestTimerTaskIterator(t *testing.T) {
+	ctx := context.Background()
+	db := &countingTimerReadsDB{DB: newTestDB(t)}
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	shardID := rand.Int31()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	keys := []tasks.Key{
+		tasks.NewKey(now, 1),
+		tasks.NewKey(now, 2),
+		tasks.NewKey(now.Add(time.Second), 3),
+		tasks.NewKey(now.Add(2*time.Second), 4),
+	}
+	var rows []sqlplugin.TimerTasksRow
+	for _, key := range keys {
+		rows = append(rows, sqlplugin.TimerTasksRow{
+			ShardID:             shardID,
+			VisibilityTimestamp: key.FireTime,
+			TaskID:              key.TaskID,
+			Data:                []byte("timer"),
+			DataEncoding:        "test",
+		})
+	}
+	// outside of the requested range
+	rows = append(rows, sqlplugin.TimerTasksRow{
+		ShardID:             shardID,
+		VisibilityTimestamp: now.Add(time.Hour),
+		TaskID:              5,
+		Data:                []byte("timer"),
+		DataEncoding:        "test",
+	})
+	_, err := db.InsertIntoTimerTasks(ctx, rows)
+	require.NoError(t, err)
+
+	iter := store.NewTimerTaskIterator(shardID, tasks.NewKey(now, 0), tasks.NewKey(now.Add(time.Minute), 0), 2)
+	require.Zero(t, db.reads)
+
+	var readKeys []tasks.Key
+	var readsAfterTask []int
+	for iter.HasNext() {
+		task, err := iter.Next(ctx)
+		require.NoError(t, err)
+		readKeys = append(readKeys, task.Key)
+		readsAfterTask = append(readsAfterTask, db.reads)
+	}
+	require.Len(t, readKeys, len(keys))
+	for i, key := range keys {
+		require.Equal(t, key.TaskID, readKeys[i].TaskID)
+		require.True(t, key.FireTime.Equal(readKeys[i].FireTime))
+	}
+	// pages are read only once the previous page is consumed, and the last full page needs no extra read
+	require.Equal(t, []int{1, 1, 2, 2}, readsAfterTask)
+}
+
+func TestTimerTaskIterator_ContextCanceled(t *testing.T) {
+	db := newTestDB(t)
+	store := sql.NewTestSQLExecutionStore(db, &config.SQL{}, log.NewTestLogger(), metrics.NoopMetricsHandler)
+	now := time.Now().UTC()
+
+	iter := store.NewTimerTaskIterator(rand.Int31(), tasks.NewKey(now, 0), tasks.NewKey(now.Add(time.Minute), 0), 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.True(t, iter.HasNext())
+	_, err := iter.Next(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
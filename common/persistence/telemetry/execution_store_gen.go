@@ -134,6 +134,41 @@ func (d telemetryExecutionStore) AppendHistoryNodes(ctx context.Context, request
 	return
 }
 
+// CompleteAndRescheduleTimerTask wraps ExecutionStore.CompleteAndRescheduleTimerTask.
+func (d telemetryExecutionStore) CompleteAndRescheduleTimerTask(ctx context.Context, request *_sourcePersistence.InternalCompleteAndRescheduleTimerTaskRequest) (err error) {
+	ctx, span := d.tracer.Start(
+		ctx,
+		"persistence.ExecutionStore/CompleteAndRescheduleTimerTask",
+		trace.WithAttributes(
+			attribute.Key("persistence.store").String("ExecutionStore"),
+			attribute.Key("persistence.method").String("CompleteAndRescheduleTimerTask"),
+		))
+	defer span.End()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		span.SetAttributes(attribute.String("deadline", deadline.Format(time.RFC3339Nano)))
+		span.SetAttributes(attribute.String("timeout", time.Until(deadline).String()))
+	}
+
+	err = d.ExecutionStore.CompleteAndRescheduleTimerTask(ctx, request)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	if d.debugMode {
+
+		requestPayload, err := json.MarshalIndent(request, "", "    ")
+		if err != nil {
+			d.logger.Error("failed to serialize *_sourcePersistence.InternalCompleteAndRescheduleTimerTaskRequest for OTEL span", tag.Error(err))
+		} else {
+			span.SetAttributes(attribute.Key("persistence.request.payload").String(string(requestPayload)))
+		}
+
+	}
+
+	return
+}
+
 // CompleteHistoryTask wraps ExecutionStore.CompleteHistoryTask.
 func (d telemetryExecutionStore) CompleteHistoryTask(ctx context.Context, request *_sourcePersistence.CompleteHistoryTaskRequest) (err error) {
 	ctx, span := d.tracer.Start(
@@ -624,6 +659,48 @@ func (d telemetryExecutionStore) GetHistoryTreeContainingBranch(ctx context.Cont
 	return
 }
 
+// GetOldestTimerTask wraps ExecutionStore.GetOldestTimerTask.
+func (d telemetryExecutionStore) GetOldestTimerTask(ctx context.Context, request *_sourcePersistence.GetOldestTimerTaskRequest) (ip1 *_sourcePersistence.InternalGetOldestTimerTaskResponse, err error) {
+	ctx, span := d.tracer.Start(
+		ctx,
+		"persistence.ExecutionStore/GetOldestTimerTask",
+		trace.WithAttributes(
+			attribute.Key("persistence.store").String("ExecutionStore"),
+			attribute.Key("persistence.method").String("GetOldestTimerTask"),
+		))
+	defer span.End()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		span.SetAttributes(attribute.String("deadline", deadline.Format(time.RFC3339Nano)))
+		span.SetAttributes(attribute.String("timeout", time.Until(deadline).String()))
+	}
+
+	ip1, err = d.ExecutionStore.GetOldestTimerTask(ctx, request)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	if d.debugMode {
+
+		requestPayload, err := json.MarshalIndent(request, "", "    ")
+		if err != nil {
+			d.logger.Error("failed to serialize *_sourcePersistence.GetOldestTimerTaskRequest for OTEL span", tag.Error(err))
+		} else {
+			span.SetAttributes(attribute.Key("persistence.request.payload").String(string(requestPayload)))
+		}
+
+		responsePayload, err := json.MarshalIndent(ip1, "", "    ")
+		if err != nil {
+			d.logger.Error("failed to serialize *_sourcePersistence.InternalGetOldestTimerTaskResponse for OTEL span", tag.Error(err))
+		} else {
+			span.SetAttributes(attribute.Key("persistence.response.payload").String(string(responsePayload)))
+		}
+
+	}
+
+	return
+}
+
 // GetReplicationTasksFromDLQ wraps ExecutionStore.GetReplicationTasksFromDLQ.
 func (d telemetryExecutionStore) GetReplicationTasksFromDLQ(ctx context.Context, request *_sourcePersistence.GetReplicationTasksFromDLQRequest) (ip1 *_sourcePersistence.InternalGetReplicationTasksFromDLQResponse, err error) {
 	ctx, span := d.tracer.Start(
@@ -28,6 +28,8 @@ import (
 	"context"
 	"math"
 	"math/rand"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -36,6 +38,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	commonpb "go.temporal.io/api/common/v1"
 	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
 	persistencespb "go.temporal.io/server/api/persistence/v1"
 	"go.temporal.io/server/common/debug"
 	"go.temporal.io/server/common/definition"
@@ -106,6 +109,7 @@ func NewExecutionMutableStateTaskSuite(
 			nil,
 			logger,
 			dynamicconfig.GetIntPropertyFn(4*1024*1024),
+			nil,
 		),
 		Logger: logger,
 	}
@@ -506,6 +510,143 @@ func (s *ExecutionMutableStateTaskSuite) TestGetTimerTasksOrdered() {
 	s.True(loadedTasks[0].GetKey().CompareTo(loadedTasks[1].GetKey()) < 0)
 }
 
+func (s *ExecutionMutableStateTaskSuite) TestGetEarliestTimerFireTime() {
+	resp, err := s.ExecutionManager.GetEarliestTimerFireTime(s.Ctx, &p.GetEarliestTimerFireTimeRequest{
+		ShardID: s.ShardID,
+	})
+	s.NoError(err)
+	s.False(resp.Found)
+
+	now := time.Now().UTC().Truncate(p.ScheduledTaskMinPrecision)
+	timerTasks := []tasks.Task{
+		&tasks.UserTimerTask{
+			WorkflowKey:         s.WorkflowKey,
+			TaskID:              1,
+			VisibilityTimestamp: now.Add(time.Hour),
+		},
+		&tasks.UserTimerTask{
+			WorkflowKey:         s.WorkflowKey,
+			TaskID:              2,
+			VisibilityTimestamp: now.Add(time.Second),
+		},
+		&tasks.UserTimerTask{
+			WorkflowKey:         s.WorkflowKey,
+			TaskID:              3,
+			VisibilityTimestamp: now.Add(time.Minute),
+		},
+	}
+	err = s.ExecutionManager.AddHistoryTasks(s.Ctx, &p.AddHistoryTasksRequest{
+		ShardID:     s.ShardID,
+		RangeID:     s.RangeID,
+		NamespaceID: s.WorkflowKey.NamespaceID,
+		WorkflowID:  s.WorkflowKey.WorkflowID,
+		Tasks: map[tasks.Category][]tasks.Task{
+			tasks.CategoryTimer: timerTasks,
+		},
+	})
+	s.NoError(err)
+
+	resp, err = s.ExecutionManager.GetEarliestTimerFireTime(s.Ctx, &p.GetEarliestTimerFireTimeRequest{
+		ShardID: s.ShardID,
+	})
+	s.NoError(err)
+	s.True(resp.Found)
+	s.True(now.Add(time.Second).Equal(resp.FireTime), "expected %v, got %v", now.Add(time.Second), resp.FireTime)
+}
+
+func (s *ExecutionMutableStateTaskSuite) TestGetOldestTimerTask() {
+	resp, err := s.ExecutionManager.GetOldestTimerTask(s.Ctx, &p.GetOldestTimerTaskRequest{
+		ShardID: s.ShardID,
+	})
+	s.NoError(err)
+	s.Nil(resp.Task)
+
+	past := time.Now().UTC().Truncate(p.ScheduledTaskMinPrecision).Add(-time.Minute)
+	timerTasks := []tasks.Task{
+		&tasks.UserTimerTask{
+			WorkflowKey:         s.WorkflowKey,
+			TaskID:              1,
+			VisibilityTimestamp: past.Add(time.Second),
+		},
+		&tasks.UserTimerTask{
+			WorkflowKey:         s.WorkflowKey,
+			TaskID:              3,
+			VisibilityTimestamp: past,
+		},
+		&tasks.UserTimerTask{
+			WorkflowKey:         s.WorkflowKey,
+			TaskID:              2,
+			VisibilityTimestamp: past,
+		},
+	}
+	err = s.ExecutionManager.AddHistoryTasks(s.Ctx, &p.AddHistoryTasksRequest{
+		ShardID:     s.ShardID,
+		RangeID:     s.RangeID,
+		NamespaceID: s.WorkflowKey.NamespaceID,
+		WorkflowID:  s.WorkflowKey.WorkflowID,
+		Tasks: map[tasks.Category][]tasks.Task{
+			tasks.CategoryTimer: timerTasks,
+		},
+	})
+	s.NoError(err)
+
+	resp, err = s.ExecutionManager.GetOldestTimerTask(s.Ctx, &p.GetOldestTimerTaskRequest{
+		ShardID: s.ShardID,
+	})
+	s.NoError(err)
+	s.NotNil(resp.Task)
+	s.Equal(tasks.NewKey(past, 2), resp.Task.GetKey())
+	s.Equal(s.WorkflowKey, definition.NewWorkflowKey(resp.Task.GetNamespaceID(), resp.Task.GetWorkflowID(), resp.Task.GetRunID()))
+}
+
+func (s *ExecutionMutableStateTaskSuite) TestCompleteAndRescheduleTimerTask() {
+	now := time.Now().UTC().Truncate(p.ScheduledTaskMinPrecision)
+	completedTask := &tasks.UserTimerTask{
+		WorkflowKey:         s.WorkflowKey,
+		TaskID:              1,
+		VisibilityTimestamp: now,
+	}
+	nextTask := &tasks.UserTimerTask{
+		WorkflowKey:         s.WorkflowKey,
+		TaskID:              2,
+		VisibilityTimestamp: now.Add(time.Minute),
+	}
+	err := s.ExecutionManager.AddHistoryTasks(s.Ctx, &p.AddHistoryTasksRequest{
+		ShardID:     s.ShardID,
+		RangeID:     s.RangeID,
+		NamespaceID: s.WorkflowKey.NamespaceID,
+		WorkflowID:  s.WorkflowKey.WorkflowID,
+		Tasks: map[tasks.Category][]tasks.Task{
+			tasks.CategoryTimer: {completedTask},
+		},
+	})
+	s.NoError(err)
+
+	request := &p.CompleteAndRescheduleTimerTaskRequest{
+		ShardID:          s.ShardID,
+		RangeID:          s.RangeID - 1,
+		CompletedTaskKey: completedTask.GetKey(),
+		NextTask:         nextTask,
+	}
+	// a stale shard owner changes nothing
+	err = s.ExecutionManager.CompleteAndRescheduleTimerTask(s.Ctx, request)
+	s.IsType(&p.ShardOwnershipLostError{}, err)
+	loadedTasks := s.PaginateTasks(tasks.CategoryTimer, tasks.NewKey(now, 0), tasks.NewKey(now.Add(time.Hour), 0), 10)
+	s.Len(loadedTasks, 1)
+	s.Equal(completedTask.GetKey(), loadedTasks[0].GetKey())
+
+	request.RangeID = s.RangeID
+	err = s.ExecutionManager.CompleteAndRescheduleTimerTask(s.Ctx, request)
+	s.NoError(err)
+	loadedTasks = s.PaginateTasks(tasks.CategoryTimer, tasks.NewKey(now, 0), tasks.NewKey(now.Add(time.Hour), 0), 10)
+	s.Len(loadedTasks, 1)
+	s.Equal(nextTask.GetKey(), loadedTasks[0].GetKey())
+
+	// the completed timer task is gone, so it can't be rescheduled twice
+	err = s.ExecutionManager.CompleteAndRescheduleTimerTask(s.Ctx, request)
+	s.IsType(&serviceerror.NotFound{}, err)
+}
+
 func (s *ExecutionMutableStateTaskSuite) TestGetScheduledTasksOrdered() {
 	now := time.Now().Truncate(p.ScheduledTaskMinPrecision)
 	scheduledTasks := []tasks.Task{
@@ -565,6 +706,133 @@ func (s *ExecutionMutableStateTaskSuite) TestGetScheduledTasksOrdered() {
 	s.Empty(response.Tasks)
 }
 
+func (s *ExecutionMutableStateTaskSuite) TestGetHistoryTasks_TotalOrder() {
+	now := time.Now().UTC().Truncate(p.ScheduledTaskMinPrecision)
+	taskIDs := rand.Perm(20)
+	var immediateTasks, scheduledTasks []tasks.Task
+	for i, taskID := range taskIDs {
+		immediateTask := tasks.NewFakeTask(s.WorkflowKey, fakeImmediateTaskCategory, now)
+		immediateTask.SetTaskID(int64(taskID) + 1)
+		immediateTasks = append(immediateTasks, immediateTask)
+
+		// several tasks share a fire time, so that their order depends on the task ID
+		scheduledTask := tasks.NewFakeTask(
+			s.WorkflowKey,
+			fakeScheduledTaskCategory,
+			now.Add(time.Duration(i%3)*p.ScheduledTaskMinPrecision),
+		)
+		scheduledTask.SetTaskID(int64(taskID) + 1)
+		scheduledTasks = append(scheduledTasks, scheduledTask)
+	}
+	err := s.ExecutionManager.AddHistoryTasks(s.Ctx, &p.AddHistoryTasksRequest{
+		ShardID:     s.ShardID,
+		RangeID:     s.RangeID,
+		NamespaceID: s.WorkflowKey.NamespaceID,
+		WorkflowID:  s.WorkflowKey.WorkflowID,
+		Tasks: map[tasks.Category][]tasks.Task{
+			fakeImmediateTaskCategory: immediateTasks,
+			fakeScheduledTaskCategory: scheduledTasks,
+		},
+	})
+	s.NoError(err)
+
+	taskKeys := func(historyTasks []tasks.Task) []tasks.Key {
+		keys := make([]tasks.Key, 0, len(historyTasks))
+		for _, task := range historyTasks {
+			keys = append(keys, task.GetKey())
+		}
+		return keys
+	}
+	for _, testCase := range []struct {
+		category            tasks.Category
+		createdTasks        []tasks.Task
+		inclusiveMinTaskKey tasks.Key
+		exclusiveMaxTaskKey tasks.Key
+	}{
+		{
+			category:            fakeImmediateTaskCategory,
+			createdTasks:        immediateTasks,
+			inclusiveMinTaskKey: tasks.NewImmediateKey(0),
+			exclusiveMaxTaskKey: tasks.NewImmediateKey(math.MaxInt64),
+		},
+		{
+			category:            fakeScheduledTaskCategory,
+			createdTasks:        scheduledTasks,
+			inclusiveMinTaskKey: tasks.NewKey(now, 0),
+			exclusiveMaxTaskKey: tasks.NewKey(now.Add(time.Second), 0),
+		},
+	} {
+		expectedKeys := taskKeys(testCase.createdTasks)
+		slices.SortFunc(expectedKeys, tasks.Key.CompareTo)
+		// the order must not depend on how the tasks are paged
+		for _, batchSize := range []int{1, 3, 100} {
+			loadedTasks := s.PaginateTasks(testCase.category, testCase.inclusiveMinTaskKey, testCase.exclusiveMaxTaskKey, batchSize)
+			s.Equal(expectedKeys, taskKeys(loadedTasks), "category %v, batch size %v", testCase.category.Name(), batchSize)
+		}
+	}
+}
+
+func (s *ExecutionMutableStateTaskSuite) TestAddHistoryTasks_ConcurrentOverlappingCategories() {
+	numWorkers := 8
+	numRequestsPerWorker := 5
+	now := time.Now().UTC().Truncate(p.ScheduledTaskMinPrecision)
+
+	wg := sync.WaitGroup{}
+	wg.Add(numWorkers)
+	starter := make(chan struct{})
+	addErrors := make(chan error, numWorkers*numRequestsPerWorker)
+	for worker := 0; worker < numWorkers; worker++ {
+		go func() {
+			defer wg.Done()
+			<-starter
+			for i := 0; i < numRequestsPerWorker; i++ {
+				taskID := int64(worker*numRequestsPerWorker + i + 1)
+				// every request adds tasks of all categories, so that concurrent requests write to the same tables
+				addErrors <- s.ExecutionManager.AddHistoryTasks(s.Ctx, &p.AddHistoryTasksRequest{
+					ShardID:     s.ShardID,
+					RangeID:     s.RangeID,
+					NamespaceID: s.WorkflowKey.NamespaceID,
+					WorkflowID:  s.WorkflowKey.WorkflowID,
+					Tasks: map[tasks.Category][]tasks.Task{
+						tasks.CategoryTransfer: {&tasks.ActivityTask{
+							WorkflowKey:         s.WorkflowKey,
+							TaskID:              taskID,
+							VisibilityTimestamp: now,
+						}},
+						tasks.CategoryTimer: {&tasks.UserTimerTask{
+							WorkflowKey:         s.WorkflowKey,
+							TaskID:              taskID,
+							VisibilityTimestamp: now,
+						}},
+						tasks.CategoryReplication: {&tasks.HistoryReplicationTask{
+							WorkflowKey:         s.WorkflowKey,
+							TaskID:              taskID,
+							VisibilityTimestamp: now,
+						}},
+						tasks.CategoryVisibility: {&tasks.StartExecutionVisibilityTask{
+							WorkflowKey:         s.WorkflowKey,
+							TaskID:              taskID,
+							VisibilityTimestamp: now,
+						}},
+					},
+				})
+			}
+		}()
+	}
+	close(starter)
+	wg.Wait()
+	close(addErrors)
+
+	for err := range addErrors {
+		s.NoError(err)
+	}
+	numTasks := numWorkers * numRequestsPerWorker
+	s.Len(s.PaginateTasks(tasks.CategoryTransfer, tasks.NewImmediateKey(0), tasks.NewImmediateKey(math.MaxInt64), 100), numTasks)
+	s.Len(s.PaginateTasks(tasks.CategoryTimer, tasks.NewKey(now, 0), tasks.NewKey(now.Add(time.Second), 0), 100), numTasks)
+	s.Len(s.PaginateTasks(tasks.CategoryReplication, tasks.NewImmediateKey(0), tasks.NewImmediateKey(math.MaxInt64), 100), numTasks)
+	s.Len(s.PaginateTasks(tasks.CategoryVisibility, tasks.NewImmediateKey(0), tasks.NewImmediateKey(math.MaxInt64), 100), numTasks)
+}
+
 func (s *ExecutionMutableStateTaskSuite) AddRandomTasks(
 	category tasks.Category,
 	numTasks int,
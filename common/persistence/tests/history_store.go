@@ -90,6 +90,7 @@ func NewHistoryEventsSuite(
 			nil,
 			logger,
 			dynamicconfig.GetIntPropertyFn(4*1024*1024),
+			nil,
 		),
 		serializer: eventSerializer,
 		logger:     logger,
@@ -715,6 +715,20 @@ func TestSQLiteHistoryReplicationDLQTaskSuite(t *testing.T) {
 	suite.Run(t, s)
 }
 
+func TestSQLiteHistoryTimerDLQTaskSuite(t *testing.T) {
+	cfg := NewSQLiteMemoryConfig()
+	store, err := sql.NewSQLDB(sqlplugin.DbKindMain, cfg, resolver.NewNoopResolver(), log.NewTestLogger(), metrics.NoopMetricsHandler)
+	if err != nil {
+		t.Fatalf("unable to create SQLite DB: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	s := sqltests.NewHistoryTimerDLQTaskSuite(t, store)
+	suite.Run(t, s)
+}
+
 func TestSQLiteHistoryExecutionBufferSuite(t *testing.T) {
 	cfg := NewSQLiteMemoryConfig()
 	store, err := sql.NewSQLDB(sqlplugin.DbKindMain, cfg, resolver.NewNoopResolver(), log.NewTestLogger(), metrics.NoopMetricsHandler)
@@ -1036,6 +1050,19 @@ func TestSQLiteFileHistoryReplicationDLQTaskSuite(t *testing.T) {
 	suite.Run(t, s)
 }
 
+func TestSQLiteFileHistoryTimerDLQTaskSuite(t *testing.T) {
+	cfg := NewSQLiteFileConfig()
+	SetupSQLiteDatabase(t, cfg)
+	store, err := sql.NewSQLDB(sqlplugin.DbKindMain, cfg, resolver.NewNoopResolver(), log.NewTestLogger(), metrics.NoopMetricsHandler)
+	if err != nil {
+		t.Fatalf("unable to create SQLite DB: %v", err)
+	}
+	defer os.Remove(cfg.DatabaseName)
+
+	s := sqltests.NewHistoryTimerDLQTaskSuite(t, store)
+	suite.Run(t, s)
+}
+
 func TestSQLiteFileHistoryExecutionBufferSuite(t *testing.T) {
 	cfg := NewSQLiteFileConfig()
 	SetupSQLiteDatabase(t, cfg)
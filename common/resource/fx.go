@@ -343,6 +343,9 @@ func MatchingClientProvider(matchingRawClient MatchingRawClient) MatchingClient
 
 func PersistenceConfigProvider(persistenceConfig config.Persistence, dc *dynamicconfig.Collection) *config.Persistence {
 	persistenceConfig.TransactionSizeLimit = dynamicconfig.TransactionSizeLimit.Get(dc)
+	persistenceConfig.LatencySensitiveTaskReadTimeout = dynamicconfig.LatencySensitiveTaskReadTimeout.Get(dc)
+	persistenceConfig.ConsistentTaskReadTimeout = dynamicconfig.ConsistentTaskReadTimeout.Get(dc)
+	persistenceConfig.TimerTaskRangeDeleteBatchSize = dynamicconfig.TimerTaskRangeDeleteBatchSize.Get(dc)
 	return &persistenceConfig
 }
 
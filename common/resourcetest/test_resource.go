@@ -139,6 +139,8 @@ func NewTest(controller *gomock.Controller, serviceName primitives.ServiceName)
 	shardMgr := persistence.NewMockShardManager(controller)
 	executionMgr := persistence.NewMockExecutionManager(controller)
 	executionMgr.EXPECT().GetHistoryBranchUtil().Return(&persistence.HistoryBranchUtilImpl{}).AnyTimes()
+	executionMgr.EXPECT().MarkShardClosing(gomock.Any(), gomock.Any()).AnyTimes()
+	executionMgr.EXPECT().MarkShardOpen(gomock.Any()).AnyTimes()
 	namespaceReplicationQueue := persistence.NewMockNamespaceReplicationQueue(controller)
 
 	membershipMonitor := membership.NewMockMonitor(controller)
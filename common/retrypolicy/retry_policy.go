@@ -31,6 +31,7 @@ import (
 
 	commonpb "go.temporal.io/api/common/v1"
 	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
 	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/server/common/primitives/timestamp"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -44,6 +45,21 @@ const (
 	TimeoutFailureTypePrefix = "TemporalTimeout:"
 )
 
+// RetryableClassifier decides whether workflow and activity failures are retryable, before the built-in
+// classification of the history service. Classify returns handled false for failures it doesn't classify, which
+// are then classified as if there were no classifier. A history service uses the RetryableClassifier provided to
+// its fx app, if any.
+type RetryableClassifier interface {
+	Classify(failure *failurepb.Failure) (retryable bool, handled bool)
+}
+
+// BackoffCoefficientBreakpoint overrides the backoff coefficient from an attempt on: the interval after attempt
+// FromAttempt and every later attempt grows by Coefficient, until the next breakpoint.
+type BackoffCoefficientBreakpoint struct {
+	FromAttempt int32
+	Coefficient float64
+}
+
 // DefaultRetrySettings indicates what the "default" retry settings
 // are if it is not specified on an Activity or for any unset fields
 // if a policy is explicitly set on a workflow
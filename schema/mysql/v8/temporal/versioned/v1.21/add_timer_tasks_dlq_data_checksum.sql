@@ -0,0 +1 @@
+ALTER TABLE timer_tasks_dlq ADD COLUMN data_checksum BIGINT NULL;
@@ -0,0 +1 @@
+ALTER TABLE replication_tasks_dlq ADD COLUMN reason VARCHAR(255) NULL;
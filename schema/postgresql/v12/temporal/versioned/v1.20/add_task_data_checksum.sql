@@ -0,0 +1,7 @@
+ALTER TABLE history_immediate_tasks ADD COLUMN data_checksum BIGINT NULL;
+ALTER TABLE history_scheduled_tasks ADD COLUMN data_checksum BIGINT NULL;
+ALTER TABLE transfer_tasks ADD COLUMN data_checksum BIGINT NULL;
+ALTER TABLE timer_tasks ADD COLUMN data_checksum BIGINT NULL;
+ALTER TABLE replication_tasks ADD COLUMN data_checksum BIGINT NULL;
+ALTER TABLE replication_tasks_dlq ADD COLUMN data_checksum BIGINT NULL;
+ALTER TABLE visibility_tasks ADD COLUMN data_checksum BIGINT NULL;
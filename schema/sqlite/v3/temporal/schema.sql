@@ -118,6 +118,7 @@ CREATE TABLE history_immediate_tasks(
   --
   data MEDIUMBLOB NOT NULL,
   data_encoding VARCHAR(16) NOT NULL,
+  data_checksum BIGINT,
   PRIMARY KEY (shard_id, category_id, task_id)
 );
 
@@ -129,6 +130,7 @@ CREATE TABLE history_scheduled_tasks (
   --
   data MEDIUMBLOB NOT NULL,
   data_encoding VARCHAR(16) NOT NULL,
+  data_checksum BIGINT,
   PRIMARY KEY (shard_id, category_id, visibility_timestamp, task_id)
 );
 
@@ -138,6 +140,7 @@ CREATE TABLE transfer_tasks(
 	--
 	data MEDIUMBLOB NOT NULL,
 	data_encoding VARCHAR(16) NOT NULL,
+	data_checksum BIGINT,
 	PRIMARY KEY (shard_id, task_id)
 );
 
@@ -148,6 +151,7 @@ CREATE TABLE timer_tasks (
 	--
 	data MEDIUMBLOB NOT NULL,
 	data_encoding VARCHAR(16) NOT NULL,
+	data_checksum BIGINT,
 	PRIMARY KEY (shard_id, visibility_timestamp, task_id)
 );
 
@@ -157,6 +161,7 @@ CREATE TABLE replication_tasks (
 	--
 	data MEDIUMBLOB NOT NULL,
 	data_encoding VARCHAR(16) NOT NULL,
+	data_checksum BIGINT,
 	PRIMARY KEY (shard_id, task_id)
 );
 
@@ -167,15 +172,29 @@ CREATE TABLE replication_tasks_dlq (
 	--
 	data MEDIUMBLOB NOT NULL,
 	data_encoding VARCHAR(16) NOT NULL,
+	reason VARCHAR(255),
+	data_checksum BIGINT,
 	PRIMARY KEY (source_cluster_name, shard_id, task_id)
 );
 
+CREATE TABLE timer_tasks_dlq (
+	shard_id INT NOT NULL,
+	visibility_timestamp TIMESTAMP NOT NULL,
+	task_id BIGINT NOT NULL,
+	--
+	data MEDIUMBLOB NOT NULL,
+	data_encoding VARCHAR(16) NOT NULL,
+	data_checksum BIGINT,
+	PRIMARY KEY (shard_id, visibility_timestamp, task_id)
+);
+
 CREATE TABLE visibility_tasks(
 	shard_id INT NOT NULL,
 	task_id BIGINT NOT NULL,
 	--
 	data MEDIUMBLOB NOT NULL,
 	data_encoding VARCHAR(16) NOT NULL,
+	data_checksum BIGINT,
 	PRIMARY KEY (shard_id, task_id)
 );
 
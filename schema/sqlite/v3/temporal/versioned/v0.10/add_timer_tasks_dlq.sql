@@ -0,0 +1,9 @@
+CREATE TABLE timer_tasks_dlq (
+	shard_id INT NOT NULL,
+	visibility_timestamp TIMESTAMP NOT NULL,
+	task_id BIGINT NOT NULL,
+	--
+	data MEDIUMBLOB NOT NULL,
+	data_encoding VARCHAR(16) NOT NULL,
+	PRIMARY KEY (shard_id, visibility_timestamp, task_id)
+);
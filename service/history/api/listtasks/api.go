@@ -84,6 +84,7 @@ func Invoke(
 		ExclusiveMaxTaskKey: maxTaskKey,
 		BatchSize:           int(adminRequest.BatchSize),
 		NextPageToken:       adminRequest.NextPageToken,
+		ReadTier:            persistence.ReadTierConsistent,
 	})
 	if err != nil {
 		return nil, err
@@ -179,6 +179,7 @@ func (s *apiSuite) TestGetHistoryTasks() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID),
 		BatchSize:           batchSize,
 		NextPageToken:       reqNextPageToken,
+		ReadTier:            persistence.ReadTierConsistent,
 	}).Return(&persistence.GetHistoryTasksResponse{
 		Tasks:         fakeTasks,
 		NextPageToken: respNextPageToken,
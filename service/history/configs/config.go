@@ -254,6 +254,16 @@ type Config struct {
 	// DefaultWorkflowRetryPolicy specifies the out-of-box retry policy for
 	// any unset fields on a RetryPolicy configured on a Workflow
 	DefaultWorkflowRetryPolicy dynamicconfig.TypedPropertyFnWithNamespaceFilter[retrypolicy.DefaultRetrySettings]
+	// RetryMinimumBackoffInterval is the lower bound of backoff intervals computed from a workflow retry policy
+	RetryMinimumBackoffInterval dynamicconfig.DurationPropertyFnWithNamespaceFilter
+	// RetryBackoffIntervalGranularity is the granularity backoff intervals computed from a retry policy are rounded up to
+	RetryBackoffIntervalGranularity dynamicconfig.DurationPropertyFnWithNamespaceFilter
+	// RetryBackoffJitterCoefficient is the fraction backoff intervals computed from a retry policy are jittered by
+	RetryBackoffJitterCoefficient dynamicconfig.FloatPropertyFnWithNamespaceFilter
+	// RetryBackoffCoefficientBreakpoints override the backoff coefficient of retry policies from given attempts on
+	RetryBackoffCoefficientBreakpoints dynamicconfig.TypedPropertyFnWithNamespaceFilter[[]retrypolicy.BackoffCoefficientBreakpoint]
+	// GlobalNonRetryableErrorTypes are application failure types never retried, whatever the retry policy
+	GlobalNonRetryableErrorTypes dynamicconfig.TypedPropertyFnWithNamespaceFilter[[]string]
 
 	// Workflow task settings
 	// DefaultWorkflowTaskTimeout the default workflow task timeout
@@ -602,6 +612,11 @@ func NewConfig(
 
 		DefaultActivityRetryPolicy:                       dynamicconfig.DefaultActivityRetryPolicy.Get(dc),
 		DefaultWorkflowRetryPolicy:                       dynamicconfig.DefaultWorkflowRetryPolicy.Get(dc),
+		RetryMinimumBackoffInterval:                      dynamicconfig.RetryMinimumBackoffInterval.Get(dc),
+		RetryBackoffIntervalGranularity:                  dynamicconfig.RetryBackoffIntervalGranularity.Get(dc),
+		RetryBackoffJitterCoefficient:                    dynamicconfig.RetryBackoffJitterCoefficient.Get(dc),
+		RetryBackoffCoefficientBreakpoints:               dynamicconfig.RetryBackoffCoefficientBreakpoints.Get(dc),
+		GlobalNonRetryableErrorTypes:                     dynamicconfig.GlobalNonRetryableErrorTypes.Get(dc),
 		WorkflowTaskHeartbeatTimeout:                     dynamicconfig.WorkflowTaskHeartbeatTimeout.Get(dc),
 		WorkflowTaskCriticalAttempts:                     dynamicconfig.WorkflowTaskCriticalAttempts.Get(dc),
 		WorkflowTaskRetryMaxInterval:                     dynamicconfig.WorkflowTaskRetryMaxInterval.Get(dc),
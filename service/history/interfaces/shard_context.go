@@ -46,6 +46,7 @@ import (
 	"go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/persistence/serialization"
 	"go.temporal.io/server/common/pingable"
+	"go.temporal.io/server/common/retrypolicy"
 	"go.temporal.io/server/common/searchattribute"
 	"go.temporal.io/server/service/history/configs"
 	"go.temporal.io/server/service/history/events"
@@ -130,6 +131,9 @@ type (
 		GetFinalizer() *finalizer.Finalizer
 
 		ChasmRegistry() *chasm.Registry
+		// RetryableClassifier returns the classifier of retryable workflow and activity failures provided to the
+		// history service, or nil if there is none.
+		RetryableClassifier() retrypolicy.RetryableClassifier
 	}
 
 	// A ControllableContext is a Context plus other methods needed by
@@ -56,6 +56,7 @@ import (
 	persistence0 "go.temporal.io/server/common/persistence"
 	serialization "go.temporal.io/server/common/persistence/serialization"
 	pingable "go.temporal.io/server/common/pingable"
+	retrypolicy "go.temporal.io/server/common/retrypolicy"
 	searchattribute "go.temporal.io/server/common/searchattribute"
 	configs "go.temporal.io/server/service/history/configs"
 	events "go.temporal.io/server/service/history/events"
@@ -693,6 +694,20 @@ func (mr *MockShardContextMockRecorder) NewVectorClock() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewVectorClock", reflect.TypeOf((*MockShardContext)(nil).NewVectorClock))
 }
 
+// RetryableClassifier mocks base method.
+func (m *MockShardContext) RetryableClassifier() retrypolicy.RetryableClassifier {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryableClassifier")
+	ret0, _ := ret[0].(retrypolicy.RetryableClassifier)
+	return ret0
+}
+
+// RetryableClassifier indicates an expected call of RetryableClassifier.
+func (mr *MockShardContextMockRecorder) RetryableClassifier() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryableClassifier", reflect.TypeOf((*MockShardContext)(nil).RetryableClassifier))
+}
+
 // SetCurrentTime mocks base method.
 func (m *MockShardContext) SetCurrentTime(cluster string, currentTime time.Time) {
 	m.ctrl.T.Helper()
@@ -1510,6 +1525,20 @@ func (mr *MockControllableContextMockRecorder) NewVectorClock() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewVectorClock", reflect.TypeOf((*MockControllableContext)(nil).NewVectorClock))
 }
 
+// RetryableClassifier mocks base method.
+func (m *MockControllableContext) RetryableClassifier() retrypolicy.RetryableClassifier {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryableClassifier")
+	ret0, _ := ret[0].(retrypolicy.RetryableClassifier)
+	return ret0
+}
+
+// RetryableClassifier indicates an expected call of RetryableClassifier.
+func (mr *MockControllableContextMockRecorder) RetryableClassifier() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryableClassifier", reflect.TypeOf((*MockControllableContext)(nil).RetryableClassifier))
+}
+
 // SetCurrentTime mocks base method.
 func (m *MockControllableContext) SetCurrentTime(cluster string, currentTime time.Time) {
 	m.ctrl.T.Helper()
@@ -74,6 +74,7 @@ func NewImmediateQueue(
 				ExclusiveMaxTaskKey: r.ExclusiveMax,
 				BatchSize:           options.BatchSize(),
 				NextPageToken:       paginationToken,
+				ReadTier:            persistence.ReadTierLatencySensitive,
 			}
 
 			resp, err := shard.GetHistoryTasks(ctx, request)
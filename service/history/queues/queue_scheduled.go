@@ -90,6 +90,7 @@ func NewScheduledQueue(
 				),
 				BatchSize:     options.BatchSize(),
 				NextPageToken: paginationToken,
+				ReadTier:      persistence.ReadTierLatencySensitive,
 			}
 
 			resp, err := shard.GetHistoryTasks(ctx, request)
@@ -274,6 +275,7 @@ func (p *scheduledQueue) lookAheadTask() {
 		ExclusiveMaxTaskKey: tasks.NewKey(lookAheadMaxTime, 0),
 		BatchSize:           1,
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}
 	response, err := p.shard.GetHistoryTasks(ctx, request)
 	if err != nil {
@@ -221,6 +221,7 @@ func (s *scheduledQueueSuite) TestPaginationFnProvider_Success() {
 		ExclusiveMaxTaskKey: tasks.NewKey(r.ExclusiveMax.FireTime.Add(persistence.ScheduledTaskMinPrecision), 0),
 		BatchSize:           testQueueOptions.BatchSize(),
 		NextPageToken:       currentPageToken,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(&persistence.GetHistoryTasksResponse{
 		Tasks:         mockTasks,
 		NextPageToken: nextPageToken,
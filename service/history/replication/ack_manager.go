@@ -382,6 +382,7 @@ func (p *ackMgrImpl) getReplicationTasksFn(
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 			BatchSize:           batchSize,
 			NextPageToken:       paginationToken,
+			ReadTier:            persistence.ReadTierLatencySensitive,
 		})
 		if err != nil {
 			return nil, nil, err
@@ -555,6 +556,7 @@ func (p *ackMgrImpl) GetReplicationTasksIter(
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxExclusiveTaskID),
 			BatchSize:           p.config.ReplicatorProcessorFetchTasksBatchSize(),
 			NextPageToken:       paginationToken,
+			ReadTier:            persistence.ReadTierLatencySensitive,
 		})
 		if err != nil {
 			return nil, nil, err
@@ -252,6 +252,7 @@ func (s *ackManagerSuite) TestGetTasks_NoTasksInDB() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(s.getHistoryTasksResponse(0), nil)
 
 	replicationTasks, lastTaskID, err := s.replicationAckManager.getTasks(ctx, cluster.TestCurrentClusterName, minTaskID, maxTaskID)
@@ -273,6 +274,7 @@ func (s *ackManagerSuite) TestGetTasks_FirstPersistenceErrorReturnsErrorAndEmpty
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse, nil)
 
 	gweErr := serviceerror.NewUnavailable("random error")
@@ -303,6 +305,7 @@ func (s *ackManagerSuite) TestGetTasks_SecondPersistenceErrorReturnsPartialResul
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse, nil)
 
 	eventsCache := events.NewHostLevelEventsCache(
@@ -351,6 +354,7 @@ func (s *ackManagerSuite) TestGetTasks_FullPage() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse, nil)
 
 	eventsCache := events.NewHostLevelEventsCache(
@@ -399,6 +403,7 @@ func (s *ackManagerSuite) TestGetTasks_PartialPage() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse, nil)
 
 	eventsCache := events.NewHostLevelEventsCache(
@@ -459,6 +464,7 @@ func (s *ackManagerSuite) TestGetTasks_FilterNamespace() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       nil,
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse1, nil)
 
 	tasksResponse2 := s.getHistoryTasksResponse(2)
@@ -472,6 +478,7 @@ func (s *ackManagerSuite) TestGetTasks_FilterNamespace() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       []byte{22, 3, 83}, // previous token
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse2, nil)
 
 	tasksResponse3 := s.getHistoryTasksResponse(1)
@@ -482,6 +489,7 @@ func (s *ackManagerSuite) TestGetTasks_FilterNamespace() {
 		ExclusiveMaxTaskKey: tasks.NewImmediateKey(maxTaskID + 1),
 		BatchSize:           s.replicationAckManager.pageSize(),
 		NextPageToken:       []byte{22, 8, 78}, // previous token
+		ReadTier:            persistence.ReadTierLatencySensitive,
 	}).Return(tasksResponse3, nil)
 
 	eventsCache := events.NewHostLevelEventsCache(
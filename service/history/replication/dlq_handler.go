@@ -295,6 +295,7 @@ func (r *dlqHandlerImpl) readMessagesWithAckLevel(
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(lastMessageID + 1),
 			BatchSize:           pageSize,
 			NextPageToken:       pageToken,
+			ReadTier:            persistence.ReadTierConsistent,
 		},
 		SourceClusterName: sourceCluster,
 	})
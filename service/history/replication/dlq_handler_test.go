@@ -191,6 +191,7 @@ func (s *dlqHandlerSuite) TestReadMessages_OK() {
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(lastMessageID + 1),
 			BatchSize:           pageSize,
 			NextPageToken:       pageToken,
+			ReadTier:            persistence.ReadTierConsistent,
 		},
 		SourceClusterName: s.sourceCluster,
 	}).Return(dbResp, nil)
@@ -286,6 +287,7 @@ func (s *dlqHandlerSuite) TestMergeMessages() {
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(lastMessageID + 1),
 			BatchSize:           pageSize,
 			NextPageToken:       pageToken,
+			ReadTier:            persistence.ReadTierConsistent,
 		},
 		SourceClusterName: s.sourceCluster,
 	}).Return(dbResp, nil)
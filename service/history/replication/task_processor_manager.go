@@ -357,6 +357,7 @@ func (r *taskProcessorManagerImpl) cleanupReplicationTasks() error {
 		ctx,
 		&persistence.RangeCompleteHistoryTasksRequest{
 			ShardID:             r.shard.GetShardID(),
+			RangeID:             r.shard.GetRangeID(),
 			TaskCategory:        tasks.CategoryReplication,
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(minAckedTaskID + 1),
 		},
@@ -197,10 +197,13 @@ func (s *taskProcessorManagerSuite) TestCleanupReplicationTask_Cleanup() {
 		},
 	}, true)
 	s.taskProcessorManager.minTxAckedTaskID = ackedTaskID - 1
+	rangeID := rand.Int63()
+	s.mockShard.EXPECT().GetRangeID().Return(rangeID).AnyTimes()
 	s.mockExecutionManager.EXPECT().RangeCompleteHistoryTasks(
 		gomock.Any(),
 		&persistence.RangeCompleteHistoryTasksRequest{
 			ShardID:             s.shardID,
+			RangeID:             rangeID,
 			TaskCategory:        tasks.CategoryReplication,
 			ExclusiveMaxTaskKey: tasks.NewImmediateKey(ackedTaskID + 1),
 		},
@@ -38,6 +38,7 @@ import (
 	"go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/persistence/serialization"
 	"go.temporal.io/server/common/resource"
+	"go.temporal.io/server/common/retrypolicy"
 	"go.temporal.io/server/common/searchattribute"
 	"go.temporal.io/server/service/history/configs"
 	"go.temporal.io/server/service/history/events"
@@ -83,6 +84,7 @@ type (
 
 		StateMachineRegistry *hsm.Registry
 		ChasmRegistry        *chasm.Registry
+		RetryableClassifier  retrypolicy.RetryableClassifier `optional:"true"`
 	}
 
 	contextFactoryImpl struct {
@@ -125,6 +127,7 @@ func (c *contextFactoryImpl) CreateContext(
 		c.EventsCache,
 		c.StateMachineRegistry,
 		c.ChasmRegistry,
+		c.RetryableClassifier,
 	)
 	if err != nil {
 		return nil, err
@@ -68,6 +68,7 @@ import (
 	"go.temporal.io/server/common/persistence/serialization"
 	"go.temporal.io/server/common/pingable"
 	"go.temporal.io/server/common/primitives/timestamp"
+	"go.temporal.io/server/common/retrypolicy"
 	"go.temporal.io/server/common/rpc"
 	"go.temporal.io/server/common/searchattribute"
 	"go.temporal.io/server/common/util"
@@ -151,6 +152,8 @@ type (
 		stateLock  sync.Mutex
 		state      contextState
 		stopReason stopReason
+		// rangeID mirrors shardInfo.RangeId, so that it can be read without rwLock, e.g. within stateLock
+		rangeID atomic.Int64
 
 		// All following fields are protected by rwLock, and only valid if state >= Acquiring:
 		rwLock                        sync.RWMutex
@@ -173,6 +176,8 @@ type (
 		stateMachineRegistry *hsm.Registry
 
 		chasmRegistry *chasm.Registry
+		// retryableClassifier is nil if no classifier was provided
+		retryableClassifier retrypolicy.RetryableClassifier
 	}
 
 	remoteClusterInfo struct {
@@ -1225,6 +1230,7 @@ func (s *ContextImpl) renewRangeLocked(isStealing bool) error {
 
 	s.shardInfo = trimShardInfo(s.clusterMetadata.GetAllClusterInfo(), copyShardInfo(updatedShardInfo))
 	s.taskKeyManager.setRangeID(s.shardInfo.RangeId)
+	s.rangeID.Store(s.shardInfo.RangeId)
 
 	return nil
 }
@@ -1240,6 +1246,7 @@ func (s *ContextImpl) monitorQueueMetrics() {
 			return
 		case <-timer.C:
 			s.emitShardInfoMetricsLogs()
+			s.emitTimerTaskLagMetric()
 			// We reset the timer (rather than using a ticker) so that delays in grabbing the shard lock
 			// don't cause us to pile up
 			timer.Reset(queueMetricUpdateInterval)
@@ -1362,6 +1369,27 @@ Loop:
 	}
 }
 
+// emitTimerTaskLagMetric reads the oldest timer task of the shard from persistence, so it must not be called
+// within rwLock.
+func (s *ContextImpl) emitTimerTaskLagMetric() {
+	ctx, cancel := s.newIOContext()
+	defer cancel()
+
+	resp, err := s.executionManager.GetOldestTimerTask(ctx, &persistence.GetOldestTimerTaskRequest{
+		ShardID: s.shardID,
+	})
+	if err != nil {
+		s.contextTaggedLogger.Warn("Failed to read the oldest timer task of the shard", tag.Error(err))
+		return
+	}
+
+	var lag time.Duration
+	if resp.Task != nil {
+		lag = max(s.timeSource.Now().Sub(resp.Task.GetVisibilityTime()), 0)
+	}
+	metrics.ShardInfoTimerTaskLagGauge.With(s.GetMetricsHandler()).Record(lag.Seconds(), metrics.ShardIDTag(s.shardID))
+}
+
 func (s *ContextImpl) SetCurrentTime(cluster string, currentTime time.Time) {
 	s.wLock()
 	defer s.wUnlock()
@@ -1640,6 +1668,8 @@ func (s *ContextImpl) transition(request contextRequest) error {
 		s.state = contextStateStopping
 		s.stopReason = request.reason
 		s.contextTaggedLogger.Info("", tag.LifeCycleStopping, tag.ComponentShardContext)
+		// Fail in-flight and new task writes of this owner fast, without waiting for the range ID check
+		s.executionManager.MarkShardClosing(s.shardID, s.rangeID.Load())
 		// Cancel lifecycle context as soon as we know we're shutting down
 		s.lifecycleCancel()
 		// This will cause the controller to remove this shard from the map and then call s.FinishStop()
@@ -1654,6 +1684,7 @@ func (s *ContextImpl) transition(request contextRequest) error {
 		// Do this again in case we skipped the stopping state, which could happen
 		// when calling CloseShardByID or the controller is shutting down.
 		s.lifecycleCancel()
+		s.executionManager.MarkShardClosing(s.shardID, s.rangeID.Load())
 	}
 
 	switch s.state {
@@ -1998,6 +2029,8 @@ func (s *ContextImpl) acquireShard() {
 		if err != nil {
 			return err
 		}
+		// The new range ID is higher than any closing mark of a previous owner in this host, clear it
+		s.executionManager.MarkShardOpen(s.shardID)
 
 		s.contextTaggedLogger.Info("Acquired shard")
 
@@ -2093,6 +2126,7 @@ func newContext(
 	eventsCache events.Cache,
 	stateMachineRegistry *hsm.Registry,
 	chasmRegistry *chasm.Registry,
+	retryableClassifier retrypolicy.RetryableClassifier,
 ) (*ContextImpl, error) {
 	hostIdentity := hostInfoProvider.HostInfo().Identity()
 	sequenceID := atomic.AddInt64(&shardContextSequenceID, 1)
@@ -2142,6 +2176,7 @@ func newContext(
 		ioSemaphore:             locks.NewPrioritySemaphore(ioConcurrency),
 		stateMachineRegistry:    stateMachineRegistry,
 		chasmRegistry:           chasmRegistry,
+		retryableClassifier:     retryableClassifier,
 	}
 	shardContext.taskKeyManager = newTaskKeyManager(
 		shardContext.taskCategoryRegistry,
@@ -2248,6 +2283,10 @@ func (s *ContextImpl) ChasmRegistry() *chasm.Registry {
 	return s.chasmRegistry
 }
 
+func (s *ContextImpl) RetryableClassifier() retrypolicy.RetryableClassifier {
+	return s.retryableClassifier
+}
+
 func (s *ContextImpl) GetCachedWorkflowContext(
 	ctx context.Context,
 	namespaceID namespace.ID,
@@ -29,6 +29,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"testing"
 	"time"
 
@@ -41,6 +42,8 @@ import (
 	"go.temporal.io/server/common/clock"
 	"go.temporal.io/server/common/cluster"
 	"go.temporal.io/server/common/definition"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/metrics/metricstest"
 	"go.temporal.io/server/common/namespace"
 	"go.temporal.io/server/common/persistence"
 	"go.temporal.io/server/common/primitives/timestamp"
@@ -431,6 +434,67 @@ func (s *contextSuite) TestAcquireShardNoError() {
 	s.Assert().Equal(contextStateAcquired, s.mockShard.state)
 }
 
+func (s *contextSuite) TestAcquireShard_MarksShardOpenAndClosing() {
+	executionManager := persistence.NewMockExecutionManager(s.controller)
+	s.mockShard.executionManager = executionManager
+	s.mockShard.state = contextStateAcquiring
+	s.mockShard.acquireShardRetryPolicy = backoff.NewExponentialRetryPolicy(time.Nanosecond).
+		WithMaximumAttempts(5)
+	s.mockShardManager.EXPECT().UpdateShard(gomock.Any(), gomock.Any()).
+		Return(nil).Times(1)
+	s.mockHistoryEngine.EXPECT().NotifyNewTasks(gomock.Any()).MinTimes(1)
+	gomock.InOrder(
+		executionManager.EXPECT().MarkShardOpen(s.shardID).Times(1),
+		// closing is marked with the range ID renewed by the acquire
+		executionManager.EXPECT().MarkShardClosing(s.shardID, int64(2)).Times(1),
+	)
+
+	s.mockShard.acquireShard()
+	s.Assert().Equal(contextStateAcquired, s.mockShard.state)
+
+	s.mockShard.UnloadForOwnershipLost()
+	s.Assert().Equal(contextStateStopping, s.mockShard.state)
+}
+
+func (s *contextSuite) TestEmitTimerTaskLagMetric() {
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	defer metricsHandler.StopCapture(capture)
+	s.mockShard.SetMetricsHandler(metricsHandler)
+
+	now := time.Now().UTC()
+	s.timeSource.Update(now)
+	s.mockExecutionManager.EXPECT().GetOldestTimerTask(gomock.Any(), &persistence.GetOldestTimerTaskRequest{
+		ShardID: s.shardID,
+	}).Return(&persistence.GetOldestTimerTaskResponse{
+		Task: &tasks.UserTimerTask{VisibilityTimestamp: now.Add(-time.Minute)},
+	}, nil)
+	s.mockShard.emitTimerTaskLagMetric()
+
+	// a timer task that isn't due yet has no lag
+	s.mockExecutionManager.EXPECT().GetOldestTimerTask(gomock.Any(), gomock.Any()).
+		Return(&persistence.GetOldestTimerTaskResponse{
+			Task: &tasks.UserTimerTask{VisibilityTimestamp: now.Add(time.Minute)},
+		}, nil)
+	s.mockShard.emitTimerTaskLagMetric()
+
+	s.mockExecutionManager.EXPECT().GetOldestTimerTask(gomock.Any(), gomock.Any()).
+		Return(&persistence.GetOldestTimerTaskResponse{}, nil)
+	s.mockShard.emitTimerTaskLagMetric()
+
+	// nothing is recorded if the read fails
+	s.mockExecutionManager.EXPECT().GetOldestTimerTask(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("some random error"))
+	s.mockShard.emitTimerTaskLagMetric()
+
+	recordings := capture.Snapshot()[metrics.ShardInfoTimerTaskLagGauge.Name()]
+	s.Len(recordings, 3)
+	s.Equal(time.Minute.Seconds(), recordings[0].Value)
+	s.Equal(strconv.Itoa(int(s.shardID)), recordings[0].Tags["shard_id"])
+	s.Equal(float64(0), recordings[1].Value)
+	s.Equal(float64(0), recordings[2].Value)
+}
+
 func (s *contextSuite) TestHandoverNamespace() {
 	s.mockHistoryEngine.EXPECT().NotifyNewTasks(gomock.Any()).Times(1)
 
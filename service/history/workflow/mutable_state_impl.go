@@ -1383,13 +1383,33 @@ func (ms *MutableStateImpl) GetRetryBackoffDuration(
 		info.RetryMaximumAttempts,
 		info.RetryInitialInterval,
 		info.RetryMaximumInterval,
+		ms.config.RetryMinimumBackoffInterval(ms.namespaceEntry.Name().String()),
 		info.WorkflowExecutionExpirationTime,
 		info.RetryBackoffCoefficient,
 		failure,
 		info.RetryNonRetryableErrorTypes,
+		ms.config.GlobalNonRetryableErrorTypes(ms.namespaceEntry.Name().String()),
+		ms.retryBackoffOptions(info.NamespaceId, info.WorkflowId, ms.executionState.RunId),
 	)
 }
 
+// retryBackoffOptions returns the backoff interval options of the namespace, which apply to both workflow and
+// activity retries. taskIdentity identifies the retried workflow or activity, and seeds the backoff jitter.
+func (ms *MutableStateImpl) retryBackoffOptions(taskIdentity ...string) backoffIntervalOptions {
+	namespaceName := ms.namespaceEntry.Name().String()
+	opts := backoffIntervalOptions{
+		classifier:  ms.shard.RetryableClassifier(),
+		granularity: ms.config.RetryBackoffIntervalGranularity(namespaceName),
+	}
+	if coefficient := ms.config.RetryBackoffJitterCoefficient(namespaceName); coefficient > 0 {
+		opts.jitter = DeterministicBackoffJitter(min(coefficient, 1), taskIdentity...)
+	}
+	if breakpoints := ms.config.RetryBackoffCoefficientBreakpoints(namespaceName); len(breakpoints) != 0 {
+		opts.coefficientBreakpoints = sortedCoefficientBreakpoints(breakpoints)
+	}
+	return opts
+}
+
 func (ms *MutableStateImpl) GetCronBackoffDuration() time.Duration {
 	if ms.executionInfo.CronSchedule == "" {
 		return backoff.NoBackoff
@@ -5431,7 +5451,12 @@ func (ms *MutableStateImpl) RetryActivity(
 		return enumspb.RETRY_STATE_CANCEL_REQUESTED, nil
 	}
 
-	if !isRetryable(activityFailure, ai.RetryNonRetryableErrorTypes) {
+	if !isRetryable(
+		activityFailure,
+		ai.RetryNonRetryableErrorTypes,
+		ms.config.GlobalNonRetryableErrorTypes(ms.namespaceEntry.Name().String()),
+		ms.shard.RetryableClassifier(),
+	) {
 		return enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, nil
 	}
 
@@ -5466,15 +5491,17 @@ func (ms *MutableStateImpl) RetryActivity(
 	}
 
 	now := ms.timeSource.Now().In(time.UTC)
-	retryBackoff, retryState := nextBackoffInterval(
-		ms.timeSource.Now().In(time.UTC),
+	retryBackoff, retryState := retryBackoffInterval(
+		now,
 		ai.Attempt,
 		ai.RetryMaximumAttempts,
 		ai.RetryInitialInterval,
 		retryMaxInterval,
+		ms.config.RetryMinimumBackoffInterval(ms.namespaceEntry.Name().String()),
 		ai.RetryExpirationTime,
 		ai.RetryBackoffCoefficient,
-		makeBackoffAlgorithm(delay),
+		activityFailure,
+		ms.retryBackoffOptions(ms.executionInfo.NamespaceId, ms.executionInfo.WorkflowId, ms.executionState.RunId, ai.ActivityId),
 	)
 	if retryState != enumspb.RETRY_STATE_IN_PROGRESS {
 		return retryState, nil
@@ -65,6 +65,7 @@ import (
 	"go.temporal.io/server/common/persistence/transitionhistory"
 	"go.temporal.io/server/common/persistence/versionhistory"
 	"go.temporal.io/server/common/primitives/timestamp"
+	"go.temporal.io/server/common/retrypolicy"
 	"go.temporal.io/server/common/searchattribute"
 	serviceerror2 "go.temporal.io/server/common/serviceerror"
 	"go.temporal.io/server/common/testing/protorequire"
@@ -2118,6 +2119,28 @@ func (s *mutableStateSuite) TestRetryWorkflowTask_WithNextRetryDelay() {
 	s.Equal(enumspb.RETRY_STATE_IN_PROGRESS, retryState)
 	s.Equal(duration, expectedDelayDuration)
 }
+func (s *mutableStateSuite) TestGetRetryBackoffDuration_Jitter() {
+	s.mockConfig.RetryBackoffJitterCoefficient = dynamicconfig.GetFloatPropertyFnFilteredByNamespace(0.5)
+	info := s.mutableState.executionInfo
+	info.HasRetryPolicy = true
+	info.Attempt = 1
+	info.RetryInitialInterval = durationpb.New(10 * time.Second)
+	info.RetryBackoffCoefficient = 2
+	retryFailure := failure.NewServerFailure("retryable failure", false)
+
+	interval, retryState := s.mutableState.GetRetryBackoffDuration(retryFailure)
+	s.Equal(enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	s.GreaterOrEqual(interval, 5*time.Second)
+	s.Less(interval, 15*time.Second)
+
+	// the jitter is the same for the same run, and differs for another run
+	sameRunInterval, _ := s.mutableState.GetRetryBackoffDuration(retryFailure)
+	s.Equal(interval, sameRunInterval)
+	s.mutableState.executionState.RunId = uuid.New()
+	otherRunInterval, _ := s.mutableState.GetRetryBackoffDuration(retryFailure)
+	s.NotEqual(interval, otherRunInterval)
+}
+
 func (s *mutableStateSuite) TestRetryActivity_TruncateRetryableFailure() {
 	s.mockEventsCache.EXPECT().PutEvent(gomock.Any(), gomock.Any()).AnyTimes()
 
@@ -2180,6 +2203,107 @@ func (s *mutableStateSuite) TestRetryActivity_TruncateRetryableFailure() {
 	s.Equal(activityFailure.GetMessage(), activityInfo.RetryLastFailure.Cause.GetMessage())
 }
 
+func (s *mutableStateSuite) TestRetryActivity_IntervalGranularity() {
+	s.mockEventsCache.EXPECT().PutEvent(gomock.Any(), gomock.Any()).AnyTimes()
+	s.mockConfig.RetryBackoffIntervalGranularity = dynamicconfig.GetDurationPropertyFnFilteredByNamespace(time.Hour)
+
+	workflowTaskCompletedEventID := int64(4)
+	_, activityInfo, err := s.mutableState.AddActivityTaskScheduledEvent(
+		workflowTaskCompletedEventID,
+		&commandpb.ScheduleActivityTaskCommandAttributes{
+			ActivityId:   "5",
+			ActivityType: &commonpb.ActivityType{Name: "activity-type"},
+			TaskQueue:    &taskqueuepb.TaskQueue{Name: "task-queue"},
+			RetryPolicy: &commonpb.RetryPolicy{
+				InitialInterval: timestamp.DurationFromSeconds(1),
+			},
+		},
+		false,
+	)
+	s.NoError(err)
+	_, err = s.mutableState.AddActivityTaskStartedEvent(
+		activityInfo,
+		activityInfo.ScheduledEventId,
+		uuid.New(),
+		"worker-identity",
+		nil,
+		nil,
+		nil,
+	)
+	s.NoError(err)
+
+	activityFailure := &failurepb.Failure{
+		Message: "activity failure",
+		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
+			Type: "application-failure-type",
+		}},
+	}
+	before := time.Now()
+	retryState, err := s.mutableState.RetryActivity(activityInfo, activityFailure)
+	after := time.Now()
+	s.NoError(err)
+	s.Equal(enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+
+	// the 1s interval of the first retry is rounded up to the granularity
+	activityInfo, ok := s.mutableState.GetActivityInfo(activityInfo.ScheduledEventId)
+	s.True(ok)
+	s.False(activityInfo.ScheduledTime.AsTime().Before(before.Add(time.Hour)))
+	s.False(activityInfo.ScheduledTime.AsTime().After(after.Add(time.Hour)))
+}
+
+func (s *mutableStateSuite) TestRetryActivity_CoefficientBreakpoints() {
+	s.mockEventsCache.EXPECT().PutEvent(gomock.Any(), gomock.Any()).AnyTimes()
+	s.mockConfig.RetryBackoffCoefficientBreakpoints = dynamicconfig.GetTypedPropertyFnFilteredByNamespace(
+		[]retrypolicy.BackoffCoefficientBreakpoint{{FromAttempt: 1, Coefficient: 10}},
+	)
+
+	workflowTaskCompletedEventID := int64(4)
+	_, activityInfo, err := s.mutableState.AddActivityTaskScheduledEvent(
+		workflowTaskCompletedEventID,
+		&commandpb.ScheduleActivityTaskCommandAttributes{
+			ActivityId:   "5",
+			ActivityType: &commonpb.ActivityType{Name: "activity-type"},
+			TaskQueue:    &taskqueuepb.TaskQueue{Name: "task-queue"},
+			RetryPolicy: &commonpb.RetryPolicy{
+				InitialInterval:    timestamp.DurationFromSeconds(1),
+				BackoffCoefficient: 2,
+				MaximumInterval:    timestamp.DurationFromSeconds(100),
+			},
+		},
+		false,
+	)
+	s.NoError(err)
+	_, err = s.mutableState.AddActivityTaskStartedEvent(
+		activityInfo,
+		activityInfo.ScheduledEventId,
+		uuid.New(),
+		"worker-identity",
+		nil,
+		nil,
+		nil,
+	)
+	s.NoError(err)
+	activityInfo.Attempt = 2
+
+	activityFailure := &failurepb.Failure{
+		Message: "activity failure",
+		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
+			Type: "application-failure-type",
+		}},
+	}
+	before := time.Now()
+	retryState, err := s.mutableState.RetryActivity(activityInfo, activityFailure)
+	after := time.Now()
+	s.NoError(err)
+	s.Equal(enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+
+	// the interval after the second attempt grows by the breakpoint coefficient instead of the policy's
+	activityInfo, ok := s.mutableState.GetActivityInfo(activityInfo.ScheduledEventId)
+	s.True(ok)
+	s.False(activityInfo.ScheduledTime.AsTime().Before(before.Add(10 * time.Second)))
+	s.False(activityInfo.ScheduledTime.AsTime().After(after.Add(10 * time.Second)))
+}
+
 func (s *mutableStateSuite) TestupdateBuildIdsAndDeploymentSearchAttributes() {
 	versioned := func(buildId string) *commonpb.WorkerVersionStamp {
 		return &commonpb.WorkerVersionStamp{BuildId: buildId, UseVersioning: true}
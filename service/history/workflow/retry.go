@@ -25,8 +25,11 @@
 package workflow
 
 import (
+	"cmp"
 	"context"
+	"hash/fnv"
 	"math"
+	"math/rand"
 	"slices"
 	"time"
 
@@ -41,21 +44,120 @@ import (
 	clockspb "go.temporal.io/server/api/clock/v1"
 	"go.temporal.io/server/api/historyservice/v1"
 	workflowspb "go.temporal.io/server/api/workflow/v1"
+	"go.temporal.io/server/common"
 	"go.temporal.io/server/common/backoff"
+	"go.temporal.io/server/common/namespace"
 	"go.temporal.io/server/common/primitives/timestamp"
 	"go.temporal.io/server/common/retrypolicy"
 	"go.temporal.io/server/common/worker_versioning"
+	"go.temporal.io/server/service/history/configs"
 	historyi "go.temporal.io/server/service/history/interfaces"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// EffectiveRetryPolicy is the retry policy of an activity after the namespace defaults and the server side
+// bounds on backoff intervals are applied. These are the values the retry backoff is computed with.
+type EffectiveRetryPolicy struct {
+	InitialInterval time.Duration
+	// MaximumInterval is 0 if the backoff interval is unbounded.
+	MaximumInterval    time.Duration
+	BackoffCoefficient float64
+	// MaximumAttempts is 0 if attempts are unlimited.
+	MaximumAttempts int32
+	// MinimumInterval is the lower bound applied to every backoff interval.
+	MinimumInterval time.Duration
+	// IntervalGranularity is the duration backoff intervals are rounded up to a multiple of, 0 if they aren't
+	// rounded.
+	IntervalGranularity time.Duration
+	// JitterCoefficient is the fraction exponential backoff intervals are jittered by, 0 if they aren't jittered.
+	JitterCoefficient float64
+	// NonRetryableErrorTypes are the failure types that aren't retried: those of the retry policy followed by the
+	// namespace-wide ones.
+	NonRetryableErrorTypes []string
+	// CoefficientBreakpoints change the backoff coefficient from their attempt on, sorted by attempt. Empty if the
+	// backoff coefficient applies to every attempt.
+	CoefficientBreakpoints []retrypolicy.BackoffCoefficientBreakpoint
+}
+
+// defaultRetryableClassifier is the built-in classification, which classifies every failure. It is consulted
+// after the injected retrypolicy.RetryableClassifier, if any.
+type defaultRetryableClassifier struct {
+	nonRetryableTypes []string
+}
+
 type BackoffCalculatorAlgorithmFunc func(duration *durationpb.Duration, coefficient float64, currentAttempt int32) time.Duration
 
 func ExponentialBackoffAlgorithm(initInterval *durationpb.Duration, backoffCoefficient float64, currentAttempt int32) time.Duration {
 	return time.Duration(int64(float64(initInterval.AsDuration().Nanoseconds()) * math.Pow(backoffCoefficient, float64(currentAttempt-1))))
 }
 
+// PiecewiseExponentialBackoffAlgorithm returns a BackoffCalculatorAlgorithmFunc that grows the interval by the
+// backoff coefficient up to the first breakpoint, and by the coefficient of the latest breakpoint reached from
+// there on. Breakpoints must be sorted by FromAttempt. An interval that overflows is returned as 0, which is
+// bounded by the maximum interval like an overflowing exponential interval.
+func PiecewiseExponentialBackoffAlgorithm(breakpoints []retrypolicy.BackoffCoefficientBreakpoint) BackoffCalculatorAlgorithmFunc {
+	return func(initInterval *durationpb.Duration, backoffCoefficient float64, currentAttempt int32) time.Duration {
+		multiplier := 1.0
+		// the interval after attempt n has grown by the coefficients of attempts 1 to n-1
+		attempt := int32(1)
+		coefficient := backoffCoefficient
+		for _, breakpoint := range breakpoints {
+			if breakpoint.FromAttempt >= currentAttempt {
+				break
+			}
+			if breakpoint.FromAttempt > attempt {
+				multiplier *= math.Pow(coefficient, float64(breakpoint.FromAttempt-attempt))
+				attempt = breakpoint.FromAttempt
+			}
+			coefficient = breakpoint.Coefficient
+		}
+		multiplier *= math.Pow(coefficient, float64(currentAttempt-attempt))
+
+		interval := float64(initInterval.AsDuration().Nanoseconds()) * multiplier
+		if math.IsNaN(interval) || interval >= math.MaxInt64 {
+			return 0
+		}
+		return time.Duration(interval)
+	}
+}
+
+// BackoffJitterFunc jitters the retry backoff interval computed for currentAttempt.
+type BackoffJitterFunc func(interval time.Duration, currentAttempt int32) time.Duration
+
+// DeterministicBackoffJitter returns a BackoffJitterFunc that jitters intervals by up to coefficient, drawing
+// from a random source seeded from taskIdentity (e.g. the namespace, workflow, run and activity IDs of the
+// retried task) and the attempt. The same task always gets the same interval for a given attempt, while
+// different tasks are spread apart. This is meant for reproducing retry behavior when debugging.
+func DeterministicBackoffJitter(coefficient float64, taskIdentity ...string) BackoffJitterFunc {
+	h := fnv.New64a()
+	for _, id := range taskIdentity {
+		_, _ = h.Write([]byte(id))
+		// separator, so that ("ab", "c") and ("a", "bc") get different seeds
+		_, _ = h.Write([]byte{0})
+	}
+	seed := int64(h.Sum64())
+	return func(interval time.Duration, currentAttempt int32) time.Duration {
+		source := rand.New(rand.NewSource(seed ^ int64(currentAttempt)))
+		return backoff.JitterWithSource(interval, coefficient, source)
+	}
+}
+
+// backoffIntervalOptions are the optional knobs of getBackoffInterval. The zero value disables all of them.
+type backoffIntervalOptions struct {
+	// classifier, if not nil, decides whether failures it handles are retryable, see isRetryable.
+	classifier retrypolicy.RetryableClassifier
+	// granularity, if positive, rounds the interval up to a multiple of it, so that the retry aligns with the
+	// timer tick; a retry that is then scheduled after the expiration time times out.
+	granularity time.Duration
+	// coefficientBreakpoints, if not empty, change the backoff coefficient at each breakpoint, see
+	// PiecewiseExponentialBackoffAlgorithm.
+	coefficientBreakpoints []retrypolicy.BackoffCoefficientBreakpoint
+	// jitter, if not nil, is applied to the exponential backoff interval before it is bounded by the maximum and
+	// minimum intervals. A retry delay requested by the worker is never jittered.
+	jitter BackoffJitterFunc
+}
+
 // TODO treat 0 as 0, not infinite
 
 func getBackoffInterval(
@@ -64,22 +166,60 @@ func getBackoffInterval(
 	maxAttempts int32,
 	initInterval *durationpb.Duration,
 	maxInterval *durationpb.Duration,
+	minInterval time.Duration,
 	expirationTime *timestamppb.Timestamp,
 	backoffCoefficient float64,
 	failure *failurepb.Failure,
 	nonRetryableTypes []string,
+	globalNonRetryableTypes []string,
+	opts backoffIntervalOptions,
 ) (time.Duration, enumspb.RetryState) {
 
-	if !isRetryable(failure, nonRetryableTypes) {
+	if !isRetryable(failure, nonRetryableTypes, globalNonRetryableTypes, opts.classifier) {
 		return backoff.NoBackoff, enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE
 	}
+	return retryBackoffInterval(now, currentAttempt, maxAttempts, initInterval, maxInterval, minInterval, expirationTime, backoffCoefficient, failure, opts)
+}
 
+// retryBackoffInterval computes the backoff interval of the next attempt after a failure that is known to be
+// retryable. A retry delay requested by the worker in the failure replaces the exponential backoff interval.
+func retryBackoffInterval(
+	now time.Time,
+	currentAttempt int32,
+	maxAttempts int32,
+	initInterval *durationpb.Duration,
+	maxInterval *durationpb.Duration,
+	minInterval time.Duration,
+	expirationTime *timestamppb.Timestamp,
+	backoffCoefficient float64,
+	failure *failurepb.Failure,
+	opts backoffIntervalOptions,
+) (time.Duration, enumspb.RetryState) {
+	var intervalCalculator BackoffCalculatorAlgorithmFunc = ExponentialBackoffAlgorithm
+	if len(opts.coefficientBreakpoints) != 0 {
+		intervalCalculator = PiecewiseExponentialBackoffAlgorithm(opts.coefficientBreakpoints)
+	}
 	// Check if the remote worker sent an application failure indicating a custom backoff duration.
-	delayedRetryDuration := nextRetryDelayFrom(failure)
-	if delayedRetryDuration != nil {
-		return nextBackoffInterval(now, currentAttempt, maxAttempts, initInterval, maxInterval, expirationTime, backoffCoefficient, makeBackoffAlgorithm(delayedRetryDuration))
+	if delayedRetryDuration := nextRetryDelayFrom(failure); delayedRetryDuration != nil {
+		intervalCalculator = makeBackoffAlgorithm(delayedRetryDuration)
+	} else if opts.jitter != nil {
+		exponentialCalculator := intervalCalculator
+		intervalCalculator = func(initInterval *durationpb.Duration, backoffCoefficient float64, currentAttempt int32) time.Duration {
+			return opts.jitter(exponentialCalculator(initInterval, backoffCoefficient, currentAttempt), currentAttempt)
+		}
 	}
-	return nextBackoffInterval(now, currentAttempt, maxAttempts, initInterval, maxInterval, expirationTime, backoffCoefficient, ExponentialBackoffAlgorithm)
+	interval, retryState := nextBackoffInterval(now, currentAttempt, maxAttempts, initInterval, maxInterval, minInterval, expirationTime, backoffCoefficient, intervalCalculator)
+	if retryState != enumspb.RETRY_STATE_IN_PROGRESS {
+		return interval, retryState
+	}
+
+	if granularity := opts.granularity; granularity > 0 && interval%granularity != 0 {
+		interval += granularity - interval%granularity
+		if retryExpired(now, interval, expirationTime) {
+			return backoff.NoBackoff, enumspb.RETRY_STATE_TIMEOUT
+		}
+	}
+	return interval, retryState
 }
 
 func nextRetryDelayFrom(failure *failurepb.Failure) *time.Duration {
@@ -102,6 +242,7 @@ func nextBackoffInterval(
 	maxAttempts int32,
 	initInterval *durationpb.Duration,
 	maxInterval *durationpb.Duration,
+	minInterval time.Duration,
 	expirationTime *timestamppb.Timestamp,
 	backoffCoefficient float64,
 	intervalCalculator BackoffCalculatorAlgorithmFunc,
@@ -134,18 +275,54 @@ func nextBackoffInterval(
 	if maxInterval.AsDuration() != 0 && (interval <= 0 || interval > maxInterval.AsDuration()) {
 		interval = maxInterval.AsDuration()
 	}
+	interval = max(interval, minInterval)
 
-	if expirationTime != nil && now.Add(interval).After(expirationTime.AsTime()) {
+	if retryExpired(now, interval, expirationTime) {
 		return backoff.NoBackoff, enumspb.RETRY_STATE_TIMEOUT
 	}
 	return interval, enumspb.RETRY_STATE_IN_PROGRESS
 }
 
-func isRetryable(failure *failurepb.Failure, nonRetryableTypes []string) bool {
+// retryExpired returns whether a retry made interval after now is past expirationTime, and must time out instead.
+// The expiration time is exclusive: a retry scheduled exactly at the expiration time isn't made, as nothing is
+// left of the retry period for it to run in. A nil or zero expiration time never expires.
+func retryExpired(now time.Time, interval time.Duration, expirationTime *timestamppb.Timestamp) bool {
+	if expirationTime == nil || expirationTime.AsTime().IsZero() {
+		return false
+	}
+	return !now.Add(interval).Before(expirationTime.AsTime())
+}
+
+// isRetryable consults classifier, if not nil, and then the built-in classification, which treats the types of
+// both nonRetryableTypes, from the retry policy, and globalNonRetryableTypes, from the server config, as
+// non-retryable.
+func isRetryable(
+	failure *failurepb.Failure,
+	nonRetryableTypes []string,
+	globalNonRetryableTypes []string,
+	classifier retrypolicy.RetryableClassifier,
+) bool {
 	if failure == nil {
 		return true
 	}
 
+	if classifier != nil {
+		if retryable, handled := classifier.Classify(failure); handled {
+			return retryable
+		}
+	}
+	if len(globalNonRetryableTypes) != 0 {
+		nonRetryableTypes = slices.Concat(nonRetryableTypes, globalNonRetryableTypes)
+	}
+	retryable, _ := defaultRetryableClassifier{nonRetryableTypes: nonRetryableTypes}.Classify(failure)
+	return retryable
+}
+
+func (c defaultRetryableClassifier) Classify(failure *failurepb.Failure) (bool, bool) {
+	return c.isRetryable(failure), true
+}
+
+func (c defaultRetryableClassifier) isRetryable(failure *failurepb.Failure) bool {
 	if failure.GetTerminatedFailureInfo() != nil || failure.GetCanceledFailureInfo() != nil {
 		return false
 	}
@@ -155,7 +332,7 @@ func isRetryable(failure *failurepb.Failure, nonRetryableTypes []string) bool {
 		if timeoutType == enumspb.TIMEOUT_TYPE_START_TO_CLOSE ||
 			timeoutType == enumspb.TIMEOUT_TYPE_HEARTBEAT {
 			return !slices.Contains(
-				nonRetryableTypes,
+				c.nonRetryableTypes,
 				retrypolicy.TimeoutFailureTypePrefix+timeoutType.String(),
 			)
 		}
@@ -173,13 +350,99 @@ func isRetryable(failure *failurepb.Failure, nonRetryableTypes []string) bool {
 		}
 
 		return !slices.Contains(
-			nonRetryableTypes,
+			c.nonRetryableTypes,
 			failure.GetApplicationFailureInfo().GetType(),
 		)
 	}
 	return true
 }
 
+// SimulateRetries replays the retry decision for a failure of the given application
+// failure type against policy, without scheduling anything. It returns the attempt
+// at which retrying would stop and the resulting RetryState. If retrying would still
+// be in progress after maxIterations attempts, RETRY_STATE_IN_PROGRESS is returned.
+func SimulateRetries(
+	policy *commonpb.RetryPolicy,
+	failureType string,
+	maxIterations int32,
+) (int32, enumspb.RetryState) {
+	failure := &failurepb.Failure{
+		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
+			Type: failureType,
+		}},
+	}
+
+	now := time.Unix(0, 0).UTC()
+	attempt := int32(1)
+	for ; attempt <= maxIterations; attempt++ {
+		interval, retryState := getBackoffInterval(
+			now,
+			attempt,
+			policy.GetMaximumAttempts(),
+			policy.GetInitialInterval(),
+			policy.GetMaximumInterval(),
+			0,
+			nil,
+			policy.GetBackoffCoefficient(),
+			failure,
+			policy.GetNonRetryableErrorTypes(),
+			nil,
+			backoffIntervalOptions{},
+		)
+		if retryState != enumspb.RETRY_STATE_IN_PROGRESS {
+			return attempt, retryState
+		}
+		now = now.Add(interval)
+	}
+	return maxIterations, enumspb.RETRY_STATE_IN_PROGRESS
+}
+
+// GetEffectiveRetryPolicy returns the retry policy an activity with rawPolicy retries with in the given namespace:
+// unset fields are filled in from the namespace default activity retry policy, the intervals are raised
+// to the namespace minimum backoff interval, and the namespace interval granularity, jitter, coefficient
+// breakpoints and non-retryable error types are added.
+func GetEffectiveRetryPolicy(
+	rawPolicy *commonpb.RetryPolicy,
+	namespaceName namespace.Name,
+	config *configs.Config,
+) EffectiveRetryPolicy {
+	policy := &commonpb.RetryPolicy{}
+	if rawPolicy != nil {
+		policy = common.CloneProto(rawPolicy)
+	}
+	retrypolicy.EnsureDefaults(policy, config.DefaultActivityRetryPolicy(namespaceName.String()))
+
+	minInterval := config.RetryMinimumBackoffInterval(namespaceName.String())
+	effective := EffectiveRetryPolicy{
+		InitialInterval:     max(policy.GetInitialInterval().AsDuration(), minInterval),
+		MaximumInterval:     policy.GetMaximumInterval().AsDuration(),
+		BackoffCoefficient:  policy.GetBackoffCoefficient(),
+		MaximumAttempts:     policy.GetMaximumAttempts(),
+		MinimumInterval:     minInterval,
+		IntervalGranularity: max(config.RetryBackoffIntervalGranularity(namespaceName.String()), 0),
+		JitterCoefficient:   min(max(config.RetryBackoffJitterCoefficient(namespaceName.String()), 0), 1),
+		NonRetryableErrorTypes: slices.Concat(
+			policy.GetNonRetryableErrorTypes(),
+			config.GlobalNonRetryableErrorTypes(namespaceName.String()),
+		),
+		CoefficientBreakpoints: sortedCoefficientBreakpoints(config.RetryBackoffCoefficientBreakpoints(namespaceName.String())),
+	}
+	if effective.MaximumInterval != 0 {
+		effective.MaximumInterval = max(effective.MaximumInterval, minInterval)
+	}
+	return effective
+}
+
+// sortedCoefficientBreakpoints returns a copy of breakpoints sorted by attempt, as
+// PiecewiseExponentialBackoffAlgorithm requires. Dynamic config doesn't guarantee the order.
+func sortedCoefficientBreakpoints(
+	breakpoints []retrypolicy.BackoffCoefficientBreakpoint,
+) []retrypolicy.BackoffCoefficientBreakpoint {
+	return slices.SortedStableFunc(slices.Values(breakpoints), func(a, b retrypolicy.BackoffCoefficientBreakpoint) int {
+		return cmp.Compare(a.FromAttempt, b.FromAttempt)
+	})
+}
+
 // Helpers for creating new retry/cron workflows:
 
 func SetupNewWorkflowForRetryOrCron(
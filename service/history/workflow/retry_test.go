@@ -30,12 +30,15 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	commonpb "go.temporal.io/api/common/v1"
 	enumspb "go.temporal.io/api/enums/v1"
 	failurepb "go.temporal.io/api/failure/v1"
 	"go.temporal.io/server/common/backoff"
+	"go.temporal.io/server/common/dynamicconfig"
 	"go.temporal.io/server/common/failure"
 	"go.temporal.io/server/common/number"
 	"go.temporal.io/server/common/retrypolicy"
+	"go.temporal.io/server/service/history/tests"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -46,74 +49,74 @@ func Test_IsRetryable(t *testing.T) {
 	f := &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_TerminatedFailureInfo{TerminatedFailureInfo: &failurepb.TerminatedFailureInfo{}},
 	}
-	a.False(isRetryable(f, nil))
+	a.False(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_CanceledFailureInfo{CanceledFailureInfo: &failurepb.CanceledFailureInfo{}},
 	}
-	a.False(isRetryable(f, nil))
+	a.False(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_TimeoutFailureInfo{TimeoutFailureInfo: &failurepb.TimeoutFailureInfo{
 			TimeoutType: enumspb.TIMEOUT_TYPE_UNSPECIFIED,
 		}},
 	}
-	a.False(isRetryable(f, nil))
+	a.False(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_TimeoutFailureInfo{TimeoutFailureInfo: &failurepb.TimeoutFailureInfo{
 			TimeoutType: enumspb.TIMEOUT_TYPE_START_TO_CLOSE,
 		}},
 	}
-	a.True(isRetryable(f, nil))
-	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_START_TO_CLOSE.String()}))
+	a.True(isRetryable(f, nil, nil, nil))
+	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_START_TO_CLOSE.String()}, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_TimeoutFailureInfo{TimeoutFailureInfo: &failurepb.TimeoutFailureInfo{
 			TimeoutType: enumspb.TIMEOUT_TYPE_SCHEDULE_TO_START,
 		}},
 	}
-	a.False(isRetryable(f, nil))
-	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_SCHEDULE_TO_START.String()}))
+	a.False(isRetryable(f, nil, nil, nil))
+	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_SCHEDULE_TO_START.String()}, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_TimeoutFailureInfo{TimeoutFailureInfo: &failurepb.TimeoutFailureInfo{
 			TimeoutType: enumspb.TIMEOUT_TYPE_SCHEDULE_TO_CLOSE,
 		}},
 	}
-	a.False(isRetryable(f, nil))
-	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_SCHEDULE_TO_CLOSE.String()}))
+	a.False(isRetryable(f, nil, nil, nil))
+	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_SCHEDULE_TO_CLOSE.String()}, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_TimeoutFailureInfo{TimeoutFailureInfo: &failurepb.TimeoutFailureInfo{
 			TimeoutType: enumspb.TIMEOUT_TYPE_HEARTBEAT,
 		}},
 	}
-	a.True(isRetryable(f, nil))
-	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_HEARTBEAT.String()}))
-	a.True(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_START_TO_CLOSE.String()}))
-	a.True(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + "unknown timeout type string"}))
+	a.True(isRetryable(f, nil, nil, nil))
+	a.False(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_HEARTBEAT.String()}, nil, nil))
+	a.True(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + enumspb.TIMEOUT_TYPE_START_TO_CLOSE.String()}, nil, nil))
+	a.True(isRetryable(f, []string{retrypolicy.TimeoutFailureTypePrefix + "unknown timeout type string"}, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_ServerFailureInfo{ServerFailureInfo: &failurepb.ServerFailureInfo{
 			NonRetryable: false,
 		}},
 	}
-	a.True(isRetryable(f, nil))
+	a.True(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_ServerFailureInfo{ServerFailureInfo: &failurepb.ServerFailureInfo{
 			NonRetryable: true,
 		}},
 	}
-	a.False(isRetryable(f, nil))
+	a.False(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
 			NonRetryable: true,
 		}},
 	}
-	a.False(isRetryable(f, nil))
+	a.False(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
@@ -121,10 +124,14 @@ func Test_IsRetryable(t *testing.T) {
 			Type:         "type",
 		}},
 	}
-	a.True(isRetryable(f, nil))
-	a.True(isRetryable(f, []string{"otherType"}))
-	a.False(isRetryable(f, []string{"otherType", "type"}))
-	a.False(isRetryable(f, []string{"type"}))
+	a.True(isRetryable(f, nil, nil, nil))
+	a.True(isRetryable(f, []string{"otherType"}, nil, nil))
+	a.False(isRetryable(f, []string{"otherType", "type"}, nil, nil))
+	a.False(isRetryable(f, []string{"type"}, nil, nil))
+	// the global non-retryable types are added to the retry policy's
+	a.True(isRetryable(f, nil, []string{"otherType"}, nil))
+	a.False(isRetryable(f, nil, []string{"type"}, nil))
+	a.False(isRetryable(f, []string{"otherType"}, []string{"type"}, nil))
 
 	// When any failure is inside ChildWorkflowExecutionFailure, it is always retryable because ChildWorkflow is always retryable.
 	f = &failurepb.Failure{
@@ -135,7 +142,7 @@ func Test_IsRetryable(t *testing.T) {
 			}},
 		},
 	}
-	a.True(isRetryable(f, nil))
+	a.True(isRetryable(f, nil, nil, nil))
 
 	f = &failurepb.Failure{
 		FailureInfo: &failurepb.Failure_ChildWorkflowExecutionFailureInfo{ChildWorkflowExecutionFailureInfo: &failurepb.ChildWorkflowExecutionFailureInfo{}},
@@ -148,7 +155,41 @@ func Test_IsRetryable(t *testing.T) {
 			},
 		},
 	}
-	a.True(isRetryable(f, nil))
+	a.True(isRetryable(f, nil, nil, nil))
+}
+
+type testRetryableClassifier struct {
+	failureTypes map[string]bool
+}
+
+func (c testRetryableClassifier) Classify(failure *failurepb.Failure) (bool, bool) {
+	retryable, ok := c.failureTypes[failure.GetApplicationFailureInfo().GetType()]
+	return retryable, ok
+}
+
+func Test_RetryableClassifier(t *testing.T) {
+	applicationFailure := func(failureType string) *failurepb.Failure {
+		return &failurepb.Failure{
+			FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
+				Type: failureType,
+			}},
+		}
+	}
+	classifier := testRetryableClassifier{failureTypes: map[string]bool{
+		"custom-non-retryable": false,
+		"custom-retryable":     true,
+	}}
+
+	assert.False(t, isRetryable(applicationFailure("custom-non-retryable"), nil, nil, classifier))
+	// the classifier takes precedence over the retry policy's non-retryable types
+	assert.True(t, isRetryable(applicationFailure("custom-retryable"), []string{"custom-retryable"}, nil, classifier))
+	assert.False(t, isRetryable(applicationFailure("custom-retryable"), []string{"custom-retryable"}, nil, nil))
+
+	// failures not handled by the classifier are classified as before
+	assert.True(t, isRetryable(applicationFailure("other"), nil, nil, classifier))
+	assert.False(t, isRetryable(applicationFailure("other"), []string{"other"}, nil, classifier))
+	assert.False(t, isRetryable(failure.NewServerFailure("bad-reason", true), nil, nil, classifier))
+	assert.True(t, isRetryable(nil, nil, nil, classifier))
 }
 
 func Test_NonRetriableErrors(t *testing.T) {
@@ -169,10 +210,13 @@ func Test_NonRetriableErrors(t *testing.T) {
 			doNotCare(maxRetryAttempts),
 			doNotCare(retryInterval),
 			doNotCare(maxRetryInterval),
+			doNotCare[time.Duration](0),
 			doNotCare(expirationTime),
 			doNotCare(backoffCoefficient),
 			nonRetriableFailure,
 			doNotCare(nonRetryableErrorTypes),
+			nil,
+			backoffIntervalOptions{},
 		)
 		assert.Equal(t, backoff.NoBackoff, interval)
 		assert.Equal(t, enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, retryState)
@@ -187,10 +231,13 @@ func Test_NonRetriableErrors(t *testing.T) {
 			doNotCare(maxRetryAttempts),
 			doNotCare(retryInterval),
 			doNotCare(maxRetryInterval),
+			doNotCare[time.Duration](0),
 			doNotCare(expirationTime),
 			doNotCare(backoffCoefficient),
 			retriableFailure,
 			doNotCare(nonRetryableErrorTypes),
+			nil,
+			backoffIntervalOptions{},
 		)
 		assert.NotEqual(t, enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, retryState)
 	})
@@ -211,6 +258,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			5,
 			initInterval(initialDelay),
 			doNotCare(maxInterval(10*time.Second)),
+			0,
 			doNotCare(expirationIn(30*time.Second)),
 			doNotCare[float64](2),
 			ExponentialBackoffAlgorithm,
@@ -229,6 +277,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			maxAttempts,
 			doNotCare(initInterval(initialDelay)),
 			doNotCare(maxInterval(10*time.Second)),
+			0,
 			doNotCare(expirationIn(30*time.Second)),
 			doNotCare[float64](2),
 			ExponentialBackoffAlgorithm,
@@ -247,6 +296,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			maxAttempts,
 			initInterval(initialDelay),
 			doNotCare(maxInterval(200*time.Second)),
+			0,
 			doNotCare(expirationIn(600*time.Second)),
 			3,
 			ExponentialBackoffAlgorithm,
@@ -263,6 +313,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			doNotCare[int32](20),
 			initInterval(3*time.Second),
 			maxInterval(maxBackoff),
+			0,
 			doNotCare(expirationIn(600*time.Second)),
 			doNotCare[float64](2),
 			ExponentialBackoffAlgorithm,
@@ -278,6 +329,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			10,
 			doNotCare(initInterval(3*time.Second)),
 			doNotCare(maxInterval(10*time.Second)),
+			0,
 			doNotCare(expirationIn(600*time.Second)),
 			doNotCare[float64](2),
 			ExponentialBackoffAlgorithm,
@@ -294,6 +346,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			0,
 			doNotCare(initInterval(initialDelay)),
 			doNotCare(maxInterval(30*time.Minute)),
+			0,
 			doNotCare(expirationIn(60*time.Minute)),
 			2,
 			ExponentialBackoffAlgorithm,
@@ -310,6 +363,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			0,
 			initInterval(initialDelay),
 			maxInterval(30*time.Minute),
+			0,
 			expirationIn(1*time.Minute),
 			2,
 			ExponentialBackoffAlgorithm,
@@ -326,6 +380,7 @@ func Test_nextBackoffInterval(t *testing.T) {
 			0,
 			initInterval(initialDelay),
 			maxInterval(30*time.Minute),
+			0,
 			expirationIn(0),
 			2,
 			ExponentialBackoffAlgorithm,
@@ -333,6 +388,359 @@ func Test_nextBackoffInterval(t *testing.T) {
 		assert.Equal(t, backoff.NoBackoff, interval)
 		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
 	})
+
+	t.Run("if next retry is exactly at expiration should return no more retries", func(t *testing.T) {
+		interval, retryState := nextBackoffInterval(
+			now,
+			1,
+			doNotCare[int32](0),
+			initInterval(10*time.Millisecond),
+			doNotCare(maxInterval(10*time.Second)),
+			0,
+			expirationIn(10*time.Millisecond),
+			doNotCare[float64](2),
+			ExponentialBackoffAlgorithm,
+		)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
+	})
+
+	t.Run("if next retry is just before expiration should retry", func(t *testing.T) {
+		interval, retryState := nextBackoffInterval(
+			now,
+			1,
+			doNotCare[int32](0),
+			initInterval(10*time.Millisecond),
+			doNotCare(maxInterval(10*time.Second)),
+			0,
+			expirationIn(10*time.Millisecond+time.Nanosecond),
+			doNotCare[float64](2),
+			ExponentialBackoffAlgorithm,
+		)
+		assert.Equal(t, 10*time.Millisecond, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	})
+
+	t.Run("if interval is below min interval should set it to min", func(t *testing.T) {
+		minInterval := 10 * time.Millisecond
+		interval, retryState := nextBackoffInterval(
+			doNotCare(now),
+			1,
+			doNotCare[int32](5),
+			initInterval(time.Microsecond),
+			doNotCare(maxInterval(10*time.Second)),
+			minInterval,
+			doNotCare(expirationIn(30*time.Second)),
+			doNotCare[float64](2),
+			ExponentialBackoffAlgorithm,
+		)
+		assert.Equal(t, minInterval, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	})
+
+	t.Run("min interval should not apply when there are no more retries", func(t *testing.T) {
+		interval, retryState := nextBackoffInterval(
+			doNotCare(now),
+			5,
+			5,
+			doNotCare(initInterval(time.Microsecond)),
+			doNotCare(maxInterval(10*time.Second)),
+			10*time.Millisecond,
+			doNotCare(expirationIn(30*time.Second)),
+			doNotCare[float64](2),
+			ExponentialBackoffAlgorithm,
+		)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED, retryState)
+	})
+}
+
+func Test_DeterministicBackoffJitter(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-04-13T16:08:08+00:00")
+	retryFailure := failure.NewServerFailure("good-reason", false)
+	backoffFor := func(jitter BackoffJitterFunc, attempt int32, retryFailure *failurepb.Failure) time.Duration {
+		interval, retryState := getBackoffInterval(
+			now,
+			attempt,
+			0,
+			durationpb.New(10*time.Second),
+			durationpb.New(0),
+			0,
+			nil,
+			2,
+			retryFailure,
+			nil,
+			nil,
+			backoffIntervalOptions{jitter: jitter},
+		)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+		return interval
+	}
+
+	taskA := DeterministicBackoffJitter(0.5, "namespace-id", "workflow-id", "run-a", "activity-id")
+	taskB := DeterministicBackoffJitter(0.5, "namespace-id", "workflow-id", "run-b", "activity-id")
+	for attempt := int32(1); attempt <= 5; attempt++ {
+		intervalA := backoffFor(taskA, attempt, retryFailure)
+		intervalB := backoffFor(taskB, attempt, retryFailure)
+
+		// reproducible, even from a newly created jitter func
+		assert.Equal(t, intervalA, backoffFor(taskA, attempt, retryFailure))
+		assert.Equal(t, intervalA, backoffFor(DeterministicBackoffJitter(0.5, "namespace-id", "workflow-id", "run-a", "activity-id"), attempt, retryFailure))
+		assert.NotEqual(t, intervalA, intervalB)
+
+		unjittered := backoffFor(nil, attempt, retryFailure)
+		for _, interval := range []time.Duration{intervalA, intervalB} {
+			assert.GreaterOrEqual(t, interval, unjittered/2)
+			assert.Less(t, interval, unjittered*3/2)
+		}
+	}
+
+	// a retry delay requested by the worker is not jittered
+	delayFailure := &failurepb.Failure{
+		FailureInfo: &failurepb.Failure_ApplicationFailureInfo{ApplicationFailureInfo: &failurepb.ApplicationFailureInfo{
+			NextRetryDelay: durationpb.New(7 * time.Second),
+		}},
+	}
+	assert.Equal(t, 7*time.Second, backoffFor(taskA, 1, delayFailure))
+}
+
+func Test_NextRetry(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-04-13T16:08:08+00:00")
+	retryFailure := failure.NewServerFailure("good-reason", false)
+	nextRetry := func(attempt int32, granularity time.Duration, expirationTime *timestamppb.Timestamp, retryFailure *failurepb.Failure) (time.Duration, enumspb.RetryState) {
+		return getBackoffInterval(
+			now,
+			attempt,
+			3,
+			durationpb.New(1234*time.Millisecond),
+			durationpb.New(0),
+			0,
+			expirationTime,
+			2,
+			retryFailure,
+			nil,
+			nil,
+			backoffIntervalOptions{granularity: granularity},
+		)
+	}
+
+	t.Run("interval is not rounded without granularity", func(t *testing.T) {
+		interval, retryState := nextRetry(1, 0, nil, retryFailure)
+		assert.Equal(t, 1234*time.Millisecond, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	})
+
+	t.Run("interval is rounded up to granularity", func(t *testing.T) {
+		interval, retryState := nextRetry(1, 100*time.Millisecond, nil, retryFailure)
+		assert.Equal(t, 1300*time.Millisecond, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+
+		interval, retryState = nextRetry(2, time.Second, nil, retryFailure)
+		assert.Equal(t, 3*time.Second, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+
+		// an interval that already is a multiple of the granularity is kept
+		interval, retryState = nextRetry(1, 2*time.Millisecond, nil, retryFailure)
+		assert.Equal(t, 1234*time.Millisecond, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	})
+
+	t.Run("terminal states are not affected", func(t *testing.T) {
+		interval, retryState := nextRetry(3, 100*time.Millisecond, nil, retryFailure)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED, retryState)
+
+		interval, retryState = nextRetry(1, 100*time.Millisecond, nil, failure.NewServerFailure("bad-reason", true))
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, retryState)
+
+		interval, retryState = nextRetry(1, 100*time.Millisecond, timestamppb.New(now.Add(time.Second)), retryFailure)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
+	})
+
+	t.Run("retry rounded past the expiration time times out", func(t *testing.T) {
+		interval, retryState := nextRetry(1, 0, timestamppb.New(now.Add(1250*time.Millisecond)), retryFailure)
+		assert.Equal(t, 1234*time.Millisecond, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+
+		interval, retryState = nextRetry(1, 100*time.Millisecond, timestamppb.New(now.Add(1250*time.Millisecond)), retryFailure)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
+	})
+
+	t.Run("retry exactly at the expiration time times out", func(t *testing.T) {
+		interval, retryState := nextRetry(1, 0, timestamppb.New(now.Add(1234*time.Millisecond)), retryFailure)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
+
+		// and so does a retry rounded up to exactly the expiration time
+		interval, retryState = nextRetry(1, 100*time.Millisecond, timestamppb.New(now.Add(1300*time.Millisecond)), retryFailure)
+		assert.Equal(t, backoff.NoBackoff, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
+
+		interval, retryState = nextRetry(1, 100*time.Millisecond, timestamppb.New(now.Add(1300*time.Millisecond+time.Nanosecond)), retryFailure)
+		assert.Equal(t, 1300*time.Millisecond, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	})
+}
+
+func Test_PiecewiseBackoffCoefficient(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2018-04-13T16:08:08+00:00")
+	retryFailure := failure.NewServerFailure("good-reason", false)
+	backoffFor := func(attempt int32, maxInterval time.Duration, breakpoints []retrypolicy.BackoffCoefficientBreakpoint) (time.Duration, enumspb.RetryState) {
+		return getBackoffInterval(
+			now,
+			attempt,
+			0,
+			durationpb.New(time.Second),
+			durationpb.New(maxInterval),
+			0,
+			nil,
+			2,
+			retryFailure,
+			nil,
+			nil,
+			backoffIntervalOptions{coefficientBreakpoints: breakpoints},
+		)
+	}
+
+	t.Run("no breakpoints behave like the scalar coefficient", func(t *testing.T) {
+		for attempt := int32(1); attempt <= 70; attempt++ {
+			for _, maxInterval := range []time.Duration{0, time.Hour} {
+				expectedInterval, expectedState := backoffFor(attempt, maxInterval, nil)
+				interval, retryState := backoffFor(attempt, maxInterval, []retrypolicy.BackoffCoefficientBreakpoint{})
+				assert.Equal(t, expectedInterval, interval, "attempt %v", attempt)
+				assert.Equal(t, expectedState, retryState, "attempt %v", attempt)
+			}
+		}
+	})
+
+	t.Run("coefficient changes at breakpoints", func(t *testing.T) {
+		// 2 for the first two retries, then 3, then 1.5
+		breakpoints := []retrypolicy.BackoffCoefficientBreakpoint{
+			{FromAttempt: 3, Coefficient: 3},
+			{FromAttempt: 5, Coefficient: 1.5},
+		}
+		for attempt, expected := range map[int32]time.Duration{
+			1: time.Second,
+			2: 2 * time.Second,
+			3: 4 * time.Second,
+			4: 12 * time.Second,
+			5: 36 * time.Second,
+			6: 54 * time.Second,
+			7: 81 * time.Second,
+		} {
+			interval, retryState := backoffFor(attempt, 0, breakpoints)
+			assert.Equal(t, expected, interval, "attempt %v", attempt)
+			assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+		}
+
+		// a breakpoint from the first attempt replaces the scalar coefficient
+		interval, _ := backoffFor(3, 0, []retrypolicy.BackoffCoefficientBreakpoint{{FromAttempt: 1, Coefficient: 3}})
+		assert.Equal(t, 9*time.Second, interval)
+	})
+
+	t.Run("overflow is capped at the maximum interval", func(t *testing.T) {
+		breakpoints := []retrypolicy.BackoffCoefficientBreakpoint{{FromAttempt: 2, Coefficient: 1e10}}
+		interval, retryState := backoffFor(5, time.Hour, breakpoints)
+		assert.Equal(t, time.Hour, interval)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+
+		_, retryState = backoffFor(5, 0, breakpoints)
+		assert.Equal(t, enumspb.RETRY_STATE_TIMEOUT, retryState)
+	})
+}
+
+func Test_SimulateRetries(t *testing.T) {
+	policy := &commonpb.RetryPolicy{
+		InitialInterval:        durationpb.New(time.Second),
+		BackoffCoefficient:     2,
+		MaximumInterval:        durationpb.New(10 * time.Second),
+		MaximumAttempts:        5,
+		NonRetryableErrorTypes: []string{"bad-request"},
+	}
+
+	t.Run("non-retryable type should stop at first attempt", func(t *testing.T) {
+		attempts, retryState := SimulateRetries(policy, "bad-request", 100)
+		assert.Equal(t, int32(1), attempts)
+		assert.Equal(t, enumspb.RETRY_STATE_NON_RETRYABLE_FAILURE, retryState)
+	})
+
+	t.Run("retryable type should run to max attempts", func(t *testing.T) {
+		attempts, retryState := SimulateRetries(policy, "transient", 100)
+		assert.Equal(t, int32(5), attempts)
+		assert.Equal(t, enumspb.RETRY_STATE_MAXIMUM_ATTEMPTS_REACHED, retryState)
+	})
+
+	t.Run("unlimited attempts should stop at max iterations", func(t *testing.T) {
+		unlimited := &commonpb.RetryPolicy{
+			InitialInterval:    durationpb.New(time.Second),
+			BackoffCoefficient: 2,
+			MaximumInterval:    durationpb.New(10 * time.Second),
+		}
+		attempts, retryState := SimulateRetries(unlimited, "transient", 10)
+		assert.Equal(t, int32(10), attempts)
+		assert.Equal(t, enumspb.RETRY_STATE_IN_PROGRESS, retryState)
+	})
+}
+
+func Test_GetEffectiveRetryPolicy(t *testing.T) {
+	config := tests.NewDynamicConfig()
+	config.DefaultActivityRetryPolicy = dynamicconfig.GetTypedPropertyFnFilteredByNamespace(retrypolicy.DefaultRetrySettings{
+		InitialInterval:            2 * time.Second,
+		MaximumIntervalCoefficient: 10,
+		BackoffCoefficient:         3,
+		MaximumAttempts:            7,
+	})
+	config.RetryMinimumBackoffInterval = dynamicconfig.GetDurationPropertyFnFilteredByNamespace(100 * time.Millisecond)
+	config.RetryBackoffIntervalGranularity = dynamicconfig.GetDurationPropertyFnFilteredByNamespace(time.Second)
+	config.RetryBackoffJitterCoefficient = dynamicconfig.GetFloatPropertyFnFilteredByNamespace(0.2)
+	config.GlobalNonRetryableErrorTypes = dynamicconfig.GetTypedPropertyFnFilteredByNamespace([]string{"global-type"})
+	config.RetryBackoffCoefficientBreakpoints = dynamicconfig.GetTypedPropertyFnFilteredByNamespace([]retrypolicy.BackoffCoefficientBreakpoint{
+		{FromAttempt: 5, Coefficient: 1},
+		{FromAttempt: 3, Coefficient: 1.2},
+	})
+	sortedBreakpoints := []retrypolicy.BackoffCoefficientBreakpoint{
+		{FromAttempt: 3, Coefficient: 1.2},
+		{FromAttempt: 5, Coefficient: 1},
+	}
+
+	t.Run("out of range intervals should be raised to min interval", func(t *testing.T) {
+		effective := GetEffectiveRetryPolicy(&commonpb.RetryPolicy{
+			InitialInterval:        durationpb.New(time.Microsecond),
+			MaximumInterval:        durationpb.New(10 * time.Millisecond),
+			BackoffCoefficient:     1.5,
+			MaximumAttempts:        3,
+			NonRetryableErrorTypes: []string{"policy-type"},
+		}, tests.Namespace, config)
+		assert.Equal(t, EffectiveRetryPolicy{
+			InitialInterval:        100 * time.Millisecond,
+			MaximumInterval:        100 * time.Millisecond,
+			BackoffCoefficient:     1.5,
+			MaximumAttempts:        3,
+			MinimumInterval:        100 * time.Millisecond,
+			IntervalGranularity:    time.Second,
+			JitterCoefficient:      0.2,
+			NonRetryableErrorTypes: []string{"policy-type", "global-type"},
+			CoefficientBreakpoints: sortedBreakpoints,
+		}, effective)
+	})
+
+	t.Run("unset fields should use namespace defaults", func(t *testing.T) {
+		effective := GetEffectiveRetryPolicy(nil, tests.Namespace, config)
+		assert.Equal(t, EffectiveRetryPolicy{
+			InitialInterval:        2 * time.Second,
+			MaximumInterval:        20 * time.Second,
+			BackoffCoefficient:     3,
+			MaximumAttempts:        7,
+			MinimumInterval:        100 * time.Millisecond,
+			IntervalGranularity:    time.Second,
+			JitterCoefficient:      0.2,
+			NonRetryableErrorTypes: []string{"global-type"},
+			CoefficientBreakpoints: sortedBreakpoints,
+		}, effective)
+	})
 }
 
 func doNotCare[T any](x T) T { return x }